@@ -14,8 +14,8 @@ func (app *application) createAttendanceHandler(w http.ResponseWriter, r *http.R
     var input struct {
         OfficerID     string  `json:"officer_id"`
         SessionID     string  `json:"session_id"`
-        Status        string  `json:"status"`
-        CreditedHours float64 `json:"credited_hours"`
+        Status        string   `json:"status"`
+        CreditedHours *float64 `json:"credited_hours"`
     }
 
     err := app.readJSON(w, r, &input)
@@ -28,18 +28,51 @@ func (app *application) createAttendanceHandler(w http.ResponseWriter, r *http.R
         OfficerID:     input.OfficerID,
         SessionID:     input.SessionID,
         Status:        input.Status,
-        CreditedHours: input.CreditedHours,
     }
 
     v := validator.New()
+
+    force := app.readBool(r.URL.Query(), "force", false, v)
+    if force && !app.hasPermission(r, "schedule:override") {
+        app.notPermittedResponse(w, r)
+        return
+    }
+
+    // Officers get the session's default credit unless told otherwise.
+    if input.CreditedHours != nil {
+        attendance.CreditedHours = *input.CreditedHours
+    } else if attendance.SessionID != "" {
+        attendance.CreditedHours, err = app.models.Sessions.DefaultCreditHours(attendance.SessionID)
+        if err != nil {
+            switch {
+            case errors.Is(err, data.ErrRecordNotFound):
+                v.AddError("session_id", "must reference an existing session")
+            default:
+                app.serverErrorResponse(w, r, err)
+                return
+            }
+        }
+    }
+
     if data.ValidateAttendance(v, attendance); !v.Valid() {
         app.failedValidationResponse(w, r, v.Errors)
         return
     }
 
-    err = app.models.Attendance.Insert(attendance)
+    err = app.models.Attendance.Insert(app.auditActor(r), attendance, force)
     if err != nil {
-        app.serverErrorResponse(w, r, err)
+        var conflictErr *data.ScheduleConflictError
+        switch {
+        case errors.As(err, &conflictErr):
+            app.scheduleConflictResponse(w, r, conflictErr.Conflicts)
+        case errors.Is(err, data.ErrAttendanceLocked):
+            app.attendanceLockedResponse(w, r)
+        case errors.Is(err, data.ErrRecordNotFound):
+            v.AddError("session_id", "must reference an existing session")
+            app.failedValidationResponse(w, r, v.Errors)
+        default:
+            app.serverErrorResponse(w, r, err)
+        }
         return
     }
 
@@ -113,9 +146,11 @@ func (app *application) updateAttendanceHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	err = app.models.Attendance.Update(attendance)
+	err = app.models.Attendance.Update(app.auditActor(r), attendance)
 	if err != nil {
 		switch {
+		case errors.Is(err, data.ErrAttendanceLocked):
+			app.attendanceLockedResponse(w, r)
 		case errors.Is(err, data.ErrEditConflict):
 			app.editConflictResponse(w, r)
 		default:
@@ -134,9 +169,11 @@ func (app *application) deleteAttendanceHandler(w http.ResponseWriter, r *http.R
     params := httprouter.ParamsFromContext(r.Context())
     id := params.ByName("id")
 
-    err := app.models.Attendance.Delete(id)
+    err := app.models.Attendance.Delete(app.auditActor(r), id)
     if err != nil {
         switch {
+        case errors.Is(err, data.ErrAttendanceLocked):
+            app.attendanceLockedResponse(w, r)
         case errors.Is(err, data.ErrRecordNotFound):
             app.notFoundResponse(w, r)
         default:
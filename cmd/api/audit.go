@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/amari03/test1/internal/data"
+	"github.com/amari03/test1/internal/validator"
+)
+
+// auditActor returns who is making the changes in the current request, for
+// the models to record in the audit log alongside them: the current user
+// (through their API key, if they used one) and the request's ID.
+func (app *application) auditActor(r *http.Request) data.AuditActor {
+	var actor data.AuditActor
+
+	if user := app.contextGetUser(r); !user.IsAnonymous() {
+		actor.UserID = &user.ID
+	}
+	if key := app.contextGetAPIKey(r); key != nil {
+		actor.APIKeyID = &key.ID
+	}
+	if id := app.contextGetRequestID(r); id != "" {
+		actor.RequestID = &id
+	}
+	return actor
+}
+
+// selfAuditActor is auditActor for a change to user's own record. Someone
+// using an emailed token on their own account, to activate it or reset its
+// password for example, isn't logged in, so they are recorded as the actor.
+func (app *application) selfAuditActor(r *http.Request, user *data.User) data.AuditActor {
+	actor := app.auditActor(r)
+	if actor.UserID == nil {
+		actor.UserID = &user.ID
+	}
+	return actor
+}
+
+// listAuditEventsHandler handles GET /v1/audit-events
+// Events can be filtered by actor_user_id, action, entity_type, entity_id and
+// request_id, and by time with from and to (RFC 3339, to is exclusive).
+func (app *application) listAuditEventsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.AuditEventFilter
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.ActorUserID = app.readString(qs, "actor_user_id", "")
+	input.Action = app.readString(qs, "action", "")
+	input.EntityType = app.readString(qs, "entity_type", "")
+	input.EntityID = app.readString(qs, "entity_id", "")
+	input.RequestID = app.readString(qs, "request_id", "")
+	input.From = app.readTime(qs, "from", v)
+	input.To = app.readTime(qs, "to", v)
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-created_at")
+	input.Filters.SortSafelist = []string{"created_at", "-created_at"}
+
+	if input.Action != "" {
+		v.Check(validator.In(input.Action, data.AuditActionInsert, data.AuditActionUpdate, data.AuditActionDelete, data.AuditActionOverride), "action", "must be insert, update, delete or override")
+	}
+	if input.EntityType != "" {
+		v.Check(validator.In(input.EntityType, data.AuditEntityTypes...), "entity_type", "is not a known entity type")
+	}
+	if input.From != nil && input.To != nil {
+		v.Check(input.From.Before(*input.To), "to", "must be after from")
+	}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	events, metadata, err := app.models.AuditEvents.GetAll(input.AuditEventFilter, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"audit_events": events, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
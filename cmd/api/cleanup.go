@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// startCleanup deletes expired tokens, stale login failures and orphaned
+// import files every app.config.tokens.cleanupInterval until ctx is
+// cancelled. Like the job workers it is tracked by app.wg.
+func (app *application) startCleanup(ctx context.Context) {
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+
+		ticker := time.NewTicker(app.config.tokens.cleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			app.cleanup()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// cleanup runs one round of deletions, logging rather than returning errors
+// so that the next round still happens.
+func (app *application) cleanup() {
+	tokens, err := app.models.Tokens.DeleteExpired()
+	if err != nil {
+		app.logger.Error(err.Error())
+	}
+
+	window := max(emailLockoutPolicy.Window, ipLockoutPolicy.Window)
+	failures, err := app.models.LoginFailures.DeleteStale(window)
+	if err != nil {
+		app.logger.Error(err.Error())
+	}
+
+	files, err := app.removeOrphanedImportFiles()
+	if err != nil {
+		app.logger.Error(err.Error())
+	}
+
+	if tokens > 0 || failures > 0 || files > 0 {
+		app.logger.Info("deleted expired records", "tokens", tokens, "login_failures", failures, "import_files", files)
+	}
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/amari03/test1/internal/data"
+	"github.com/amari03/test1/internal/validator"
+	"github.com/julienschmidt/httprouter"
+)
+
+// createComplianceRequirementHandler handles POST /v1/compliance-requirements
+func (app *application) createComplianceRequirementHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name          string   `json:"name"`
+		Kind          string   `json:"kind"`
+		RankCode      *string  `json:"rank_code"`
+		Category      *string  `json:"category"`
+		RequiredHours *float64 `json:"required_hours"`
+		CourseID      *string  `json:"course_id"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	req := &data.ComplianceRequirement{
+		Name:          input.Name,
+		Kind:          input.Kind,
+		RankCode:      optionalString(input.RankCode),
+		Category:      optionalString(input.Category),
+		RequiredHours: input.RequiredHours,
+		CourseID:      optionalString(input.CourseID),
+	}
+
+	v := validator.New()
+	if data.ValidateComplianceRequirement(v, req); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.checkComplianceRequirementReferences(v, req)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.ComplianceRequirements.Insert(app.auditActor(r), req)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/compliance-requirements/%s", req.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"requirement": req}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getComplianceRequirementHandler handles GET /v1/compliance-requirements/:id
+func (app *application) getComplianceRequirementHandler(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id := params.ByName("id")
+
+	req, err := app.models.ComplianceRequirements.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"requirement": req}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateComplianceRequirementHandler handles PATCH /v1/compliance-requirements/:id
+// An empty string clears rank_code or category.
+func (app *application) updateComplianceRequirementHandler(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id := params.ByName("id")
+
+	req, err := app.models.ComplianceRequirements.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Name          *string  `json:"name"`
+		Kind          *string  `json:"kind"`
+		RankCode      *string  `json:"rank_code"`
+		Category      *string  `json:"category"`
+		RequiredHours *float64 `json:"required_hours"`
+		CourseID      *string  `json:"course_id"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		req.Name = *input.Name
+	}
+	if input.Kind != nil {
+		req.Kind = *input.Kind
+	}
+	if input.RankCode != nil {
+		req.RankCode = optionalString(input.RankCode)
+	}
+	if input.Category != nil {
+		req.Category = optionalString(input.Category)
+	}
+	if input.RequiredHours != nil {
+		req.RequiredHours = input.RequiredHours
+	}
+	if input.CourseID != nil {
+		req.CourseID = optionalString(input.CourseID)
+	}
+
+	v := validator.New()
+	if data.ValidateComplianceRequirement(v, req); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.checkComplianceRequirementReferences(v, req)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.ComplianceRequirements.Update(app.auditActor(r), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"requirement": req}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteComplianceRequirementHandler handles DELETE /v1/compliance-requirements/:id
+func (app *application) deleteComplianceRequirementHandler(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id := params.ByName("id")
+
+	err := app.models.ComplianceRequirements.Delete(app.auditActor(r), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "compliance requirement successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listComplianceRequirementsHandler handles GET /v1/compliance-requirements
+func (app *application) listComplianceRequirementsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Kind     string
+		RankCode string
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Kind = app.readString(qs, "kind", "")
+	input.RankCode = app.readString(qs, "rank_code", "")
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "name")
+	input.Filters.SortSafelist = []string{"name", "kind", "rank_code", "created_at", "-name", "-kind", "-rank_code", "-created_at"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	reqs, metadata, err := app.models.ComplianceRequirements.GetAll(input.Kind, input.RankCode, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"requirements": reqs, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listComplianceHandler handles GET /v1/compliance
+// It evaluates a page of officers against every requirement for their rank in
+// the given year (the current year by default). Officers short of a
+// requirement are only at risk within the configured window before the end of
+// the year.
+func (app *application) listComplianceHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Year        int
+		RankCode    string
+		RegionID    string
+		FormationID string
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	now := time.Now()
+	currentYear := now.Year()
+
+	input.Year = app.readInt(qs, "year", currentYear, v)
+	input.RankCode = app.readString(qs, "rank_code", "")
+	input.RegionID = app.readString(qs, "region_id", "")
+	input.FormationID = app.readString(qs, "formation_id", "")
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "last_name")
+	input.Filters.SortSafelist = []string{"id", "first_name", "last_name", "rank_code", "-id", "-first_name", "-last_name", "-rank_code"}
+
+	v.Check(input.Year >= 2000, "year", "must be 2000 or later")
+	v.Check(input.Year <= currentYear, "year", "must not be in the future")
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	officers, metadata, err := app.models.Officers.GetAll("", "", input.RankCode, input.RegionID, input.FormationID, "", false, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	requirements, err := app.models.ComplianceRequirements.List()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	officerIDs := make([]string, len(officers))
+	for i, officer := range officers {
+		officerIDs[i] = officer.ID
+	}
+
+	progress, err := app.models.ComplianceRequirements.GetProgress(officerIDs, input.Year)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	deadline := time.Date(input.Year+1, time.January, 1, 0, 0, 0, 0, time.UTC)
+	untilDeadline := deadline.Sub(now)
+
+	results := make([]*data.OfficerCompliance, len(officers))
+	for i, officer := range officers {
+		results[i] = data.EvaluateCompliance(officer, requirements, progress[officer.ID], untilDeadline, app.config.compliance.atRiskWindow)
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"year": input.Year, "compliance": results, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// checkComplianceRequirementReferences checks that the requirement's rank and
+// course exist, and that the course is mandatory, recording any problems in
+// the validator.
+func (app *application) checkComplianceRequirementReferences(v *validator.Validator, req *data.ComplianceRequirement) error {
+	if req.RankCode != nil {
+		err := app.checkRankCode(v, *req.RankCode)
+		if err != nil {
+			return err
+		}
+	}
+
+	if req.CourseID != nil {
+		course, err := app.models.Courses.Get(*req.CourseID)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("course_id", "must reference an existing course")
+		case err != nil:
+			return err
+		case course.Category != "mandatory":
+			v.AddError("course_id", "must reference a mandatory course")
+		}
+	}
+
+	return nil
+}
@@ -14,6 +14,16 @@ type contextKey string
 // userContextKey is the key we'll use to store the User struct in the context.
 const userContextKey = contextKey("user")
 
+// authenticationTokenContextKey is the key for the plaintext bearer token the
+// user authenticated with.
+const authenticationTokenContextKey = contextKey("authenticationToken")
+
+// apiKeyContextKey is the key for the API key a request was made with.
+const apiKeyContextKey = contextKey("apiKey")
+
+// requestIDContextKey is the key for the ID given to each request.
+const requestIDContextKey = contextKey("requestID")
+
 // contextSetUser returns a new request with the provided User struct added to the context.
 func (app *application) contextSetUser(r *http.Request, user *data.User) *http.Request {
 	ctx := context.WithValue(r.Context(), userContextKey, user)
@@ -29,4 +39,44 @@ func (app *application) contextGetUser(r *http.Request) *data.User {
 		panic("missing user value in request context")
 	}
 	return user
-}
\ No newline at end of file
+}
+
+// contextSetAuthenticationToken returns a new request with the bearer token
+// the user authenticated with added to the context.
+func (app *application) contextSetAuthenticationToken(r *http.Request, token string) *http.Request {
+	ctx := context.WithValue(r.Context(), authenticationTokenContextKey, token)
+	return r.WithContext(ctx)
+}
+
+// contextGetAuthenticationToken returns the bearer token the user
+// authenticated with, or "" for anonymous requests.
+func (app *application) contextGetAuthenticationToken(r *http.Request) string {
+	token, _ := r.Context().Value(authenticationTokenContextKey).(string)
+	return token
+}
+
+// contextSetAPIKey returns a new request with the API key it was made with
+// added to the context.
+func (app *application) contextSetAPIKey(r *http.Request, key *data.APIKey) *http.Request {
+	ctx := context.WithValue(r.Context(), apiKeyContextKey, key)
+	return r.WithContext(ctx)
+}
+
+// contextGetAPIKey returns the API key the request was made with, or nil if it
+// wasn't made with one.
+func (app *application) contextGetAPIKey(r *http.Request) *data.APIKey {
+	key, _ := r.Context().Value(apiKeyContextKey).(*data.APIKey)
+	return key
+}
+// contextSetRequestID returns a new request with its ID added to the context.
+func (app *application) contextSetRequestID(r *http.Request, id string) *http.Request {
+	ctx := context.WithValue(r.Context(), requestIDContextKey, id)
+	return r.WithContext(ctx)
+}
+
+// contextGetRequestID returns the request's ID, or "" if it hasn't been
+// given one.
+func (app *application) contextGetRequestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDContextKey).(string)
+	return id
+}
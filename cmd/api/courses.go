@@ -45,7 +45,7 @@ func (app *application) createCourseHandler(w http.ResponseWriter, r *http.Reque
         return
     }
 
-    err = app.models.Courses.Insert(course)
+    err = app.models.Courses.Insert(app.auditActor(r), course)
     if err != nil {
         app.serverErrorResponse(w, r, err)
         return
@@ -122,7 +122,7 @@ func (app *application) updateCourseHandler(w http.ResponseWriter, r *http.Reque
         return
     }
 
-    err = app.models.Courses.Update(course)
+    err = app.models.Courses.Update(app.auditActor(r), course)
     if err != nil {
         switch {
         case errors.Is(err, data.ErrEditConflict):
@@ -143,11 +143,13 @@ func (app *application) deleteCourseHandler(w http.ResponseWriter, r *http.Reque
     params := httprouter.ParamsFromContext(r.Context())
     id := params.ByName("id")
 
-    err := app.models.Courses.Delete(id)
+    err := app.models.Courses.Delete(app.auditActor(r), id)
     if err != nil {
         switch {
         case errors.Is(err, data.ErrRecordNotFound):
             app.notFoundResponse(w, r)
+        case errors.Is(err, data.ErrRecordInUse):
+            app.recordInUseResponse(w, r, "the course has sessions with attendance and cannot be deleted")
         default:
             app.serverErrorResponse(w, r, err)
         }
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/amari03/test1/internal/data"
+	"github.com/amari03/test1/internal/validator"
+)
+
+// requestEmailChange sends a confirmation token to the user's pending email
+// address, and tells the current address about the change so that its owner
+// notices if it wasn't them. Any earlier confirmation token stops working.
+func (app *application) requestEmailChange(user *data.User) error {
+	err := app.models.Tokens.DeleteAllForUser(data.ScopeEmailChange, user.ID)
+	if err != nil {
+		return err
+	}
+
+	token, err := app.models.Tokens.New(user.ID, app.config.tokens.emailChangeTTL, data.ScopeEmailChange)
+	if err != nil {
+		return err
+	}
+
+	err = app.enqueueTokenEmail(*user.PendingEmail, "email_change.tmpl", map[string]interface{}{
+		"expiresIn": humanDuration(app.config.tokens.emailChangeTTL),
+	}, "emailChangeToken", token)
+	if err != nil {
+		return err
+	}
+
+	return app.enqueueEmail(user.Email, "email_change_notice.tmpl", map[string]interface{}{
+		"newEmail": *user.PendingEmail,
+	})
+}
+
+// confirmEmailChangeHandler handles PUT /v1/users/email
+// The token sent to the new address proves that the user owns it, so the
+// change is applied.
+func (app *application) confirmEmailChangeHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		TokenPlaintext string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if data.ValidateTokenPlaintext(v, input.TokenPlaintext); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetForToken(data.ScopeEmailChange, input.TokenPlaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("token", "invalid or expired email change token")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Users.ConfirmEmailChange(app.selfAuditActor(r, user), user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateEmail):
+			v.AddError("email", "a user with this email address already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Tokens.DeleteAllForUser(data.ScopeEmailChange, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
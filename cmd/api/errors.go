@@ -3,7 +3,12 @@ package main
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/amari03/test1/internal/data"
 )
 
 // errorResponse is a generic helper for sending JSON-formatted error messages.
@@ -47,7 +52,7 @@ func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.
 
 // logError is a helper to log errors with request details.
 func (app *application) logError(r *http.Request, err error) {
-	app.logger.Error(err.Error(), "request_method", r.Method, "request_url", r.URL.String())
+	app.logger.Error(err.Error(), "request_method", r.Method, "request_url", r.URL.String(), "request_id", app.contextGetRequestID(r))
 }
 
 func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Request) {
@@ -75,4 +80,74 @@ func (app *application) authenticationRequiredResponse(w http.ResponseWriter, r
 func (app *application) inactiveAccountResponse(w http.ResponseWriter, r *http.Request) {
     message := "your user account must be activated to access this resource"
     app.errorResponse(w, r, http.StatusForbidden, message)
-}
\ No newline at end of file
+}
+
+// recordInUseResponse is used for 409 Conflict responses when a record cannot
+// be deleted because other records still reference it.
+func (app *application) recordInUseResponse(w http.ResponseWriter, r *http.Request, message string) {
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "your user account doesn't have the necessary permissions to access this resource"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+func (app *application) invalidRefreshTokenResponse(w http.ResponseWriter, r *http.Request) {
+	message := "invalid or expired refresh token"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (app *application) invalidMFACodeResponse(w http.ResponseWriter, r *http.Request) {
+	message := "invalid two-factor code, please log in again"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (app *application) mfaRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	message := "your role requires two-factor authentication, set it up at /v1/mfa/totp"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+func (app *application) tooManyLoginAttemptsResponse(w http.ResponseWriter, r *http.Request, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	message := "too many failed login attempts, please try again later"
+	app.errorResponse(w, r, http.StatusTooManyRequests, message)
+}
+
+func (app *application) registrationClosedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "registration is closed, please ask an administrator for an invitation"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+// scheduleConflictResponse lists the sessions a booking clashes with. Admins
+// can book it anyway by repeating the request with ?force=true.
+func (app *application) scheduleConflictResponse(w http.ResponseWriter, r *http.Request, conflicts []data.ScheduleConflict) {
+	env := envelope{
+		"error":     "this clashes with other sessions",
+		"conflicts": conflicts,
+	}
+	err := app.writeJSON(w, http.StatusConflict, env, nil)
+	if err != nil {
+		app.logError(r, err)
+		w.WriteHeader(500)
+	}
+}
+
+// attendanceLockedResponse is sent when attendance is changed for a session
+// that has been completed.
+func (app *application) attendanceLockedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the session has been completed, so its attendance can no longer be changed"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+// enrollmentClosedResponse is sent when an officer is enrolled in a session
+// that is under way, completed or cancelled.
+func (app *application) enrollmentClosedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the session is no longer taking enrollments"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+func (app *application) seriesCancelledResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the session series has been cancelled"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
@@ -15,6 +15,7 @@ func (app *application) createFacilitatorHandler(w http.ResponseWriter, r *http.
         FirstName string  `json:"first_name"`
         LastName  string  `json:"last_name"`
         Notes     *string `json:"notes"`
+        Email     *string `json:"email"`
     }
 
     err := app.readJSON(w, r, &input)
@@ -27,6 +28,7 @@ func (app *application) createFacilitatorHandler(w http.ResponseWriter, r *http.
         FirstName: input.FirstName,
         LastName:  input.LastName,
         Notes:     input.Notes,
+        Email:     optionalString(input.Email),
     }
 
     v := validator.New()
@@ -35,7 +37,7 @@ func (app *application) createFacilitatorHandler(w http.ResponseWriter, r *http.
         return
     }
 
-    err = app.models.Facilitators.Insert(facilitator)
+    err = app.models.Facilitators.Insert(app.auditActor(r), facilitator)
     if err != nil {
         app.serverErrorResponse(w, r, err)
         return
@@ -90,6 +92,7 @@ func (app *application) updateFacilitatorHandler(w http.ResponseWriter, r *http.
         FirstName *string `json:"first_name"`
         LastName  *string `json:"last_name"`
         Notes     *string `json:"notes"`
+        Email     *string `json:"email"`
     }
 
     err = app.readJSON(w, r, &input)
@@ -107,6 +110,10 @@ func (app *application) updateFacilitatorHandler(w http.ResponseWriter, r *http.
     if input.Notes != nil {
         facilitator.Notes = input.Notes
     }
+    // An empty string clears the email address.
+    if input.Email != nil {
+        facilitator.Email = optionalString(input.Email)
+    }
 
     v := validator.New()
     if data.ValidateFacilitator(v, facilitator); !v.Valid() {
@@ -114,7 +121,7 @@ func (app *application) updateFacilitatorHandler(w http.ResponseWriter, r *http.
         return
     }
 
-    err = app.models.Facilitators.Update(facilitator)
+    err = app.models.Facilitators.Update(app.auditActor(r), facilitator)
     if err != nil {
         switch {
         case errors.Is(err, data.ErrEditConflict):
@@ -135,7 +142,7 @@ func (app *application) deleteFacilitatorHandler(w http.ResponseWriter, r *http.
     params := httprouter.ParamsFromContext(r.Context())
     id := params.ByName("id")
 
-    err := app.models.Facilitators.Delete(id)
+    err := app.models.Facilitators.Delete(app.auditActor(r), id)
     if err != nil {
         switch {
         case errors.Is(err, data.ErrRecordNotFound):
@@ -9,6 +9,7 @@ import (
 	"io"
 	"strings"
 	"net/url"
+	"time"
 	"github.com/amari03/test1/internal/validator"
 
 	"github.com/julienschmidt/httprouter"
@@ -113,21 +114,63 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	return i
 }
 
-// background runs an arbitrary function in a background goroutine.
-// It increments the WaitGroup counter before starting, and decrements it when the goroutine finishes.
-// It also recovers from any panics to prevent the application from crashing.
-func (app *application) background(fn func()) {
-    app.wg.Add(1)
+func (app *application) readBool(qs url.Values, key string, defaultValue bool, v *validator.Validator) bool {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be a boolean value")
+		return defaultValue
+	}
+	return b
+}
+
+// readTime reads an optional RFC 3339 timestamp from the query string.
+func (app *application) readTime(qs url.Values, key string, v *validator.Validator) *time.Time {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		v.AddError(key, "must be an RFC 3339 timestamp, such as 2024-01-31T09:00:00Z")
+		return nil
+	}
+	return &t
+}
 
-    go func() {
-        defer app.wg.Done()
+// optionalString normalizes an optional string from a request body: an empty
+// string is treated the same as a missing value. This lets clients clear an
+// optional reference in a PATCH request by sending "".
+func optionalString(s *string) *string {
+	if s == nil || *s == "" {
+		return nil
+	}
+	return s
+}
 
-        defer func() {
-            if err := recover(); err != nil {
-                app.logger.Error(fmt.Sprintf("%v", err))
-            }
-        }()
+// humanDuration formats a token lifetime for an email, e.g. "3 days" or "45
+// minutes".
+func humanDuration(d time.Duration) string {
+	unit := func(n int64, name string) string {
+		if n == 1 {
+			return "1 " + name
+		}
+		return fmt.Sprintf("%d %ss", n, name)
+	}
 
-        fn()
-    }()
-}
\ No newline at end of file
+	switch {
+	case d >= 24*time.Hour && d%(24*time.Hour) == 0:
+		return unit(int64(d/(24*time.Hour)), "day")
+	case d >= time.Hour && d%time.Hour == 0:
+		return unit(int64(d/time.Hour), "hour")
+	case d >= time.Minute && d%time.Minute == 0:
+		return unit(int64(d/time.Minute), "minute")
+	default:
+		return d.String()
+	}
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"encoding/csv"
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/amari03/test1/internal/data"
 	"github.com/amari03/test1/internal/validator"
@@ -11,31 +13,70 @@ import (
 )
 
 // createImportJobHandler handles POST /v1/import-jobs
+// It expects a multipart form with a "type" field, a CSV "file" and an
+// optional "dry_run" flag. The file is saved and processed by the job queue;
+// poll the import job to see the outcome.
 func (app *application) createImportJobHandler(w http.ResponseWriter, r *http.Request) {
-	var input struct {
-		Type string `json:"type"`
-		// FilePath is removed
-	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize)
 
-	err := app.readJSON(w, r, &input)
+	err := r.ParseMultipartForm(1 << 20)
 	if err != nil {
-		app.badRequestResponse(w, r, err)
+		var maxBytesError *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesError):
+			app.badRequestResponse(w, r, fmt.Errorf("body must not be larger than %d bytes", maxImportFileSize))
+		default:
+			app.badRequestResponse(w, r, errors.New("body must be a multipart form with a type and a file"))
+		}
 		return
 	}
+	defer r.MultipartForm.RemoveAll()
 
-	// Following the pattern from your courses.go handler
 	job := &data.ImportJob{
-		Type:            input.Type,
-		CreatedByUserID: "1a7a5180-4303-4318-8789-1a007f339eec", // Dummy user
+		Type:            r.FormValue("type"),
+		CreatedByUserID: app.contextGetUser(r).ID,
 	}
 
 	v := validator.New()
-	if data.ValidateImportJob(v, job); !v.Valid() {
+	data.ValidateImportJob(v, job)
+
+	if dryRun := r.FormValue("dry_run"); dryRun != "" {
+		job.DryRun, err = strconv.ParseBool(dryRun)
+		if err != nil {
+			v.AddError("dry_run", "must be a boolean value")
+		}
+	}
+
+	file, _, err := r.FormFile("file")
+	switch {
+	case errors.Is(err, http.ErrMissingFile):
+		v.AddError("file", "must be provided")
+	case err != nil:
+		app.badRequestResponse(w, r, err)
+		return
+	default:
+		defer file.Close()
+	}
+
+	if !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.ImportJobs.Insert(job)
+	job.FilePath, err = app.saveImportFile(file)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.ImportJobs.Insert(app.auditActor(r), job)
+	if err != nil {
+		app.removeImportFile(job.FilePath)
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.enqueueJob(data.JobKindImport, importPayload{ImportJobID: job.ID})
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -108,4 +149,77 @@ func (app *application) listImportJobsHandler(w http.ResponseWriter, r *http.Req
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
+}
+
+// listImportJobErrorsHandler handles GET /v1/import-jobs/:id/errors
+// Pass format=csv to download every error as a CSV file instead of a page of
+// JSON.
+func (app *application) listImportJobErrorsHandler(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id := params.ByName("id")
+
+	var input struct {
+		Format string
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Format = app.readString(qs, "format", "json")
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 100, v)
+	input.Filters.Sort = "row"
+	input.Filters.SortSafelist = []string{"row"}
+
+	v.Check(validator.In(input.Format, "json", "csv"), "format", "must be json or csv")
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	job, err := app.models.ImportJobs.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if input.Format == "csv" {
+		rowErrors, err := app.models.ImportJobs.GetAllErrors(job.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="import-job-%s-errors.csv"`, job.ID))
+
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"row", "column", "message"})
+		for _, rowError := range rowErrors {
+			cw.Write([]string{strconv.Itoa(rowError.Row), rowError.Column, rowError.Message})
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			app.logger.Error(err.Error(), "import_job_id", job.ID)
+		}
+		return
+	}
+
+	rowErrors, metadata, err := app.models.ImportJobs.GetErrors(job.ID, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"errors": rowErrors, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
 }
\ No newline at end of file
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/amari03/test1/internal/data"
+)
+
+// maxImportFileSize is the largest file accepted by POST /v1/import-jobs.
+const maxImportFileSize = 10 << 20
+
+// orphanedImportFileAge is how old a file in the import directory must be
+// before cleanup deletes it for having no unfinished job. A file is saved
+// before its job is created, so a new one is left alone.
+const orphanedImportFileAge = time.Hour
+
+// saveImportFile copies an uploaded file into the import directory and returns
+// the path it was saved to.
+func (app *application) saveImportFile(src io.Reader) (string, error) {
+	err := os.MkdirAll(app.config.imports.dir, 0o700)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp(app.config.imports.dir, "import-*.csv")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, src)
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+
+	return f.Name(), f.Close()
+}
+
+// runImportJob processes an import job, moving it to running and then to
+// succeeded or failed. Problems with the file are recorded on the job and as
+// row errors. Any other error is returned so that the queue retries the job,
+// until the final attempt, when the job is failed with a generic message.
+func (app *application) runImportJob(job *data.ImportJob, finalAttempt bool) error {
+	if job.Status == data.ImportStatusSucceeded {
+		return nil
+	}
+
+	err := app.models.ImportJobs.Start(job)
+	if err != nil {
+		// ErrEditConflict means the job has succeeded in the meantime.
+		if errors.Is(err, data.ErrEditConflict) {
+			return nil
+		}
+		return err
+	}
+
+	rowsImported, runErr := app.importFile(job)
+
+	var errorMessage *string
+	var rowErrors []data.ImportRowError
+	var importErr *data.ImportError
+
+	switch {
+	case runErr == nil:
+	case errors.As(runErr, &importErr):
+		msg := importErr.Error()
+		errorMessage = &msg
+		rowErrors = importErr.Rows
+		runErr = nil
+	case errors.Is(runErr, fs.ErrNotExist):
+		// Cleanup deleted the file after the job was dead-lettered, so
+		// retrying it can't succeed.
+		msg := "the uploaded file is no longer available; upload it again"
+		errorMessage = &msg
+		runErr = nil
+	case !finalAttempt:
+		// Leave the job running for the next attempt.
+		return runErr
+	default:
+		msg := "the import could not be completed because of a server error"
+		errorMessage = &msg
+	}
+
+	err = app.models.ImportJobs.Finish(job, rowsImported, errorMessage, rowErrors)
+	if err != nil {
+		return err
+	}
+
+	// The job won't run again, so its copy of the officers' details can go.
+	app.removeImportFile(job.FilePath)
+	return runErr
+}
+
+// importFile opens the job's file and imports it.
+func (app *application) importFile(job *data.ImportJob) (int, error) {
+	f, err := os.Open(job.FilePath)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	return app.models.ImportJobs.Run(job, f)
+}
+
+// removeImportFile deletes an uploaded file, logging rather than returning
+// any error as the job it belonged to has already finished.
+func (app *application) removeImportFile(path string) {
+	err := os.Remove(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		app.logger.Error(err.Error(), "file", path)
+	}
+}
+
+// removeOrphanedImportFiles deletes the files in the import directory that no
+// unfinished job needs, such as those of jobs that were dead-lettered after
+// they stopped responding, and returns how many it deleted.
+func (app *application) removeOrphanedImportFiles() (int, error) {
+	entries, err := os.ReadDir(app.config.imports.dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	paths, err := app.models.ImportJobs.UnfinishedFilePaths()
+	if err != nil {
+		return 0, err
+	}
+	unfinished := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		unfinished[filepath.Clean(path)] = true
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		path := filepath.Join(app.config.imports.dir, entry.Name())
+		if entry.IsDir() || unfinished[path] {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < orphanedImportFileAge {
+			continue
+		}
+
+		err = os.Remove(path)
+		if err != nil {
+			app.logger.Error(err.Error(), "file", path)
+			continue
+		}
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/amari03/test1/internal/data"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Failed logins are counted per email address and per IP address. An IP is
+// allowed more failures, since several users can share one.
+var (
+	emailLockoutPolicy = data.LockoutPolicy{
+		FreeAttempts:    3,
+		MaxAttempts:     10,
+		MaxDelay:        30 * time.Second,
+		LockoutDuration: 15 * time.Minute,
+		Window:          time.Hour,
+	}
+	ipLockoutPolicy = data.LockoutPolicy{
+		FreeAttempts:    10,
+		MaxAttempts:     50,
+		MaxDelay:        30 * time.Second,
+		LockoutDuration: 15 * time.Minute,
+		Window:          time.Hour,
+	}
+)
+
+// clientIP returns the IP address a request came from.
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// checkLoginThrottle turns away a login for an email address or IP that has
+// failed too often recently, before any password hashing is done. If it
+// returns false a response has been sent.
+func (app *application) checkLoginThrottle(w http.ResponseWriter, r *http.Request, email string) bool {
+	wait, err := app.models.LoginFailures.Wait(data.LoginEmailKey(email), data.LoginIPKey(clientIP(r)))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return false
+	}
+	if wait > 0 {
+		app.tooManyLoginAttemptsResponse(w, r, wait)
+		return false
+	}
+	return true
+}
+
+// recordLoginFailure counts a failed login against the email address and the
+// IP. user is nil if there is no account with the email address; otherwise
+// its owner is emailed when it gets locked.
+func (app *application) recordLoginFailure(r *http.Request, email string, user *data.User) error {
+	ip := clientIP(r)
+
+	lockedUntil, err := app.models.LoginFailures.RecordFailure(data.LoginEmailKey(email), emailLockoutPolicy)
+	if err != nil {
+		return err
+	}
+	if lockedUntil != nil {
+		app.logger.Warn("account locked after failed logins", "email", email, "ip", ip)
+		if user != nil {
+			err = app.enqueueEmail(user.Email, "account_locked.tmpl", map[string]interface{}{
+				"ipAddress":   ip,
+				"lockedUntil": lockedUntil.UTC().Format("2 Jan 2006 15:04 MST"),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	lockedUntil, err = app.models.LoginFailures.RecordFailure(data.LoginIPKey(ip), ipLockoutPolicy)
+	if lockedUntil != nil {
+		app.logger.Warn("IP address locked out after failed logins", "ip", ip)
+	}
+	return err
+}
+
+// completeLogin issues the authentication and refresh tokens once a user has
+// passed every step of logging in.
+func (app *application) completeLogin(w http.ResponseWriter, r *http.Request, user *data.User) {
+	err := app.models.LoginFailures.Reset(data.LoginEmailKey(user.Email))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Users.RecordLogin(user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	token, refreshToken, err := app.models.Tokens.NewLogin(user.ID, app.config.tokens.authenticationTTL, app.config.tokens.refreshTTL)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"authentication_token": token, "refresh_token": refreshToken}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// unlockUserHandler handles POST /v1/users/:id/unlock
+// It lets an admin clear a user's failed logins, ending a lockout early.
+func (app *application) unlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id := params.ByName("id")
+
+	user, err := app.models.Users.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.LoginFailures.Reset(data.LoginEmailKey(user.Email))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "the account has been unlocked"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
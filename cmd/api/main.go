@@ -4,8 +4,11 @@ import (
     "context"
     "database/sql"
     "flag"
+    "fmt"
     "log/slog"
     "os"
+    "path/filepath"
+    "slices"
     "time"
     "sync"
     "strings"
@@ -33,6 +36,36 @@ type config struct {
     cors struct {
         trustedOrigins []string
     }
+    compliance struct {
+        atRiskWindow time.Duration
+    }
+    imports struct {
+        dir string
+    }
+    jobs struct {
+        workers     int
+        maxAttempts int
+    }
+    mfa struct {
+        issuer        string
+        requiredRoles []string
+    }
+    registration struct {
+        open bool
+    }
+    // How long each kind of token lasts, and how often expired ones are
+    // deleted.
+    tokens struct {
+        activationTTL     time.Duration
+        authenticationTTL time.Duration
+        refreshTTL        time.Duration
+        passwordResetTTL  time.Duration
+        mfaPendingTTL     time.Duration
+        invitationTTL     time.Duration
+        emailChangeTTL    time.Duration
+        cleanupInterval   time.Duration
+    }
+    passwords data.PasswordConfig
 }
 
 type application struct {
@@ -62,10 +95,51 @@ func main() {
 		return nil
 	})
 
+    flag.DurationVar(&cfg.compliance.atRiskWindow, "compliance-at-risk-window", 90*24*time.Hour, "How long before the end of the year officers short of a requirement are at risk")
+
+    flag.StringVar(&cfg.imports.dir, "import-dir", filepath.Join(os.TempDir(), "test1-imports"), "Directory for uploaded import files")
+
+    flag.IntVar(&cfg.jobs.workers, "jobs-workers", 4, "Number of background job workers")
+    flag.IntVar(&cfg.jobs.maxAttempts, "jobs-max-attempts", 5, "Attempts before a background job is dead-lettered")
+
+    flag.StringVar(&cfg.mfa.issuer, "mfa-issuer", "National Training API", "Issuer name shown in authenticator apps")
+    flag.Func("mfa-required-roles", "Roles that must use two-factor authentication (space-separated)", func(val string) error {
+        for _, role := range strings.Fields(val) {
+            if !slices.Contains(data.Roles, role) {
+                return fmt.Errorf("unknown role %q", role)
+            }
+        }
+        cfg.mfa.requiredRoles = strings.Fields(val)
+        return nil
+    })
+
+    flag.BoolVar(&cfg.registration.open, "registration-open", true, "Allow anyone to register; if false, users must be invited by an admin")
+
+    // Authentication tokens are short lived so that one left behind on a
+    // shared terminal soon stops working. Clients stay logged in by exchanging
+    // their refresh token for a new pair before the authentication token
+    // expires.
+    flag.DurationVar(&cfg.tokens.activationTTL, "token-activation-ttl", 3*24*time.Hour, "Lifetime of account activation tokens")
+    flag.DurationVar(&cfg.tokens.authenticationTTL, "token-authentication-ttl", 15*time.Minute, "Lifetime of authentication tokens")
+    flag.DurationVar(&cfg.tokens.refreshTTL, "token-refresh-ttl", 30*24*time.Hour, "Lifetime of refresh tokens")
+    flag.DurationVar(&cfg.tokens.passwordResetTTL, "token-password-reset-ttl", 45*time.Minute, "Lifetime of password reset tokens")
+    flag.DurationVar(&cfg.tokens.mfaPendingTTL, "token-mfa-pending-ttl", 5*time.Minute, "Time allowed to enter a two-factor code after giving a password")
+    flag.DurationVar(&cfg.tokens.invitationTTL, "token-invitation-ttl", 7*24*time.Hour, "Lifetime of user invitations")
+    flag.DurationVar(&cfg.tokens.emailChangeTTL, "token-email-change-ttl", 24*time.Hour, "Time allowed to confirm a new email address")
+    flag.DurationVar(&cfg.tokens.cleanupInterval, "token-cleanup-interval", time.Hour, "How often expired tokens and stale login failures are deleted")
+
+    cfg.passwords.RegisterFlags(flag.CommandLine)
+
     flag.Parse()
 
     logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
+    err := cfg.passwords.Apply()
+    if err != nil {
+        logger.Error(err.Error())
+        os.Exit(1)
+    }
+
     db, err := openDB(cfg)
     if err != nil {
         logger.Error(err.Error())
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/amari03/test1/internal/data"
+	"github.com/amari03/test1/internal/validator"
+	"github.com/julienschmidt/httprouter"
+)
+
+// mfaRequired reports whether the user's role must use two-factor
+// authentication.
+func (app *application) mfaRequired(user *data.User) bool {
+	return slices.Contains(app.config.mfa.requiredRoles, user.Role)
+}
+
+// createMFATokenHandler handles POST /v1/tokens/mfa
+// It is the second step of logging in for users with two-factor
+// authentication: the mfa-pending token from the first step, along with a
+// code from their app or a recovery code, is exchanged for the usual
+// authentication and refresh tokens. A wrong code uses up the pending token,
+// so the user has to give their password again, and counts as a failed
+// login.
+func (app *application) createMFATokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		TokenPlaintext string `json:"token"`
+		Code           string `json:"code"`
+		RecoveryCode   string `json:"recovery_code"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	data.ValidateTokenPlaintext(v, input.TokenPlaintext)
+	v.Check(input.Code != "" || input.RecoveryCode != "", "code", "must be provided")
+	v.Check(input.Code == "" || input.RecoveryCode == "", "code", "must not be provided along with a recovery code")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetForToken(data.ScopeMFAPending, input.TokenPlaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("token", "invalid or expired two-factor token")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var ok bool
+	if input.Code != "" {
+		ok, err = app.models.MFA.CheckTOTP(user.ID, input.Code, time.Now())
+	} else {
+		ok, err = app.models.MFA.UseRecoveryCode(user.ID, input.RecoveryCode)
+	}
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Tokens.DeleteAllForUser(data.ScopeMFAPending, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !ok {
+		err = app.recordLoginFailure(r, user.Email, user)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		app.invalidMFACodeResponse(w, r)
+		return
+	}
+
+	app.completeLogin(w, r, user)
+}
+
+// beginTOTPHandler handles POST /v1/mfa/totp
+// It generates a new secret for the current user. Two-factor authentication
+// isn't turned on until they confirm a code from their app.
+func (app *application) beginTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.MFAEnabled {
+		app.recordInUseResponse(w, r, "two-factor authentication is already enabled")
+		return
+	}
+
+	secret, err := data.NewTOTPSecret()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.MFA.BeginTOTP(user.ID, secret)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.recordInUseResponse(w, r, "two-factor authentication is already enabled")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	totp := envelope{
+		"secret":           secret,
+		"provisioning_uri": data.TOTPProvisioningURI(app.config.mfa.issuer, user.Email, secret),
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"totp": totp}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// confirmTOTPHandler handles POST /v1/mfa/totp/confirm
+// A code from the user's app turns two-factor authentication on. The
+// response holds their recovery codes, which are not shown again.
+func (app *application) confirmTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.MFAEnabled {
+		app.recordInUseResponse(w, r, "two-factor authentication is already enabled")
+		return
+	}
+
+	if !app.checkTOTPCode(w, r, user) {
+		return
+	}
+
+	codes, err := app.models.MFA.EnableTOTP(app.auditActor(r), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"recovery_codes": codes}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// replaceRecoveryCodesHandler handles POST /v1/mfa/recovery-codes
+// It replaces the current user's recovery codes, for when they have used or
+// lost them.
+func (app *application) replaceRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if !user.MFAEnabled {
+		app.recordInUseResponse(w, r, "two-factor authentication is not enabled")
+		return
+	}
+
+	if !app.checkTOTPCode(w, r, user) {
+		return
+	}
+
+	codes, err := app.models.MFA.ReplaceRecoveryCodes(app.auditActor(r), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"recovery_codes": codes}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// disableTOTPHandler handles DELETE /v1/mfa/totp
+// Users can turn two-factor authentication off with a current code, unless
+// their role requires it.
+func (app *application) disableTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if app.mfaRequired(user) {
+		app.errorResponse(w, r, http.StatusForbidden, "your role requires two-factor authentication")
+		return
+	}
+
+	if !app.checkTOTPCode(w, r, user) {
+		return
+	}
+
+	err := app.models.MFA.Disable(app.auditActor(r), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "two-factor authentication has been disabled"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// resetUserMFAHandler handles DELETE /v1/users/:id/mfa
+// Admins can turn off two-factor authentication for a user who has lost both
+// their device and their recovery codes, so that they can enrol again.
+func (app *application) resetUserMFAHandler(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id := params.ByName("id")
+
+	err := app.models.MFA.Disable(app.auditActor(r), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "two-factor authentication has been reset"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// checkTOTPCode reads {"code": "..."} from the request body and checks it
+// against the user's secret. If it returns false a response has been sent.
+func (app *application) checkTOTPCode(w http.ResponseWriter, r *http.Request, user *data.User) bool {
+	var input struct {
+		Code string `json:"code"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return false
+	}
+
+	v := validator.New()
+	if v.Check(input.Code != "", "code", "must be provided"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return false
+	}
+
+	ok, err := app.models.MFA.CheckTOTP(user.ID, input.Code, time.Now())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return false
+	}
+	if !ok {
+		v.AddError("code", "is incorrect or has already been used")
+		app.failedValidationResponse(w, r, v.Errors)
+		return false
+	}
+
+	return true
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"crypto/rand"
 	"fmt"
 	"net"
 	"net/http"
@@ -14,6 +15,38 @@ import (
 	"github.com/amari03/test1/internal/validator"
 )
 
+// requestID gives every request an ID, which is sent back in the
+// X-Request-ID header, logged with errors and recorded in the audit log. An ID
+// already set by a proxy in front of the API is kept if it looks sensible, so
+// that its logs can be matched up with ours.
+func (app *application) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = rand.Text()
+		}
+
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, app.contextSetRequestID(r, id))
+	})
+}
+
+// validRequestID reports whether a request ID from a client is short and only
+// uses letters, digits, dashes, underscores and dots.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // recoverPanic is middleware that recovers from panics and returns a 500 error.
 func (app *application) recoverPanic(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -96,12 +129,16 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		// 3. Check if the header is in the "Bearer <token>" format.
+		// 3. Check if the header is in the "Bearer <token>" or "ApiKey <key>" format.
 		headerParts := strings.Split(authorizationHeader, " ")
-		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+		if len(headerParts) != 2 || (headerParts[0] != "Bearer" && headerParts[0] != "ApiKey") {
 			app.invalidAuthenticationTokenResponse(w, r)
 			return
 		}
+		if headerParts[0] == "ApiKey" {
+			app.authenticateAPIKey(w, r, next, headerParts[1])
+			return
+		}
 		token := headerParts[1]
 
 		// 4. Validate the token.
@@ -123,12 +160,44 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		// 6. Add the user information to the request context.
+		err = app.models.Tokens.Touch(data.ScopeAuthentication, token)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		// 6. Add the user and their token to the request context.
 		r = app.contextSetUser(r, user)
+		r = app.contextSetAuthenticationToken(r, token)
 		next.ServeHTTP(w, r)
 	})
 }
 
+// authenticateAPIKey authenticates a request made with an API key as the
+// key's owner.
+func (app *application) authenticateAPIKey(w http.ResponseWriter, r *http.Request, next http.Handler, plaintext string) {
+	key, user, err := app.models.APIKeys.GetForKey(plaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.APIKeys.Touch(key.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	r = app.contextSetUser(r, user)
+	r = app.contextSetAPIKey(r, key)
+	next.ServeHTTP(w, r)
+}
+
 // requireAuthenticatedUser checks if the user is authenticated (i.e., not anonymous).
 func (app *application) requireAuthenticatedUser(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -164,6 +233,55 @@ func (app *application) requireActivatedUser(next http.Handler) http.Handler {
 	return app.requireAuthenticatedUser(fn)
 }
 
+// requirePermission checks that the user is activated and that their role
+// grants the given permission code. Requests made with an API key also need
+// the key to have been given the permission. Users whose role requires
+// two-factor authentication can't do anything until they have set it up.
+func (app *application) requirePermission(code string, next http.Handler) http.Handler {
+	fn := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
+		if !app.hasPermission(r, code) {
+			app.notPermittedResponse(w, r)
+			return
+		}
+
+		if !user.MFAEnabled && app.mfaRequired(user) {
+			app.mfaRequiredResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+
+	return app.requireActivatedUser(fn)
+}
+
+// hasPermission reports whether the request's user has a permission and, if
+// they used an API key, whether the key has it too.
+func (app *application) hasPermission(r *http.Request, code string) bool {
+	if !data.PermissionsForRole(app.contextGetUser(r).Role).Include(code) {
+		return false
+	}
+	if key := app.contextGetAPIKey(r); key != nil && !key.Permissions.Include(code) {
+		return false
+	}
+	return true
+}
+
+// requireLoginToken rejects requests made with an API key. It guards the
+// routes for managing a user's own account and credentials, which an
+// integration has no business using.
+func (app *application) requireLoginToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.contextGetAPIKey(r) != nil {
+			app.notPermittedResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (app *application) enableCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Add the "Vary: Origin" header.
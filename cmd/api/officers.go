@@ -18,6 +18,10 @@ func (app *application) createOfficerHandler(w http.ResponseWriter, r *http.Requ
         LastName         string  `json:"last_name"`
         Sex              string  `json:"sex"`
         RankCode         string  `json:"rank_code"`
+        RegionID         *string `json:"region_id"`
+        FormationID      *string `json:"formation_id"`
+        PostingID        *string `json:"posting_id"`
+        Email            *string `json:"email"`
     }
 
     err := app.readJSON(w, r, &input)
@@ -32,6 +36,10 @@ func (app *application) createOfficerHandler(w http.ResponseWriter, r *http.Requ
         LastName:         input.LastName,
         Sex:              input.Sex,
         RankCode:         input.RankCode,
+        RegionID:         optionalString(input.RegionID),
+        FormationID:      optionalString(input.FormationID),
+        PostingID:        optionalString(input.PostingID),
+        Email:            optionalString(input.Email),
     }
 
     v := validator.New()
@@ -40,7 +48,18 @@ func (app *application) createOfficerHandler(w http.ResponseWriter, r *http.Requ
         return
     }
 
-    err = app.models.Officers.Insert(officer)
+    // Check that the rank and assignment reference existing lookup records.
+    err = app.checkOfficerReferences(v, officer)
+    if err != nil {
+        app.serverErrorResponse(w, r, err)
+        return
+    }
+    if !v.Valid() {
+        app.failedValidationResponse(w, r, v.Errors)
+        return
+    }
+
+    err = app.models.Officers.Insert(app.auditActor(r), officer)
     if err != nil {
         app.serverErrorResponse(w, r, err)
         return
@@ -78,6 +97,10 @@ func (app *application) updateOfficerHandler(w http.ResponseWriter, r *http.Requ
 		LastName         *string `json:"last_name"`
 		Sex              *string `json:"sex"`
 		RankCode         *string `json:"rank_code"`
+		RegionID         *string `json:"region_id"`
+		FormationID      *string `json:"formation_id"`
+		PostingID        *string `json:"posting_id"`
+		Email            *string `json:"email"`
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -102,6 +125,20 @@ func (app *application) updateOfficerHandler(w http.ResponseWriter, r *http.Requ
 	if input.RankCode != nil {
 		officer.RankCode = *input.RankCode
 	}
+	// An empty string clears the assignment.
+	if input.RegionID != nil {
+		officer.RegionID = optionalString(input.RegionID)
+	}
+	if input.FormationID != nil {
+		officer.FormationID = optionalString(input.FormationID)
+	}
+	if input.PostingID != nil {
+		officer.PostingID = optionalString(input.PostingID)
+	}
+	// An empty string clears the email address.
+	if input.Email != nil {
+		officer.Email = optionalString(input.Email)
+	}
 
 	// Re-validate the updated officer record.
 	v := validator.New()
@@ -110,8 +147,18 @@ func (app *application) updateOfficerHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	err = app.checkOfficerReferences(v, officer)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
 	// Pass the updated officer record to the Update() method.
-	err = app.models.Officers.Update(officer)
+	err = app.models.Officers.Update(app.auditActor(r), officer)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict): // Handle edit conflicts specifically
@@ -129,11 +176,90 @@ func (app *application) updateOfficerHandler(w http.ResponseWriter, r *http.Requ
 	}
 }
 
+// archiveOfficerHandler handles POST /v1/officers/:id/archive
+func (app *application) archiveOfficerHandler(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id := params.ByName("id")
+
+	officer, err := app.models.Officers.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if officer.ArchivedAt != nil {
+		app.errorResponse(w, r, http.StatusConflict, "the officer is already archived")
+		return
+	}
+
+	err = app.models.Officers.Archive(app.auditActor(r), officer)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"officer": officer}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// unarchiveOfficerHandler handles POST /v1/officers/:id/unarchive
+func (app *application) unarchiveOfficerHandler(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id := params.ByName("id")
+
+	officer, err := app.models.Officers.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if officer.ArchivedAt == nil {
+		app.errorResponse(w, r, http.StatusConflict, "the officer is not archived")
+		return
+	}
+
+	err = app.models.Officers.Unarchive(app.auditActor(r), officer)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"officer": officer}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteOfficerHandler permanently removes an officer together with their
+// attendance and feedback history. It is restricted to admins; everyone else
+// should archive instead.
 func (app *application) deleteOfficerHandler(w http.ResponseWriter, r *http.Request) {
     params := httprouter.ParamsFromContext(r.Context())
     id := params.ByName("id")
 
-    err := app.models.Officers.Delete(id)
+    err := app.models.Officers.Delete(app.auditActor(r), id)
     if err != nil {
         switch {
         case errors.Is(err, data.ErrRecordNotFound):
@@ -174,9 +300,13 @@ func (app *application) getOfficerHandler(w http.ResponseWriter, r *http.Request
 
 func (app *application) listOfficersHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		FirstName string
-		LastName  string
-		RankCode  string
+		FirstName       string
+		LastName        string
+		RankCode        string
+		RegionID        string
+		FormationID     string
+		PostingID       string
+		IncludeArchived bool
 		data.Filters
 	}
 
@@ -187,6 +317,10 @@ func (app *application) listOfficersHandler(w http.ResponseWriter, r *http.Reque
 	input.FirstName = app.readString(qs, "first_name", "")
 	input.LastName = app.readString(qs, "last_name", "")
 	input.RankCode = app.readString(qs, "rank_code", "")
+	input.RegionID = app.readString(qs, "region_id", "")
+	input.FormationID = app.readString(qs, "formation_id", "")
+	input.PostingID = app.readString(qs, "posting_id", "")
+	input.IncludeArchived = app.readBool(qs, "include_archived", false, v)
 
 	// Read pagination and sorting parameters.
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
@@ -203,7 +337,7 @@ func (app *application) listOfficersHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Call the model method.
-	officers, metadata, err := app.models.Officers.GetAll(input.FirstName, input.LastName, input.RankCode, input.Filters)
+	officers, metadata, err := app.models.Officers.GetAll(input.FirstName, input.LastName, input.RankCode, input.RegionID, input.FormationID, input.PostingID, input.IncludeArchived, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -216,3 +350,12 @@ func (app *application) listOfficersHandler(w http.ResponseWriter, r *http.Reque
 	}
 }
 
+// checkOfficerReferences checks the officer's rank and assignment against the
+// lookup tables, recording any problems in the validator.
+func (app *application) checkOfficerReferences(v *validator.Validator, officer *data.Officer) error {
+	err := app.checkRankCode(v, officer.RankCode)
+	if err != nil {
+		return err
+	}
+	return app.checkLocation(v, officer.RegionID, officer.FormationID, officer.PostingID)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/amari03/test1/internal/data"
+	"github.com/amari03/test1/internal/validator"
+)
+
+// checkFormationRegion records a validation error if the formation's region
+// does not exist.
+func (app *application) checkFormationRegion(v *validator.Validator, formation *data.Formation) error {
+	_, err := app.models.Regions.Get(formation.RegionID)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			v.AddError("region_id", "must reference an existing region")
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// checkRankCode records a validation error if the rank code does not exist.
+func (app *application) checkRankCode(v *validator.Validator, code string) error {
+	_, err := app.models.Ranks.Get(code)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			v.AddError("rank_code", "must reference an existing rank")
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// checkLocation records validation errors if the region, formation or posting
+// do not exist, or if the formation does not belong to the region. Nil IDs are
+// not checked.
+func (app *application) checkLocation(v *validator.Validator, regionID, formationID, postingID *string) error {
+	if regionID != nil {
+		_, err := app.models.Regions.Get(*regionID)
+		if err != nil {
+			if !errors.Is(err, data.ErrRecordNotFound) {
+				return err
+			}
+			v.AddError("region_id", "must reference an existing region")
+		}
+	}
+
+	if formationID != nil {
+		formation, err := app.models.Formations.Get(*formationID)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("formation_id", "must reference an existing formation")
+		case err != nil:
+			return err
+		case regionID != nil && formation.RegionID != *regionID:
+			v.AddError("formation_id", "must belong to the chosen region")
+		}
+	}
+
+	if postingID != nil {
+		_, err := app.models.Postings.Get(*postingID)
+		if err != nil {
+			if !errors.Is(err, data.ErrRecordNotFound) {
+				return err
+			}
+			v.AddError("posting_id", "must reference an existing posting")
+		}
+	}
+
+	return nil
+}
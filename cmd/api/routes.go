@@ -5,8 +5,13 @@ import (
     "github.com/julienschmidt/httprouter"
 )
 
-/*You should apply the app.requireActivatedUser wrapper to any handlers in your 
-application that require a logged-in, activated user. 
+/*Most routes are wrapped with app.requirePermission; the permissions each
+role gets are listed in internal/data/permissions.go. The exceptions are the
+routes for getting an account and a token: sign-up, activation (and resending
+it), login (including the two-factor step), token refresh, password resets,
+confirming a new email address and accepting an invitation, which are open,
+and the routes that act on the caller's own account, which need them to be
+logged in (and not using an API key).
 */
 
 func (app *application) routes() http.Handler {
@@ -20,59 +25,139 @@ func (app *application) routes() http.Handler {
     router.HandlerFunc(http.MethodPost, "/v1/users", app.createUserHandler)
     router.HandlerFunc(http.MethodPut, "/v1/users/activated", app.activateUserHandler)
     router.HandlerFunc(http.MethodPost, "/v1/tokens/authentication", app.createAuthenticationTokenHandler)
+    router.HandlerFunc(http.MethodPost, "/v1/tokens/refresh", app.refreshAuthenticationTokenHandler)
+    router.HandlerFunc(http.MethodPost, "/v1/tokens/mfa", app.createMFATokenHandler)
+    router.Handler(http.MethodGet, "/v1/tokens/authentication", app.requireLoginToken(app.requireAuthenticatedUser(http.HandlerFunc(app.listAuthenticationTokensHandler))))
+    router.Handler(http.MethodDelete, "/v1/tokens/authentication", app.requireLoginToken(app.requireAuthenticatedUser(http.HandlerFunc(app.deleteAuthenticationTokenHandler))))
+    router.Handler(http.MethodDelete, "/v1/tokens/authentication/all", app.requireLoginToken(app.requireAuthenticatedUser(http.HandlerFunc(app.deleteAllAuthenticationTokensHandler))))
+    router.HandlerFunc(http.MethodPost, "/v1/tokens/activation", app.createActivationTokenHandler)
     router.HandlerFunc(http.MethodPost, "/v1/tokens/password-reset", app.createPasswordResetTokenHandler)
     router.HandlerFunc(http.MethodPut, "/v1/users/password", app.updatePasswordHandler)
-    router.HandlerFunc(http.MethodGet, "/v1/users/:id", app.getUserHandler)
-    router.HandlerFunc(http.MethodPatch, "/v1/users/:id", app.updateUserHandler)
-    router.HandlerFunc(http.MethodDelete, "/v1/users/:id", app.deleteUserHandler)
-    //this is how the requireActivated function will look like
-    router.Handler(http.MethodGet, "/v1/users", app.requireActivatedUser(http.HandlerFunc(app.listUsersHandler)))
-
-    router.Handler(http.MethodPost, "/v1/officers", app.requireActivatedUser(http.HandlerFunc(app.createOfficerHandler)))
-    router.HandlerFunc(http.MethodGet, "/v1/officers/:id", app.getOfficerHandler)
-    router.Handler(http.MethodPatch, "/v1/officers/:id", app.requireActivatedUser(http.HandlerFunc(app.updateOfficerHandler)))
-    router.Handler(http.MethodDelete, "/v1/officers/:id", app.requireActivatedUser(http.HandlerFunc(app.deleteOfficerHandler)))
-    router.HandlerFunc(http.MethodGet, "/v1/officers", app.listOfficersHandler)
-
-    router.Handler(http.MethodPost, "/v1/courses", app.requireActivatedUser(http.HandlerFunc(app.createCourseHandler)))
-    router.HandlerFunc(http.MethodGet, "/v1/courses/:id", app.getCourseHandler)
-    router.Handler(http.MethodPatch, "/v1/courses/:id", app.requireActivatedUser(http.HandlerFunc(app.updateCourseHandler)))
-    router.Handler(http.MethodDelete, "/v1/courses/:id", app.requireActivatedUser(http.HandlerFunc(app.deleteCourseHandler)))
-    router.HandlerFunc(http.MethodGet, "/v1/courses", app.listCoursesHandler)
-
-    router.Handler(http.MethodPost, "/v1/sessions", app.requireActivatedUser(http.HandlerFunc(app.createSessionHandler)))
-    router.HandlerFunc(http.MethodGet, "/v1/sessions/:id", app.getSessionHandler)
-    router.Handler(http.MethodPatch, "/v1/sessions/:id", app.requireActivatedUser(http.HandlerFunc(app.updateSessionHandler)))
-    router.Handler(http.MethodDelete, "/v1/sessions/:id", app.requireActivatedUser(http.HandlerFunc(app.deleteSessionHandler)))
-    router.HandlerFunc(http.MethodGet, "/v1/sessions", app.listSessionsHandler)
-
-    router.Handler(http.MethodPost, "/v1/facilitators", app.requireActivatedUser(http.HandlerFunc(app.createFacilitatorHandler)))
-    router.HandlerFunc(http.MethodGet, "/v1/facilitators/:id", app.getFacilitatorHandler)
-    router.Handler(http.MethodPatch, "/v1/facilitators/:id", app.requireActivatedUser(http.HandlerFunc(app.updateFacilitatorHandler)))
-    router.Handler(http.MethodDelete, "/v1/facilitators/:id", app.requireActivatedUser(http.HandlerFunc(app.deleteFacilitatorHandler)))
-    router.HandlerFunc(http.MethodGet, "/v1/facilitators", app.listFacilitatorsHandler)
-
-    router.Handler(http.MethodPost, "/v1/attendance", app.requireActivatedUser(http.HandlerFunc(app.createAttendanceHandler)))
-    router.Handler(http.MethodDelete, "/v1/attendance/:id", app.requireActivatedUser(http.HandlerFunc(app.deleteAttendanceHandler)))
-    router.HandlerFunc(http.MethodGet, "/v1/attendance/:id", app.getAttendanceHandler)
-    router.Handler(http.MethodPatch, "/v1/attendance/:id", app.requireActivatedUser(http.HandlerFunc(app.updateAttendanceHandler)))
-    router.HandlerFunc(http.MethodGet, "/v1/attendance", app.listAttendanceHandler)
+    router.HandlerFunc(http.MethodPut, "/v1/users/email", app.confirmEmailChangeHandler)
+    // Users can read and edit their own account, including as /v1/users/me;
+    // the handlers check that anyone else is an admin.
+    router.Handler(http.MethodGet, "/v1/users/:id", app.requireLoginToken(app.requireActivatedUser(http.HandlerFunc(app.getUserHandler))))
+    router.Handler(http.MethodPatch, "/v1/users/:id", app.requireLoginToken(app.requireActivatedUser(http.HandlerFunc(app.updateUserHandler))))
+    router.Handler(http.MethodDelete, "/v1/users/:id", app.requirePermission("users:admin", http.HandlerFunc(app.deleteUserHandler)))
+    router.Handler(http.MethodPost, "/v1/users/:id/unlock", app.requirePermission("users:admin", http.HandlerFunc(app.unlockUserHandler)))
+    router.Handler(http.MethodDelete, "/v1/users/:id/mfa", app.requirePermission("users:admin", http.HandlerFunc(app.resetUserMFAHandler)))
+    router.Handler(http.MethodGet, "/v1/users", app.requirePermission("users:admin", http.HandlerFunc(app.listUsersHandler)))
+    router.Handler(http.MethodGet, "/v1/audit-events", app.requirePermission("audit:read", http.HandlerFunc(app.listAuditEventsHandler)))
+    router.Handler(http.MethodPost, "/v1/invitations", app.requirePermission("users:admin", http.HandlerFunc(app.createInvitationHandler)))
+    router.HandlerFunc(http.MethodPut, "/v1/invitations/accepted", app.acceptInvitationHandler)
+
+    // Two-factor authentication
+    router.Handler(http.MethodPost, "/v1/mfa/totp", app.requireLoginToken(app.requireActivatedUser(http.HandlerFunc(app.beginTOTPHandler))))
+    router.Handler(http.MethodPost, "/v1/mfa/totp/confirm", app.requireLoginToken(app.requireActivatedUser(http.HandlerFunc(app.confirmTOTPHandler))))
+    router.Handler(http.MethodDelete, "/v1/mfa/totp", app.requireLoginToken(app.requireActivatedUser(http.HandlerFunc(app.disableTOTPHandler))))
+    router.Handler(http.MethodPost, "/v1/mfa/recovery-codes", app.requireLoginToken(app.requireActivatedUser(http.HandlerFunc(app.replaceRecoveryCodesHandler))))
+
+    // API keys for integrations. Keys can't be used to manage keys.
+    router.Handler(http.MethodPost, "/v1/api-keys", app.requireLoginToken(app.requireActivatedUser(http.HandlerFunc(app.createAPIKeyHandler))))
+    router.Handler(http.MethodGet, "/v1/api-keys", app.requireLoginToken(app.requireActivatedUser(http.HandlerFunc(app.listAPIKeysHandler))))
+    router.Handler(http.MethodDelete, "/v1/api-keys/:id", app.requireLoginToken(app.requireActivatedUser(http.HandlerFunc(app.deleteAPIKeyHandler))))
+
+    router.Handler(http.MethodPost, "/v1/officers", app.requirePermission("officers:write", http.HandlerFunc(app.createOfficerHandler)))
+    router.Handler(http.MethodGet, "/v1/officers/:id", app.requirePermission("officers:read", http.HandlerFunc(app.getOfficerHandler)))
+    router.Handler(http.MethodGet, "/v1/officers/:id/transcript", app.requirePermission("officers:read", http.HandlerFunc(app.getOfficerTranscriptHandler)))
+    router.Handler(http.MethodPatch, "/v1/officers/:id", app.requirePermission("officers:write", http.HandlerFunc(app.updateOfficerHandler)))
+    router.Handler(http.MethodPost, "/v1/officers/:id/archive", app.requirePermission("officers:write", http.HandlerFunc(app.archiveOfficerHandler)))
+    router.Handler(http.MethodPost, "/v1/officers/:id/unarchive", app.requirePermission("officers:write", http.HandlerFunc(app.unarchiveOfficerHandler)))
+    // Hard deletes cascade away training history, so only admins may use them.
+    router.Handler(http.MethodDelete, "/v1/officers/:id", app.requirePermission("officers:delete", http.HandlerFunc(app.deleteOfficerHandler)))
+    router.Handler(http.MethodGet, "/v1/officers", app.requirePermission("officers:read", http.HandlerFunc(app.listOfficersHandler)))
+
+    router.Handler(http.MethodPost, "/v1/courses", app.requirePermission("courses:write", http.HandlerFunc(app.createCourseHandler)))
+    router.Handler(http.MethodGet, "/v1/courses/:id", app.requirePermission("courses:read", http.HandlerFunc(app.getCourseHandler)))
+    router.Handler(http.MethodPatch, "/v1/courses/:id", app.requirePermission("courses:write", http.HandlerFunc(app.updateCourseHandler)))
+    router.Handler(http.MethodDelete, "/v1/courses/:id", app.requirePermission("courses:delete", http.HandlerFunc(app.deleteCourseHandler)))
+    router.Handler(http.MethodGet, "/v1/courses", app.requirePermission("courses:read", http.HandlerFunc(app.listCoursesHandler)))
+
+    router.Handler(http.MethodPost, "/v1/sessions", app.requirePermission("sessions:write", http.HandlerFunc(app.createSessionHandler)))
+    router.Handler(http.MethodGet, "/v1/sessions/:id", app.requirePermission("sessions:read", http.HandlerFunc(app.getSessionHandler)))
+    router.Handler(http.MethodPatch, "/v1/sessions/:id", app.requirePermission("sessions:write", http.HandlerFunc(app.updateSessionHandler)))
+    router.Handler(http.MethodDelete, "/v1/sessions/:id", app.requirePermission("sessions:delete", http.HandlerFunc(app.deleteSessionHandler)))
+    router.Handler(http.MethodGet, "/v1/sessions", app.requirePermission("sessions:read", http.HandlerFunc(app.listSessionsHandler)))
+    router.Handler(http.MethodPost, "/v1/sessions/:id/roster", app.requirePermission("attendance:write", http.HandlerFunc(app.createSessionRosterHandler)))
+
+    router.Handler(http.MethodPost, "/v1/session-series", app.requirePermission("sessions:write", http.HandlerFunc(app.createSessionSeriesHandler)))
+    router.Handler(http.MethodGet, "/v1/session-series/:id", app.requirePermission("sessions:read", http.HandlerFunc(app.getSessionSeriesHandler)))
+    router.Handler(http.MethodPatch, "/v1/session-series/:id", app.requirePermission("sessions:write", http.HandlerFunc(app.updateSessionSeriesHandler)))
+    router.Handler(http.MethodPost, "/v1/session-series/:id/cancel", app.requirePermission("sessions:write", http.HandlerFunc(app.cancelSessionSeriesHandler)))
+    router.Handler(http.MethodGet, "/v1/session-series", app.requirePermission("sessions:read", http.HandlerFunc(app.listSessionSeriesHandler)))
+
+    router.Handler(http.MethodPost, "/v1/facilitators", app.requirePermission("facilitators:write", http.HandlerFunc(app.createFacilitatorHandler)))
+    router.Handler(http.MethodGet, "/v1/facilitators/:id", app.requirePermission("facilitators:read", http.HandlerFunc(app.getFacilitatorHandler)))
+    router.Handler(http.MethodPatch, "/v1/facilitators/:id", app.requirePermission("facilitators:write", http.HandlerFunc(app.updateFacilitatorHandler)))
+    router.Handler(http.MethodDelete, "/v1/facilitators/:id", app.requirePermission("facilitators:write", http.HandlerFunc(app.deleteFacilitatorHandler)))
+    router.Handler(http.MethodGet, "/v1/facilitators", app.requirePermission("facilitators:read", http.HandlerFunc(app.listFacilitatorsHandler)))
+
+    router.Handler(http.MethodPost, "/v1/attendance", app.requirePermission("attendance:write", http.HandlerFunc(app.createAttendanceHandler)))
+    router.Handler(http.MethodDelete, "/v1/attendance/:id", app.requirePermission("attendance:write", http.HandlerFunc(app.deleteAttendanceHandler)))
+    router.Handler(http.MethodGet, "/v1/attendance/:id", app.requirePermission("attendance:read", http.HandlerFunc(app.getAttendanceHandler)))
+    router.Handler(http.MethodPatch, "/v1/attendance/:id", app.requirePermission("attendance:write", http.HandlerFunc(app.updateAttendanceHandler)))
+    router.Handler(http.MethodGet, "/v1/attendance", app.requirePermission("attendance:read", http.HandlerFunc(app.listAttendanceHandler)))
 
 
     // Session Facilitators
-    router.Handler(http.MethodPost, "/v1/session-facilitators", app.requireActivatedUser(http.HandlerFunc(app.createSessionFacilitatorHandler)))
-    router.Handler(http.MethodDelete, "/v1/session-facilitators/:id", app.requireActivatedUser(http.HandlerFunc(app.deleteSessionFacilitatorHandler)))
-    router.HandlerFunc(http.MethodGet, "/v1/session-facilitators", app.listSessionFacilitatorsHandler)
+    router.Handler(http.MethodPost, "/v1/session-facilitators", app.requirePermission("sessions:write", http.HandlerFunc(app.createSessionFacilitatorHandler)))
+    router.Handler(http.MethodDelete, "/v1/session-facilitators/:id", app.requirePermission("sessions:write", http.HandlerFunc(app.deleteSessionFacilitatorHandler)))
+    router.Handler(http.MethodGet, "/v1/session-facilitators", app.requirePermission("sessions:read", http.HandlerFunc(app.listSessionFacilitatorsHandler)))
+
+    // Session Enrollments
+    router.Handler(http.MethodPost, "/v1/session-enrollments", app.requirePermission("sessions:write", http.HandlerFunc(app.createSessionEnrollmentHandler)))
+    router.Handler(http.MethodGet, "/v1/session-enrollments/:id", app.requirePermission("sessions:read", http.HandlerFunc(app.getSessionEnrollmentHandler)))
+    router.Handler(http.MethodDelete, "/v1/session-enrollments/:id", app.requirePermission("sessions:write", http.HandlerFunc(app.deleteSessionEnrollmentHandler)))
+    router.Handler(http.MethodGet, "/v1/session-enrollments", app.requirePermission("sessions:read", http.HandlerFunc(app.listSessionEnrollmentsHandler)))
 
     // Session Feedback
-    router.Handler(http.MethodPost, "/v1/session-feedback", app.requireActivatedUser(http.HandlerFunc(app.createSessionFeedbackHandler)))
-    router.HandlerFunc(http.MethodGet, "/v1/session-feedback", app.listSessionFeedbackHandler)
+    router.Handler(http.MethodPost, "/v1/session-feedback", app.requirePermission("sessions:write", http.HandlerFunc(app.createSessionFeedbackHandler)))
+    router.Handler(http.MethodGet, "/v1/session-feedback", app.requirePermission("sessions:read", http.HandlerFunc(app.listSessionFeedbackHandler)))
 
     // Import Jobs
-    router.Handler(http.MethodPost, "/v1/import-jobs", app.requireActivatedUser(http.HandlerFunc(app.createImportJobHandler)))
-    router.HandlerFunc(http.MethodGet, "/v1/import-jobs", app.listImportJobsHandler)
-    router.HandlerFunc(http.MethodGet, "/v1/import-jobs/:id", app.getImportJobHandler)
+    router.Handler(http.MethodPost, "/v1/import-jobs", app.requirePermission("imports:write", http.HandlerFunc(app.createImportJobHandler)))
+    router.Handler(http.MethodGet, "/v1/import-jobs", app.requirePermission("imports:read", http.HandlerFunc(app.listImportJobsHandler)))
+    router.Handler(http.MethodGet, "/v1/import-jobs/:id", app.requirePermission("imports:read", http.HandlerFunc(app.getImportJobHandler)))
+    router.Handler(http.MethodGet, "/v1/import-jobs/:id/errors", app.requirePermission("imports:read", http.HandlerFunc(app.listImportJobErrorsHandler)))
+
+    // Background job queue
+    router.Handler(http.MethodGet, "/v1/jobs", app.requirePermission("jobs:admin", http.HandlerFunc(app.listJobsHandler)))
+    router.Handler(http.MethodGet, "/v1/jobs/:id", app.requirePermission("jobs:admin", http.HandlerFunc(app.getJobHandler)))
+    router.Handler(http.MethodPost, "/v1/jobs/:id/retry", app.requirePermission("jobs:admin", http.HandlerFunc(app.retryJobHandler)))
+
+    // Lookup tables
+    router.Handler(http.MethodPost, "/v1/regions", app.requirePermission("lookups:write", http.HandlerFunc(app.createRegionHandler)))
+    router.Handler(http.MethodGet, "/v1/regions/:id", app.requirePermission("lookups:read", http.HandlerFunc(app.getRegionHandler)))
+    router.Handler(http.MethodPatch, "/v1/regions/:id", app.requirePermission("lookups:write", http.HandlerFunc(app.updateRegionHandler)))
+    router.Handler(http.MethodDelete, "/v1/regions/:id", app.requirePermission("lookups:write", http.HandlerFunc(app.deleteRegionHandler)))
+    router.Handler(http.MethodGet, "/v1/regions", app.requirePermission("lookups:read", http.HandlerFunc(app.listRegionsHandler)))
+
+    router.Handler(http.MethodPost, "/v1/formations", app.requirePermission("lookups:write", http.HandlerFunc(app.createFormationHandler)))
+    router.Handler(http.MethodGet, "/v1/formations/:id", app.requirePermission("lookups:read", http.HandlerFunc(app.getFormationHandler)))
+    router.Handler(http.MethodPatch, "/v1/formations/:id", app.requirePermission("lookups:write", http.HandlerFunc(app.updateFormationHandler)))
+    router.Handler(http.MethodDelete, "/v1/formations/:id", app.requirePermission("lookups:write", http.HandlerFunc(app.deleteFormationHandler)))
+    router.Handler(http.MethodGet, "/v1/formations", app.requirePermission("lookups:read", http.HandlerFunc(app.listFormationsHandler)))
+
+    router.Handler(http.MethodPost, "/v1/postings", app.requirePermission("lookups:write", http.HandlerFunc(app.createPostingHandler)))
+    router.Handler(http.MethodGet, "/v1/postings/:id", app.requirePermission("lookups:read", http.HandlerFunc(app.getPostingHandler)))
+    router.Handler(http.MethodPatch, "/v1/postings/:id", app.requirePermission("lookups:write", http.HandlerFunc(app.updatePostingHandler)))
+    router.Handler(http.MethodDelete, "/v1/postings/:id", app.requirePermission("lookups:write", http.HandlerFunc(app.deletePostingHandler)))
+    router.Handler(http.MethodGet, "/v1/postings", app.requirePermission("lookups:read", http.HandlerFunc(app.listPostingsHandler)))
+
+    router.Handler(http.MethodPost, "/v1/ranks", app.requirePermission("lookups:write", http.HandlerFunc(app.createRankHandler)))
+    router.Handler(http.MethodGet, "/v1/ranks/:code", app.requirePermission("lookups:read", http.HandlerFunc(app.getRankHandler)))
+    router.Handler(http.MethodPatch, "/v1/ranks/:code", app.requirePermission("lookups:write", http.HandlerFunc(app.updateRankHandler)))
+    router.Handler(http.MethodDelete, "/v1/ranks/:code", app.requirePermission("lookups:write", http.HandlerFunc(app.deleteRankHandler)))
+    router.Handler(http.MethodGet, "/v1/ranks", app.requirePermission("lookups:read", http.HandlerFunc(app.listRanksHandler)))
+
+    // Training compliance
+    router.Handler(http.MethodPost, "/v1/compliance-requirements", app.requirePermission("compliance:write", http.HandlerFunc(app.createComplianceRequirementHandler)))
+    router.Handler(http.MethodGet, "/v1/compliance-requirements/:id", app.requirePermission("compliance:read", http.HandlerFunc(app.getComplianceRequirementHandler)))
+    router.Handler(http.MethodPatch, "/v1/compliance-requirements/:id", app.requirePermission("compliance:write", http.HandlerFunc(app.updateComplianceRequirementHandler)))
+    router.Handler(http.MethodDelete, "/v1/compliance-requirements/:id", app.requirePermission("compliance:write", http.HandlerFunc(app.deleteComplianceRequirementHandler)))
+    router.Handler(http.MethodGet, "/v1/compliance-requirements", app.requirePermission("compliance:read", http.HandlerFunc(app.listComplianceRequirementsHandler)))
+    router.Handler(http.MethodGet, "/v1/compliance", app.requirePermission("compliance:read", http.HandlerFunc(app.listComplianceHandler)))
 
     
-    return app.recoverPanic(app.enableCORS(app.rateLimit(app.authenticate(router))))
+    return app.requestID(app.recoverPanic(app.enableCORS(app.rateLimit(app.authenticate(router)))))
 }
\ No newline at end of file
@@ -26,6 +26,14 @@ func (app *application) serve() error {
 	// This channel will receive any errors returned by the graceful shutdown process.
 	shutdownError := make(chan error)
 
+	// Start the background job workers and the cleanup of expired tokens.
+	// They are stopped once the server has shut down, and waited for along
+	// with any other background tasks.
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	app.startWorkers(workerCtx)
+	app.startCleanup(workerCtx)
+
 	// Start a background goroutine to listen for shutdown signals.
 	go func() {
 		// A quit channel which carries os.Signal values.
@@ -53,6 +61,7 @@ func (app *application) serve() error {
 
 		// THIS IS THE NEW PART
 		app.logger.Info("completing background tasks", "addr", srv.Addr)
+		stopWorkers()
 		app.wg.Wait() // Wait for all background goroutines to finish.
 		shutdownError <- nil
 	}()
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/amari03/test1/internal/data"
+	"github.com/amari03/test1/internal/validator"
+	"github.com/julienschmidt/httprouter"
+)
+
+// createSessionEnrollmentHandler handles POST /v1/session-enrollments
+// The officer is enrolled if the session has room, and waitlisted otherwise.
+// Officers can't be enrolled in sessions that clash with ones they are
+// already in, unless an admin passes ?force=true.
+func (app *application) createSessionEnrollmentHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		SessionID string `json:"session_id"`
+		OfficerID string `json:"officer_id"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	enrollment := &data.SessionEnrollment{
+		SessionID: input.SessionID,
+		OfficerID: input.OfficerID,
+	}
+
+	v := validator.New()
+
+	force := app.readBool(r.URL.Query(), "force", false, v)
+	if force && !app.hasPermission(r, "schedule:override") {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	if data.ValidateSessionEnrollment(v, enrollment); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	officer, err := app.models.Officers.Get(enrollment.OfficerID)
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound):
+		v.AddError("officer_id", "must reference an existing officer")
+	case err != nil:
+		app.serverErrorResponse(w, r, err)
+		return
+	case officer.ArchivedAt != nil:
+		v.AddError("officer_id", "must not be archived")
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.SessionEnrollments.Enroll(app.auditActor(r), enrollment, force)
+	if err != nil {
+		var conflictErr *data.ScheduleConflictError
+		switch {
+		case errors.As(err, &conflictErr):
+			app.scheduleConflictResponse(w, r, conflictErr.Conflicts)
+		case errors.Is(err, data.ErrEnrollmentClosed):
+			app.enrollmentClosedResponse(w, r)
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("session_id", "must reference an existing session")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrDuplicateRecord):
+			v.AddError("officer_id", "is already enrolled in or waitlisted for this session")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/session-enrollments/%s", enrollment.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"session_enrollment": enrollment}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getSessionEnrollmentHandler handles GET /v1/session-enrollments/:id
+func (app *application) getSessionEnrollmentHandler(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id := params.ByName("id")
+
+	enrollment, err := app.models.SessionEnrollments.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"session_enrollment": enrollment}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteSessionEnrollmentHandler handles DELETE /v1/session-enrollments/:id
+// Withdrawing an enrolled officer frees their place for the next officer on
+// the waitlist, who is returned as promoted.
+func (app *application) deleteSessionEnrollmentHandler(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id := params.ByName("id")
+
+	enrollment, err := app.models.SessionEnrollments.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	promoted, err := app.models.SessionEnrollments.Withdraw(app.auditActor(r), enrollment)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "officer successfully withdrawn from the session", "promoted": promoted}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listSessionEnrollmentsHandler handles GET /v1/session-enrollments
+// Enrollments can be filtered by session_id, officer_id and status.
+func (app *application) listSessionEnrollmentsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		SessionID string
+		OfficerID string
+		Status    string
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.SessionID = app.readString(qs, "session_id", "")
+	input.OfficerID = app.readString(qs, "officer_id", "")
+	input.Status = app.readString(qs, "status", "")
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "created_at")
+	input.Filters.SortSafelist = []string{"created_at", "status", "-created_at", "-status"}
+
+	if input.Status != "" {
+		v.Check(validator.In(input.Status, data.EnrollmentStatusEnrolled, data.EnrollmentStatusWaitlisted), "status", "must be enrolled or waitlisted")
+	}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	enrollments, metadata, err := app.models.SessionEnrollments.GetAll(input.SessionID, input.OfficerID, input.Status, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"session_enrollments": enrollments, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createSessionRosterHandler handles POST /v1/sessions/:id/roster
+// It records every enrolled officer as having attended, with the session's
+// default credit, so that only absences and exceptions need to be entered
+// by hand. Officers who already have attendance are left alone, so it is
+// safe to call again after more officers enroll.
+func (app *application) createSessionRosterHandler(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id := params.ByName("id")
+
+	records, err := app.models.Attendance.InsertRoster(app.auditActor(r), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrAttendanceLocked):
+			app.attendanceLockedResponse(w, r)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"attendance": records}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
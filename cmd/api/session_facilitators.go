@@ -31,14 +31,30 @@ func (app *application) createSessionFacilitatorHandler(w http.ResponseWriter, r
 	}
 
 	v := validator.New()
+
+	force := app.readBool(r.URL.Query(), "force", false, v)
+	if force && !app.hasPermission(r, "schedule:override") {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
 	if data.ValidateSessionFacilitator(v, sf); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.SessionFacilitators.Insert(sf)
+	err = app.models.SessionFacilitators.Insert(app.auditActor(r), sf, force)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		var conflictErr *data.ScheduleConflictError
+		switch {
+		case errors.As(err, &conflictErr):
+			app.scheduleConflictResponse(w, r, conflictErr.Conflicts)
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("session_id", "must reference an existing session")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
@@ -56,7 +72,7 @@ func (app *application) deleteSessionFacilitatorHandler(w http.ResponseWriter, r
 	params := httprouter.ParamsFromContext(r.Context())
 	id := params.ByName("id")
 
-	err := app.models.SessionFacilitators.Delete(id)
+	err := app.models.SessionFacilitators.Delete(app.auditActor(r), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
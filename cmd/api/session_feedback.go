@@ -38,7 +38,7 @@ func (app *application) createSessionFeedbackHandler(w http.ResponseWriter, r *h
 		return
 	}
 
-	err = app.models.SessionFeedback.Insert(feedback)
+	err = app.models.SessionFeedback.Insert(app.auditActor(r), feedback)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/amari03/test1/internal/data"
+)
+
+// notifySessionChange emails a session's enrolled officers and facilitators
+// if a change to it affects them: it has been cancelled or postponed, or it
+// has moved to a new time or place, or is back on after being postponed.
+// before is the session as it was.
+//
+// The change has already been made by then, so failing to queue an email is
+// logged rather than failing the request, and the others are still sent.
+func (app *application) notifySessionChange(r *http.Request, before, after *data.Session) {
+	var templateFile string
+	emailData := map[string]interface{}{}
+
+	switch {
+	case after.Status == data.SessionStatusCancelled:
+		if before.Status == data.SessionStatusCancelled {
+			return
+		}
+		templateFile = "session_cancelled.tmpl"
+		emailData["reason"] = *after.CancellationReason
+	case after.Status == data.SessionStatusPostponed:
+		if before.Status == data.SessionStatusPostponed {
+			return
+		}
+		templateFile = "session_rescheduled.tmpl"
+		emailData["postponed"] = true
+	case after.Status == data.SessionStatusCompleted:
+		return
+	case !after.Start.Equal(before.Start) || !after.End.Equal(before.End) || after.Location != before.Location ||
+		before.Status == data.SessionStatusPostponed:
+		templateFile = "session_rescheduled.tmpl"
+		emailData["postponed"] = false
+	default:
+		return
+	}
+
+	course, err := app.models.Courses.Get(after.CourseID)
+	if err != nil {
+		app.logError(r, err)
+		return
+	}
+
+	contacts, skipped, err := app.models.Sessions.Contacts(after.ID)
+	if err != nil {
+		app.logError(r, err)
+		return
+	}
+	if skipped > 0 {
+		app.logger.Info("session change not emailed to people without an email address", "session_id", after.ID, "skipped", skipped, "request_id", app.contextGetRequestID(r))
+	}
+
+	emailData["courseTitle"] = course.Title
+	emailData["location"] = after.Location
+	emailData["oldLocation"] = before.Location
+	emailData["oldStart"] = formatSessionTime(before.Start)
+	emailData["start"] = formatSessionTime(after.Start)
+	emailData["end"] = formatSessionTime(after.End)
+
+	for _, contact := range contacts {
+		emailData["name"] = contact.Name
+		err := app.enqueueEmail(contact.Email, templateFile, emailData)
+		if err != nil {
+			app.logError(r, err)
+			continue
+		}
+	}
+}
+
+// notifySessionChanges emails the people in each session a series changed.
+func (app *application) notifySessionChanges(r *http.Request, changes []data.SessionChange) {
+	for _, change := range changes {
+		if change.Before != nil && change.After != nil {
+			app.notifySessionChange(r, change.Before, change.After)
+		}
+	}
+}
+
+// formatSessionTime formats a session's start or end for an email.
+func formatSessionTime(t time.Time) string {
+	return t.UTC().Format("Mon 2 Jan 2006 15:04 MST")
+}
@@ -0,0 +1,443 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/amari03/test1/internal/data"
+	"github.com/amari03/test1/internal/validator"
+	"github.com/julienschmidt/httprouter"
+)
+
+// createSessionSeriesHandler handles POST /v1/session-series
+// It creates the series and all of its sessions. If any of them clash with
+// other sessions at the venue, none are created, unless an admin passes
+// ?force=true.
+func (app *application) createSessionSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		CourseID            string              `json:"course_id"`
+		Start               time.Time           `json:"start_datetime"`
+		End                 time.Time           `json:"end_datetime"`
+		TimeZone            *string             `json:"time_zone"`
+		Rule                data.RecurrenceRule `json:"rule"`
+		Location            string              `json:"location_text"`
+		RegionID            *string             `json:"region_id"`
+		FormationID         *string             `json:"formation_id"`
+		PostingID           *string             `json:"posting_id"`
+		FacilitatorNotes    *string             `json:"facilitator_notes"`
+		CreditHoursOverride *float64            `json:"credit_hours_override"`
+		Capacity            *int                `json:"capacity"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	series := &data.SessionSeries{
+		CourseID:            input.CourseID,
+		Start:               input.Start,
+		End:                 input.End,
+		TimeZone:            "UTC",
+		Rule:                input.Rule,
+		Location:            input.Location,
+		RegionID:            optionalString(input.RegionID),
+		FormationID:         optionalString(input.FormationID),
+		PostingID:           optionalString(input.PostingID),
+		FacilitatorNotes:    optionalString(input.FacilitatorNotes),
+		CreditHoursOverride: input.CreditHoursOverride,
+		Capacity:            input.Capacity,
+	}
+	if input.TimeZone != nil {
+		series.TimeZone = *input.TimeZone
+	}
+
+	v := validator.New()
+
+	force := app.readBool(r.URL.Query(), "force", false, v)
+	if force && !app.hasPermission(r, "schedule:override") {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	if data.ValidateSessionSeries(v, series); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.checkLocation(v, series.RegionID, series.FormationID, series.PostingID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	changes, err := app.models.SessionSeries.Insert(app.auditActor(r), series, force)
+	if err != nil {
+		var conflictErr *data.ScheduleConflictError
+		switch {
+		case errors.As(err, &conflictErr):
+			app.scheduleConflictResponse(w, r, conflictErr.Conflicts)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	sessions := make([]*data.Session, 0, len(changes.Sessions))
+	for _, change := range changes.Sessions {
+		sessions = append(sessions, change.After)
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/session-series/%s", series.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"session_series": series, "sessions": sessions}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getSessionSeriesHandler handles GET /v1/session-series/:id
+// The series' sessions are listed by GET /v1/sessions?series_id=.
+func (app *application) getSessionSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id := params.ByName("id")
+
+	series, err := app.models.SessionSeries.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"session_series": series}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateSessionSeriesHandler handles PATCH /v1/session-series/:id
+// It changes all future occurrences: those from from_session_id onwards, or
+// from the next session to start if that's left out. To change just one
+// occurrence, PATCH the session instead. A series changed from part way
+// through is split in two, and the new series is returned along with the
+// original, which now ends before it. A rule, if given, replaces the whole
+// rule; otherwise a series of a set number of sessions keeps however many it
+// had left.
+func (app *application) updateSessionSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id := params.ByName("id")
+
+	series, err := app.models.SessionSeries.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if series.CancelledAt != nil {
+		app.seriesCancelledResponse(w, r)
+		return
+	}
+
+	var input struct {
+		FromSessionID       *string              `json:"from_session_id"`
+		CourseID            *string              `json:"course_id"`
+		Start               *time.Time           `json:"start_datetime"`
+		End                 *time.Time           `json:"end_datetime"`
+		TimeZone            *string              `json:"time_zone"`
+		Rule                *data.RecurrenceRule `json:"rule"`
+		Location            *string              `json:"location_text"`
+		RegionID            *string              `json:"region_id"`
+		FormationID         *string              `json:"formation_id"`
+		PostingID           *string              `json:"posting_id"`
+		FacilitatorNotes    *string              `json:"facilitator_notes"`
+		CreditHoursOverride *float64             `json:"credit_hours_override"`
+		Capacity            *int                 `json:"capacity"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	force := app.readBool(r.URL.Query(), "force", false, v)
+	if force && !app.hasPermission(r, "schedule:override") {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	from, err := app.seriesOccurrence(v, series, input.FromSessionID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Splitting the series starts the new one at the occurrence being
+	// changed, as the rule would have it rather than as it may have been
+	// moved to.
+	if index := *from.SeriesIndex; index > 0 {
+		start := from.Start
+		if occurrences := series.Occurrences(); index < len(occurrences) {
+			start = occurrences[index]
+		}
+		series.Start, series.End = start, start.Add(series.End.Sub(series.Start))
+		if series.Rule.Count != nil {
+			remaining := *series.Rule.Count - index
+			series.Rule.Count = &remaining
+		}
+	}
+
+	// Moving the start keeps the sessions' length, unless the end moves too.
+	if input.Start != nil {
+		series.Start, series.End = *input.Start, input.Start.Add(series.End.Sub(series.Start))
+	}
+	if input.End != nil {
+		series.End = *input.End
+	}
+	if input.CourseID != nil {
+		series.CourseID = *input.CourseID
+	}
+	if input.TimeZone != nil {
+		series.TimeZone = *input.TimeZone
+	}
+	if input.Rule != nil {
+		series.Rule = *input.Rule
+	}
+	if input.Location != nil {
+		series.Location = *input.Location
+	}
+	// As for a session, an empty string clears the assignment or notes, a
+	// negative override clears it and a capacity of 0 removes the limit.
+	if input.RegionID != nil {
+		series.RegionID = optionalString(input.RegionID)
+	}
+	if input.FormationID != nil {
+		series.FormationID = optionalString(input.FormationID)
+	}
+	if input.PostingID != nil {
+		series.PostingID = optionalString(input.PostingID)
+	}
+	if input.FacilitatorNotes != nil {
+		series.FacilitatorNotes = optionalString(input.FacilitatorNotes)
+	}
+	if input.CreditHoursOverride != nil {
+		series.CreditHoursOverride = input.CreditHoursOverride
+		if *input.CreditHoursOverride < 0 {
+			series.CreditHoursOverride = nil
+		}
+	}
+	if input.Capacity != nil {
+		series.Capacity = input.Capacity
+		if *input.Capacity == 0 {
+			series.Capacity = nil
+		}
+	}
+
+	if data.ValidateSessionSeries(v, series); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.checkLocation(v, series.RegionID, series.FormationID, series.PostingID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	changes, err := app.models.SessionSeries.Update(app.auditActor(r), series, *from.SeriesIndex, force)
+	if err != nil {
+		var conflictErr *data.ScheduleConflictError
+		switch {
+		case errors.As(err, &conflictErr):
+			app.scheduleConflictResponse(w, r, conflictErr.Conflicts)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	env := envelope{"session_series": series}
+	if changes.Ended != nil {
+		env["ended_series"] = changes.Ended
+	}
+	app.notifySessionChanges(r, changes.Sessions)
+
+	// Fill any places a raised capacity has opened up.
+	if input.Capacity != nil {
+		for _, change := range changes.Sessions {
+			if change.Before == nil || change.After == nil {
+				continue
+			}
+			_, err := app.models.SessionEnrollments.PromoteWaitlisted(app.auditActor(r), change.After.ID)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// seriesOccurrence returns the session a change to a series starts from: the
+// one asked for, which must be in the series, or the next one to start.
+func (app *application) seriesOccurrence(v *validator.Validator, series *data.SessionSeries, sessionID *string) (*data.Session, error) {
+	if sessionID != nil {
+		session, err := app.models.Sessions.Get(*sessionID)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+		case err != nil:
+			return nil, err
+		case session.SeriesID != nil && *session.SeriesID == series.ID:
+			return session, nil
+		}
+		v.AddError("from_session_id", "must reference a session in this series")
+		return nil, nil
+	}
+
+	now := time.Now()
+	filters := data.Filters{Page: 1, PageSize: 1, Sort: "start_datetime", SortSafelist: []string{"start_datetime"}}
+
+	sessions, _, err := app.models.Sessions.GetAll("", "", "", series.ID, "", &now, nil, filters)
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		v.AddError("from_session_id", "the series has no sessions left to change")
+		return nil, nil
+	}
+	return sessions[0], nil
+}
+
+// cancelSessionSeriesHandler handles POST /v1/session-series/:id/cancel
+// Sessions in the series that haven't started are cancelled with the reason
+// given, apart from any that already have attendance, and the people in them
+// are told. The series itself is kept, marked cancelled.
+func (app *application) cancelSessionSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id := params.ByName("id")
+
+	series, err := app.models.SessionSeries.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if series.CancelledAt != nil {
+		app.seriesCancelledResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.Reason != "", "reason", "must be provided")
+	v.Check(len(input.Reason) <= 500, "reason", "must not be more than 500 bytes long")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	changes, err := app.models.SessionSeries.Cancel(app.auditActor(r), series, input.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.notifySessionChanges(r, changes.Sessions)
+
+	cancelled := make([]*data.Session, 0, len(changes.Sessions))
+	for _, change := range changes.Sessions {
+		cancelled = append(cancelled, change.After)
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"session_series": series, "cancelled_sessions": cancelled}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listSessionSeriesHandler handles GET /v1/session-series
+// Series can be filtered by course_id.
+func (app *application) listSessionSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		CourseID string
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.CourseID = app.readString(qs, "course_id", "")
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "start_datetime")
+	input.Filters.SortSafelist = []string{"start_datetime", "created_at", "-start_datetime", "-created_at"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	allSeries, metadata, err := app.models.SessionSeries.GetAll(input.CourseID, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"session_series": allSeries, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
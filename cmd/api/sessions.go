@@ -13,10 +13,16 @@ import (
 
 func (app *application) createSessionHandler(w http.ResponseWriter, r *http.Request) {
     var input struct {
-        CourseID string    `json:"course_id"`
-        Start    time.Time `json:"start_datetime"`
-        End      time.Time `json:"end_datetime"`
-        Location string    `json:"location_text"`
+        CourseID            string    `json:"course_id"`
+        Start               time.Time `json:"start_datetime"`
+        End                 time.Time `json:"end_datetime"`
+        Location            string    `json:"location_text"`
+        RegionID            *string   `json:"region_id"`
+        FormationID         *string   `json:"formation_id"`
+        PostingID           *string   `json:"posting_id"`
+        FacilitatorNotes    *string   `json:"facilitator_notes"`
+        CreditHoursOverride *float64  `json:"credit_hours_override"`
+        Capacity            *int      `json:"capacity"`
     }
 
     err := app.readJSON(w, r, &input)
@@ -26,23 +32,55 @@ func (app *application) createSessionHandler(w http.ResponseWriter, r *http.Requ
     }
 
     session := &data.Session{
-        CourseID: input.CourseID,
-        Start:    input.Start,
-        End:      input.End,
-        Location: input.Location,
+        CourseID:            input.CourseID,
+        Start:               input.Start,
+        End:                 input.End,
+        Location:            input.Location,
+        RegionID:            optionalString(input.RegionID),
+        FormationID:         optionalString(input.FormationID),
+        PostingID:           optionalString(input.PostingID),
+        FacilitatorNotes:    optionalString(input.FacilitatorNotes),
+        CreditHoursOverride: input.CreditHoursOverride,
+        Capacity:            input.Capacity,
+        Status:              data.SessionStatusScheduled,
     }
 
     v := validator.New()
+
+    // Admins can book over scheduling conflicts with ?force=true.
+    force := app.readBool(r.URL.Query(), "force", false, v)
+    if force && !app.hasPermission(r, "schedule:override") {
+        app.notPermittedResponse(w, r)
+        return
+    }
+
     if data.ValidateSession(v, session); !v.Valid() {
         app.failedValidationResponse(w, r, v.Errors)
         return
     }
 
-    err = app.models.Sessions.Insert(session)
+    // Check that the region, formation and posting exist.
+    err = app.checkLocation(v, session.RegionID, session.FormationID, session.PostingID)
     if err != nil {
         app.serverErrorResponse(w, r, err)
         return
     }
+    if !v.Valid() {
+        app.failedValidationResponse(w, r, v.Errors)
+        return
+    }
+
+    err = app.models.Sessions.Insert(app.auditActor(r), session, force)
+    if err != nil {
+        var conflictErr *data.ScheduleConflictError
+        switch {
+        case errors.As(err, &conflictErr):
+            app.scheduleConflictResponse(w, r, conflictErr.Conflicts)
+        default:
+            app.serverErrorResponse(w, r, err)
+        }
+        return
+    }
 
     headers := make(http.Header)
     headers.Set("Location", fmt.Sprintf("/v1/sessions/%s", session.ID))
@@ -89,11 +127,21 @@ func (app *application) updateSessionHandler(w http.ResponseWriter, r *http.Requ
         return
     }
 
+    before := *session
+
     var input struct {
-        CourseID *string    `json:"course_id"`
-        Start    *time.Time `json:"start_datetime"`
-        End      *time.Time `json:"end_datetime"`
-        Location *string    `json:"location_text"`
+        CourseID            *string    `json:"course_id"`
+        Start               *time.Time `json:"start_datetime"`
+        End                 *time.Time `json:"end_datetime"`
+        Location            *string    `json:"location_text"`
+        RegionID            *string    `json:"region_id"`
+        FormationID         *string    `json:"formation_id"`
+        PostingID           *string    `json:"posting_id"`
+        FacilitatorNotes    *string    `json:"facilitator_notes"`
+        CreditHoursOverride *float64   `json:"credit_hours_override"`
+        Capacity            *int       `json:"capacity"`
+        Status              *string    `json:"status"`
+        CancellationReason  *string    `json:"cancellation_reason"`
     }
 
     err = app.readJSON(w, r, &input)
@@ -106,16 +154,59 @@ func (app *application) updateSessionHandler(w http.ResponseWriter, r *http.Requ
     if input.Start != nil { session.Start = *input.Start }
     if input.End != nil { session.End = *input.End }
     if input.Location != nil { session.Location = *input.Location }
+    // An empty string clears the assignment or notes.
+    if input.RegionID != nil { session.RegionID = optionalString(input.RegionID) }
+    if input.FormationID != nil { session.FormationID = optionalString(input.FormationID) }
+    if input.PostingID != nil { session.PostingID = optionalString(input.PostingID) }
+    if input.FacilitatorNotes != nil { session.FacilitatorNotes = optionalString(input.FacilitatorNotes) }
+    // A negative override clears it, so the course's default hours apply again.
+    if input.CreditHoursOverride != nil {
+        session.CreditHoursOverride = input.CreditHoursOverride
+        if *input.CreditHoursOverride < 0 { session.CreditHoursOverride = nil }
+    }
+    // A capacity of 0 removes the limit. Lowering it doesn't withdraw anyone
+    // who is already enrolled.
+    if input.Capacity != nil {
+        session.Capacity = input.Capacity
+        if *input.Capacity == 0 { session.Capacity = nil }
+    }
+    // Cancelling a session needs a reason. The data layer checks that the
+    // session can move to its new status from the one it's in.
+    if input.Status != nil { session.Status = *input.Status }
+    if input.CancellationReason != nil { session.CancellationReason = optionalString(input.CancellationReason) }
 
     v := validator.New()
+
+    force := app.readBool(r.URL.Query(), "force", false, v)
+    if force && !app.hasPermission(r, "schedule:override") {
+        app.notPermittedResponse(w, r)
+        return
+    }
+
     if data.ValidateSession(v, session); !v.Valid() {
         app.failedValidationResponse(w, r, v.Errors)
         return
     }
 
-    err = app.models.Sessions.Update(session)
+    err = app.checkLocation(v, session.RegionID, session.FormationID, session.PostingID)
+    if err != nil {
+        app.serverErrorResponse(w, r, err)
+        return
+    }
+    if !v.Valid() {
+        app.failedValidationResponse(w, r, v.Errors)
+        return
+    }
+
+    err = app.models.Sessions.Update(app.auditActor(r), session, force)
     if err != nil {
+        var conflictErr *data.ScheduleConflictError
         switch {
+        case errors.As(err, &conflictErr):
+            app.scheduleConflictResponse(w, r, conflictErr.Conflicts)
+        case errors.Is(err, data.ErrInvalidTransition):
+            v.AddError("status", fmt.Sprintf("cannot change from %s to %s", before.Status, session.Status))
+            app.failedValidationResponse(w, r, v.Errors)
         case errors.Is(err, data.ErrEditConflict):
             app.editConflictResponse(w, r)
         default:
@@ -124,6 +215,17 @@ func (app *application) updateSessionHandler(w http.ResponseWriter, r *http.Requ
         return
     }
 
+    app.notifySessionChange(r, &before, session)
+
+    // Fill any places a raised capacity has opened up.
+    if input.Capacity != nil {
+        _, err := app.models.SessionEnrollments.PromoteWaitlisted(app.auditActor(r), session.ID)
+        if err != nil {
+            app.serverErrorResponse(w, r, err)
+            return
+        }
+    }
+
     err = app.writeJSON(w, http.StatusOK, envelope{"session": session}, nil)
     if err != nil {
         app.serverErrorResponse(w, r, err)
@@ -134,11 +236,13 @@ func (app *application) deleteSessionHandler(w http.ResponseWriter, r *http.Requ
     params := httprouter.ParamsFromContext(r.Context())
     id := params.ByName("id")
 
-    err := app.models.Sessions.Delete(id)
+    err := app.models.Sessions.Delete(app.auditActor(r), id)
     if err != nil {
         switch {
         case errors.Is(err, data.ErrRecordNotFound):
             app.notFoundResponse(w, r)
+        case errors.Is(err, data.ErrRecordInUse):
+            app.recordInUseResponse(w, r, "the session has started or has attendance and cannot be deleted; set its status to cancelled instead")
         default:
             app.serverErrorResponse(w, r, err)
         }
@@ -155,6 +259,11 @@ func (app *application) listSessionsHandler(w http.ResponseWriter, r *http.Reque
 	var input struct {
 		Location string
 		CourseID string
+		RegionID string
+		SeriesID string
+		Status   string
+		From     *time.Time
+		To       *time.Time
 		data.Filters
 	}
 
@@ -163,6 +272,14 @@ func (app *application) listSessionsHandler(w http.ResponseWriter, r *http.Reque
 
 	input.Location = app.readString(qs, "location", "")
 	input.CourseID = app.readString(qs, "course_id", "")
+	input.RegionID = app.readString(qs, "region_id", "")
+	input.SeriesID = app.readString(qs, "series_id", "")
+	input.Status = app.readString(qs, "status", "")
+	input.From = app.readTime(qs, "from", v)
+	input.To = app.readTime(qs, "to", v)
+	if input.From != nil && input.To != nil {
+		v.Check(input.From.Before(*input.To), "to", "must be after from")
+	}
 
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
@@ -174,7 +291,7 @@ func (app *application) listSessionsHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	sessions, metadata, err := app.models.Sessions.GetAll(input.Location, input.CourseID, input.Filters)
+	sessions, metadata, err := app.models.Sessions.GetAll(input.Location, input.CourseID, input.RegionID, input.SeriesID, input.Status, input.From, input.To, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
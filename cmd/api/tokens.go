@@ -1,9 +1,10 @@
 package main
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"errors"
 	"net/http"
-	"time"
 
 	"github.com/amari03/test1/internal/data"
 	"github.com/amari03/test1/internal/validator"
@@ -21,20 +22,35 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 		return
 	}
 
-	// 2. Validate the email and password.
+	// 2. Validate the email and password. The password policy only applies to
+	// new passwords, so someone whose password predates it can still log in.
 	v := validator.New()
 	data.ValidateEmail(v, input.Email)
-	data.ValidatePasswordPlaintext(v, input.Password)
+	v.Check(input.Password != "", "password", "must be provided")
 	if !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	// 3. Look up the user by email. If no user is found, send an invalid credentials response.
+	// 3. Turn the client away if this email address or IP has failed to log
+	// in too often recently.
+	if !app.checkLoginThrottle(w, r, input.Email) {
+		return
+	}
+
+	// 4. Look up the user by email. If no user is found, send an invalid
+	// credentials response, after hashing the password anyway so that the
+	// timing doesn't give away which emails have accounts.
 	user, err := app.models.Users.GetByEmail(input.Email)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
+			data.CompareDummyPassword(input.Password)
+			err = app.recordLoginFailure(r, input.Email, nil)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
 			app.invalidCredentialsResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
@@ -42,26 +58,159 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 		return
 	}
 
-	// 4. Check if the provided password matches the stored hash.
+	// 5. Check if the provided password matches the stored hash.
 	match, err := user.Password.Matches(input.Password)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 	if !match {
+		err = app.recordLoginFailure(r, input.Email, user)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
 		app.invalidCredentialsResponse(w, r)
 		return
 	}
 
-	// 5. If the password is correct, generate a new authentication token.
-	token, err := app.models.Tokens.New(user.ID, 24*time.Hour, data.ScopeAuthentication)
+	// Now that we have the plaintext, upgrade a hash made with an older
+	// algorithm or weaker parameters. This isn't worth failing the login
+	// over, so errors are only logged.
+	if user.Password.NeedsRehash() {
+		app.rehashPassword(r, user, input.Password)
+	}
+
+	// 6. Users with two-factor authentication get a short-lived token instead,
+	// which they exchange along with a code at POST /v1/tokens/mfa.
+	if user.MFAEnabled {
+		pendingToken, err := app.models.Tokens.New(user.ID, app.config.tokens.mfaPendingTTL, data.ScopeMFAPending)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusAccepted, envelope{"mfa_pending_token": pendingToken}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// 7. If the password is correct, send back a new authentication and refresh token.
+	app.completeLogin(w, r, user)
+}
+
+// rehashPassword replaces a user's password hash with one made by the
+// configured hasher.
+func (app *application) rehashPassword(r *http.Request, user *data.User, plaintext string) {
+	err := user.Password.Set(plaintext)
+	if err == nil {
+		err = app.models.Users.UpdatePassword(app.selfAuditActor(r, user), user)
+	}
+	if err != nil {
+		app.logger.Error(err.Error(), "user_id", user.ID)
+	}
+}
+
+// refreshAuthenticationTokenHandler exchanges a refresh token for a new
+// authentication and refresh token. Each refresh token can only be used once.
+func (app *application) refreshAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		TokenPlaintext string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if data.ValidateTokenPlaintext(v, input.TokenPlaintext); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	token, refreshToken, err := app.models.Tokens.Refresh(input.TokenPlaintext, app.config.tokens.authenticationTTL, app.config.tokens.refreshTTL)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.invalidRefreshTokenResponse(w, r)
+		case errors.Is(err, data.ErrTokenReused):
+			// The login has already been revoked, so the client gets the same
+			// response as for any other dead token.
+			app.logger.Warn("refresh token reused; revoked the login", "ip", r.RemoteAddr)
+			app.invalidRefreshTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"authentication_token": token, "refresh_token": refreshToken}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createActivationTokenHandler handles POST /v1/tokens/activation
+// It sends a new activation token to a user who hasn't activated their
+// account, for when the first one expired or went missing. Earlier activation
+// tokens stop working. Like a password reset, the response doesn't say
+// whether the account exists.
+func (app *application) createActivationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if data.ValidateEmail(v, input.Email); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	// 6. Send the token back to the client.
-	err = app.writeJSON(w, http.StatusCreated, envelope{"authentication_token": token}, nil)
+	message := envelope{"message": "if a matching account needs activating, we have sent a new activation token"}
+
+	user, err := app.models.Users.GetByEmail(input.Email)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.writeJSON(w, http.StatusAccepted, message, nil)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !user.Activated {
+		err = app.models.Tokens.DeleteAllForUser(data.ScopeActivation, user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		token, err := app.models.Tokens.New(user.ID, app.config.tokens.activationTTL, data.ScopeActivation)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		emailData := map[string]interface{}{
+			"expiresIn": humanDuration(app.config.tokens.activationTTL),
+		}
+		err = app.enqueueTokenEmail(user.Email, "token_activation.tmpl", emailData, "activationToken", token)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusAccepted, message, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -96,23 +245,21 @@ func (app *application) createPasswordResetTokenHandler(w http.ResponseWriter, r
 		return
 	}
 
-	// Generate a password reset token with a 45-minute expiry.
-	token, err := app.models.Tokens.New(user.ID, 45*time.Minute, data.ScopePasswordReset)
+	token, err := app.models.Tokens.New(user.ID, app.config.tokens.passwordResetTTL, data.ScopePasswordReset)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	// Send the email in a background goroutine.
-	app.background(func() {
-		emailData := map[string]interface{}{
-			"passwordResetToken": token.Plaintext,
-		}
-		err = app.mailer.Send(user.Email, "password_reset.tmpl", emailData)
-		if err != nil {
-			app.logger.Error(err.Error())
-		}
-	})
+	// Queue the email for the job workers.
+	emailData := map[string]interface{}{
+		"expiresIn": humanDuration(app.config.tokens.passwordResetTTL),
+	}
+	err = app.enqueueTokenEmail(user.Email, "password_reset.tmpl", emailData, "passwordResetToken", token)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
 
 	// Send the 202 Accepted response.
 	err = app.writeJSON(w, http.StatusAccepted, envelope{"message": "if a matching account exists, we have sent instructions to reset your password"}, nil)
@@ -160,7 +307,7 @@ func (app *application) updatePasswordHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	err = app.models.Users.UpdatePassword(user)
+	err = app.models.Users.UpdatePassword(app.selfAuditActor(r, user), user)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
@@ -178,8 +325,90 @@ func (app *application) updatePasswordHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// Log the user out everywhere, in case the reset was because someone
+	// else knew the old password.
+	err = app.revokeAllLogins(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"message": "your password was successfully reset"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
+}
+
+// listAuthenticationTokensHandler lists the current user's active sessions,
+// so they can see where they are logged in.
+func (app *application) listAuthenticationTokensHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	tokens, err := app.models.Tokens.GetAllForUser(data.ScopeAuthentication, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Point out which of them is making this request.
+	currentHash := sha256.Sum256([]byte(app.contextGetAuthenticationToken(r)))
+	currentID := ""
+	for _, token := range tokens {
+		if bytes.Equal(token.Hash, currentHash[:]) {
+			currentID = token.ID
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"authentication_tokens": tokens, "current_token_id": currentID}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteAuthenticationTokenHandler logs out by revoking the token used to make
+// the request, along with its refresh token.
+func (app *application) deleteAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	err := app.models.Tokens.Revoke(data.ScopeAuthentication, app.contextGetAuthenticationToken(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "you have been logged out"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteAllAuthenticationTokensHandler logs the current user out everywhere by
+// revoking all of their authentication and refresh tokens.
+func (app *application) deleteAllAuthenticationTokensHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	err := app.revokeAllLogins(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "you have been logged out of all sessions"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// revokeAllLogins deletes all of a user's authentication and refresh tokens.
+func (app *application) revokeAllLogins(userID string) error {
+	for _, scope := range []string{data.ScopeAuthentication, data.ScopeRefresh} {
+		err := app.models.Tokens.DeleteAllForUser(scope, userID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
\ No newline at end of file
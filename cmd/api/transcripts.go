@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/amari03/test1/internal/data"
+	"github.com/julienschmidt/httprouter"
+)
+
+// getOfficerTranscriptHandler handles GET /v1/officers/:id/transcript
+func (app *application) getOfficerTranscriptHandler(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id := params.ByName("id")
+
+	officer, err := app.models.Officers.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	transcript, err := app.models.Attendance.GetTranscript(officer.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"officer": officer, "transcript": transcript}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -5,7 +5,6 @@ import (
     "errors"
     "fmt"
     "net/http"
-    "time"
     
 
     "github.com/amari03/test1/internal/data"
@@ -18,7 +17,6 @@ func (app *application) createUserHandler(w http.ResponseWriter, r *http.Request
     var input struct {
         Email    string `json:"email"`
         Password string `json:"password"`
-        Role     string `json:"role"`
     }
 
     err := app.readJSON(w, r, &input)
@@ -27,9 +25,18 @@ func (app *application) createUserHandler(w http.ResponseWriter, r *http.Request
         return
     }
 
+    // When registration is open anyone can register, so new accounts get the
+    // least privileged role until an admin changes it. When it is closed,
+    // everyone has to be invited. The first admin is made with
+    // cmd/createadmin.
+    if !app.config.registration.open {
+        app.registrationClosedResponse(w, r)
+        return
+    }
+
     user := &data.User{
         Email: input.Email,
-        Role:  input.Role,
+        Role:  "viewer",
         Activated: false, //users not activiated by default
     }
 
@@ -46,7 +53,7 @@ func (app *application) createUserHandler(w http.ResponseWriter, r *http.Request
         return
     }
 
-    err = app.models.Users.Insert(user)
+    err = app.models.Users.Insert(app.selfAuditActor(r, user), user)
     if err != nil {
         switch {
         // If we get a duplicate email error, send a specific validation error.
@@ -60,25 +67,23 @@ func (app *application) createUserHandler(w http.ResponseWriter, r *http.Request
     }
     
     // Generate a new activation token for the user.
-    token, err := app.models.Tokens.New(user.ID, 3*24*time.Hour, data.ScopeActivation)
+    token, err := app.models.Tokens.New(user.ID, app.config.tokens.activationTTL, data.ScopeActivation)
     if err != nil {
         app.serverErrorResponse(w, r, err)
         return
     }
 
-     // Send the welcome email with the activation token.
-    app.background(func() {
-        // Create a map to hold the data for the template.
-        emailData := map[string]interface{}{
-            "activationToken": token.Plaintext,
-            "userID":          user.ID,
-        }
+     // Queue the welcome email with the activation token.
+    emailData := map[string]interface{}{
+        "userID":    user.ID,
+        "expiresIn": humanDuration(app.config.tokens.activationTTL),
+    }
 
-        err = app.mailer.Send(user.Email, "user_welcome.tmpl", emailData)
-        if err != nil {
-            app.logger.Error(err.Error())
-        }
-    })
+    err = app.enqueueTokenEmail(user.Email, "user_welcome.tmpl", emailData, "activationToken", token)
+    if err != nil {
+        app.serverErrorResponse(w, r, err)
+        return
+    }
 
 
     // Per the slides, we'll want to send a welcome email here.
@@ -94,9 +99,37 @@ func (app *application) createUserHandler(w http.ResponseWriter, r *http.Request
     }
 }
 
+// readUserIDParam returns the user ID from the URL, where "me" stands for the
+// current user. /v1/users/me is served by the /v1/users/:id routes because
+// httprouter can't register both.
+func (app *application) readUserIDParam(r *http.Request) string {
+    id := httprouter.ParamsFromContext(r.Context()).ByName("id")
+    if id == "me" {
+        return app.contextGetUser(r).ID
+    }
+    return id
+}
+
+// isUserAdmin reports whether a user may manage other users' accounts. As
+// with requirePermission, an admin whose role requires two-factor
+// authentication can't until they have set it up.
+func (app *application) isUserAdmin(user *data.User) bool {
+    if !user.MFAEnabled && app.mfaRequired(user) {
+        return false
+    }
+    return data.PermissionsForRole(user.Role).Include("users:admin")
+}
+
+// getUserHandler returns a user's account. Users who aren't admins can only
+// see their own.
 func (app *application) getUserHandler(w http.ResponseWriter, r *http.Request) {
-    params := httprouter.ParamsFromContext(r.Context())
-    id := params.ByName("id")
+    currentUser := app.contextGetUser(r)
+    id := app.readUserIDParam(r)
+
+    if id != currentUser.ID && !app.isUserAdmin(currentUser) {
+        app.notPermittedResponse(w, r)
+        return
+    }
 
     user, err := app.models.Users.Get(id)
     if err != nil {
@@ -114,13 +147,27 @@ func (app *application) getUserHandler(w http.ResponseWriter, r *http.Request) {
     }
 }
 
+// updateUserHandler updates a user's account. Users who aren't admins can
+// only edit their own, and only admins can change roles. Nobody can change
+// their own role, so an admin can't lock themselves out. A new email address
+// is only held as pending until it is confirmed; see requestEmailChange.
 func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request) {
-    params := httprouter.ParamsFromContext(r.Context())
-    id := params.ByName("id")
+    currentUser := app.contextGetUser(r)
+    id := app.readUserIDParam(r)
+
+    if id != currentUser.ID && !app.isUserAdmin(currentUser) {
+        app.notPermittedResponse(w, r)
+        return
+    }
 
     user, err := app.models.Users.Get(id)
     if err != nil {
-        app.notFoundResponse(w, r)
+        switch {
+        case errors.Is(err, data.ErrRecordNotFound):
+            app.notFoundResponse(w, r)
+        default:
+            app.serverErrorResponse(w, r, err)
+        }
         return
     }
 
@@ -135,24 +182,65 @@ func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request
         return
     }
 
-    if input.Email != nil {
-        user.Email = *input.Email
+    v := validator.New()
+
+    var newEmail string
+    if input.Email != nil && *input.Email != user.Email {
+        newEmail = *input.Email
+        data.ValidateEmail(v, newEmail)
     }
-    if input.Role != nil {
+
+    if input.Role != nil && *input.Role != user.Role {
+        if !app.isUserAdmin(currentUser) {
+            app.notPermittedResponse(w, r)
+            return
+        }
+        v.Check(user.ID != currentUser.ID, "role", "you cannot change your own role")
         user.Role = *input.Role
     }
 
-    v := validator.New()
     if data.ValidateUser(v, user); !v.Valid() {
         app.failedValidationResponse(w, r, v.Errors)
         return
     }
 
-    err = app.models.Users.Update(user)
+    if newEmail != "" {
+        err = app.models.Users.SetPendingEmail(app.auditActor(r), user, newEmail)
+        if err != nil {
+            switch {
+            case errors.Is(err, data.ErrDuplicateEmail):
+                v.AddError("email", "a user with this email address already exists")
+                app.failedValidationResponse(w, r, v.Errors)
+            case errors.Is(err, data.ErrEditConflict):
+                app.editConflictResponse(w, r)
+            default:
+                app.serverErrorResponse(w, r, err)
+            }
+            return
+        }
+    }
+
+    err = app.models.Users.Update(app.auditActor(r), user)
     if err != nil {
-        app.serverErrorResponse(w, r, err)
+        switch {
+        case errors.Is(err, data.ErrDuplicateEmail):
+            v.AddError("email", "a user with this email address already exists")
+            app.failedValidationResponse(w, r, v.Errors)
+        case errors.Is(err, data.ErrEditConflict):
+            app.editConflictResponse(w, r)
+        default:
+            app.serverErrorResponse(w, r, err)
+        }
         return
     }
+
+    if newEmail != "" {
+        err = app.requestEmailChange(user)
+        if err != nil {
+            app.serverErrorResponse(w, r, err)
+            return
+        }
+    }
     
     err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
     if err != nil {
@@ -160,27 +248,27 @@ func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request
     }
 }
 
+// deleteUserHandler deletes a user's account. The route is admin only.
 func (app *application) deleteUserHandler(w http.ResponseWriter, r *http.Request) {
-        params := httprouter.ParamsFromContext(r.Context())
-        id := params.ByName("id")
+    id := app.readUserIDParam(r)
 
-        err := app.models.Users.Delete(id)
-        if err != nil {
-            switch {
-            case errors.Is(err, data.ErrRecordNotFound):
-                app.notFoundResponse(w, r)
-            default:
-                app.serverErrorResponse(w, r, err)
-            }
-            return
-        }
-
-        err = app.writeJSON(w, http.StatusOK, envelope{"message": "user successfully deleted"}, nil)
-        if err != nil {
+    err := app.models.Users.Delete(app.auditActor(r), id)
+    if err != nil {
+        switch {
+        case errors.Is(err, data.ErrRecordNotFound):
+            app.notFoundResponse(w, r)
+        default:
             app.serverErrorResponse(w, r, err)
         }
+        return
     }
 
+    err = app.writeJSON(w, http.StatusOK, envelope{"message": "user successfully deleted"}, nil)
+    if err != nil {
+        app.serverErrorResponse(w, r, err)
+    }
+}
+
 func (app *application) listUsersHandler(w http.ResponseWriter, r *http.Request) {
     users, err := app.models.Users.GetAll()
     if err != nil {
@@ -225,7 +313,7 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 
     user.Activated = true
 
-    err = app.models.Users.Update(user)
+    err = app.models.Users.Update(app.selfAuditActor(r, user), user)
     if err != nil {
         switch {
         case errors.Is(err, data.ErrEditConflict):
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/amari03/test1/internal/data"
+)
+
+// jobLockTimeout is how long a job may run before it is assumed that its
+// worker died and another worker may claim it. It must comfortably exceed
+// the longest import.
+const jobLockTimeout = 15 * time.Minute
+
+// jobPollInterval is how long an idle worker waits before checking the queue
+// again.
+const jobPollInterval = time.Second
+
+// A jobHandler does the work for one kind of job. Returning an error fails
+// the attempt and the job is retried with backoff.
+type jobHandler func(job *data.Job) error
+
+// emailPayload is an email waiting to be sent. An email carrying a token
+// holds the token's ID rather than its plaintext, which is made when the
+// email is sent and added to Data under TokenKey.
+type emailPayload struct {
+	Recipient string                 `json:"recipient"`
+	Template  string                 `json:"template"`
+	Data      map[string]interface{} `json:"data"`
+	TokenID   string                 `json:"token_id,omitempty"`
+	TokenKey  string                 `json:"token_key,omitempty"`
+}
+
+type importPayload struct {
+	ImportJobID string `json:"import_job_id"`
+}
+
+// enqueueJob adds a job to the queue for the worker pool to pick up.
+func (app *application) enqueueJob(kind string, payload interface{}) error {
+	js, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	job := &data.Job{
+		Kind:        kind,
+		Payload:     js,
+		MaxAttempts: app.config.jobs.maxAttempts,
+	}
+	return app.models.Jobs.Insert(job)
+}
+
+// enqueueEmail queues an email built from one of the mailer's templates.
+func (app *application) enqueueEmail(recipient, templateFile string, emailData map[string]interface{}) error {
+	return app.enqueueJob(data.JobKindEmail, emailPayload{
+		Recipient: recipient,
+		Template:  templateFile,
+		Data:      emailData,
+	})
+}
+
+// enqueueTokenEmail queues an email that gives the recipient a token, under
+// key in the template's data. Only the token's ID is queued, so that the
+// plaintext is never stored.
+func (app *application) enqueueTokenEmail(recipient, templateFile string, emailData map[string]interface{}, key string, token *data.Token) error {
+	return app.enqueueJob(data.JobKindEmail, emailPayload{
+		Recipient: recipient,
+		Template:  templateFile,
+		Data:      emailData,
+		TokenID:   token.ID,
+		TokenKey:  key,
+	})
+}
+
+// startWorkers starts the worker pool. Workers stop claiming jobs once ctx is
+// cancelled, after finishing the job they are on; they are tracked by app.wg
+// so that shutdown waits for them.
+func (app *application) startWorkers(ctx context.Context) {
+	handlers := map[string]jobHandler{
+		data.JobKindEmail:  app.sendEmailJob,
+		data.JobKindImport: app.importJob,
+	}
+
+	for i := 0; i < app.config.jobs.workers; i++ {
+		app.wg.Add(1)
+		go func() {
+			defer app.wg.Done()
+			app.work(ctx, handlers)
+		}()
+	}
+}
+
+// work claims and runs jobs until ctx is cancelled.
+func (app *application) work(ctx context.Context, handlers map[string]jobHandler) {
+	for ctx.Err() == nil {
+		job, err := app.models.Jobs.Claim(jobLockTimeout)
+		if err != nil {
+			if !errors.Is(err, data.ErrRecordNotFound) {
+				app.logger.Error(err.Error())
+			}
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(jobPollInterval):
+			}
+			continue
+		}
+
+		app.runJob(job, handlers)
+	}
+}
+
+// runJob runs a claimed job and records the outcome.
+func (app *application) runJob(job *data.Job, handlers map[string]jobHandler) {
+	var err error
+
+	handler, ok := handlers[job.Kind]
+	switch {
+	case job.Attempts > job.MaxAttempts:
+		err = errors.New("the job stopped responding on its final attempt")
+	case !ok:
+		err = fmt.Errorf("no handler for job kind %q", job.Kind)
+	default:
+		err = callJobHandler(handler, job)
+	}
+
+	if err != nil {
+		app.logger.Error(err.Error(), "job_id", job.ID, "kind", job.Kind, "attempt", job.Attempts)
+		err = app.models.Jobs.Fail(job, err.Error())
+	} else {
+		err = app.models.Jobs.Complete(job)
+	}
+
+	if err != nil {
+		app.logger.Error(err.Error(), "job_id", job.ID)
+	}
+}
+
+// callJobHandler runs a handler, turning a panic into an error so that one
+// bad job cannot take its worker down.
+func callJobHandler(handler jobHandler, job *data.Job) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	return handler(job)
+}
+
+// sendEmailJob handles data.JobKindEmail.
+func (app *application) sendEmailJob(job *data.Job) error {
+	var payload emailPayload
+	err := json.Unmarshal(job.Payload, &payload)
+	if err != nil {
+		return err
+	}
+
+	if payload.TokenID != "" {
+		token, err := app.models.Tokens.Reissue(payload.TokenID)
+		if err != nil {
+			if errors.Is(err, data.ErrRecordNotFound) {
+				// The token has expired or been used, so there's nothing
+				// left to send.
+				app.logger.Info("token no longer valid, email not sent", "job_id", job.ID, "template", payload.Template)
+				return nil
+			}
+			return err
+		}
+		payload.Data[payload.TokenKey] = token.Plaintext
+	}
+
+	return app.mailer.Send(payload.Recipient, payload.Template, payload.Data)
+}
+
+// importJob handles data.JobKindImport.
+func (app *application) importJob(job *data.Job) error {
+	var payload importPayload
+	err := json.Unmarshal(job.Payload, &payload)
+	if err != nil {
+		return err
+	}
+
+	importJob, err := app.models.ImportJobs.Get(payload.ImportJobID)
+	if err != nil {
+		// Nothing to do if the import job has gone.
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	return app.runImportJob(importJob, job.Attempts >= job.MaxAttempts)
+}
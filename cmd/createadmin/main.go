@@ -0,0 +1,100 @@
+// Command createadmin creates an activated admin account, so that there is
+// someone to invite or promote everyone else. The password is read from
+// standard input, so that it doesn't end up in the shell history:
+//
+//	read -s PASSWORD && echo "$PASSWORD" | go run ./cmd/createadmin -email=admin@example.com
+//
+// It takes the same -password-* flags as cmd/api, so that the password is
+// hashed and checked against the policy and blocklist the API will use.
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/amari03/test1/internal/data"
+	"github.com/amari03/test1/internal/validator"
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	var dsn, email string
+	var passwords data.PasswordConfig
+	flag.StringVar(&dsn, "db-dsn", os.Getenv("CRABOO_DB_DSN"), "PostgreSQL DSN")
+	flag.StringVar(&email, "email", "", "Email address of the new admin")
+	passwords.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	err := passwords.Apply()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	err = run(dsn, email)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(dsn, email string) error {
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		return fmt.Errorf("reading password: %w", err)
+	}
+	password = strings.TrimRight(password, "\r\n")
+
+	user := &data.User{
+		Email:     email,
+		Role:      "admin",
+		Activated: true,
+	}
+	err = user.Password.Set(password)
+	if err != nil {
+		return err
+	}
+
+	v := validator.New()
+	if data.ValidateUser(v, user); !v.Valid() {
+		for field, message := range v.Errors {
+			fmt.Fprintf(os.Stderr, "%s %s\n", field, message)
+		}
+		return errors.New("invalid admin details")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = db.PingContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = data.NewModels(db).Users.Insert(data.AuditActor{}, user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateEmail):
+			return errors.New("a user with this email address already exists")
+		default:
+			return err
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "created admin %s (%s)\n", user.Email, user.ID)
+	return nil
+}
@@ -0,0 +1,293 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"time"
+
+	"github.com/amari03/test1/internal/validator"
+	"github.com/lib/pq"
+)
+
+// apiKeyPrefixLength is how much of a key is stored in the clear so that it
+// can be recognised in listings.
+const apiKeyPrefixLength = 8
+
+// APIKey is a long-lived credential an integration uses in place of a user's
+// password. It acts as its owner, but only with the listed permissions. The
+// plaintext is only known when the key is created.
+type APIKey struct {
+	ID          string      `json:"id"`
+	UserID      string      `json:"user_id"`
+	Name        string      `json:"name"`
+	Plaintext   string      `json:"key,omitempty"`
+	Prefix      string      `json:"prefix"`
+	Hash        []byte      `json:"-"`
+	Permissions Permissions `json:"permissions"`
+	CreatedAt   time.Time   `json:"created_at"`
+	LastUsedAt  *time.Time  `json:"last_used_at,omitempty"`
+}
+
+type APIKeyModel struct {
+	DB *sql.DB
+}
+
+// generateAPIKey fills in a new random key, its prefix and its hash.
+func generateAPIKey(key *APIKey) error {
+	randomBytes := make([]byte, 20)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return err
+	}
+
+	key.Plaintext = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
+	key.Prefix = key.Plaintext[:apiKeyPrefixLength]
+	hash := sha256.Sum256([]byte(key.Plaintext))
+	key.Hash = hash[:]
+	return nil
+}
+
+// ValidateAPIKey checks a new key. The key can't be given any permission that
+// its owner's role doesn't have.
+func ValidateAPIKey(v *validator.Validator, key *APIKey, owner *User) {
+	v.Check(key.Name != "", "name", "must be provided")
+	v.Check(len(key.Name) <= 100, "name", "must not be more than 100 bytes long")
+
+	v.Check(len(key.Permissions) > 0, "permissions", "must contain at least one permission")
+	ownerPermissions := PermissionsForRole(owner.Role)
+	seen := make(map[string]bool)
+	for _, code := range key.Permissions {
+		v.Check(adminPermissions.Include(code), "permissions", "must only contain known permissions")
+		v.Check(ownerPermissions.Include(code), "permissions", "must not contain permissions your role doesn't have")
+		v.Check(!seen[code], "permissions", "must not contain duplicate values")
+		seen[code] = true
+	}
+}
+
+// Insert generates the key and stores it for its owner. The key itself is
+// kept out of the audit log.
+func (m APIKeyModel) Insert(actor AuditActor, key *APIKey) error {
+	err := generateAPIKey(key)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO api_keys (user_id, name, prefix, hash, permissions)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, created_at`
+
+	args := []interface{}{key.UserID, key.Name, key.Prefix, key.Hash, pq.Array([]string(key.Permissions))}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	logged := *key
+	logged.Plaintext = ""
+	err = audit(ctx, tx, actor, AuditActionInsert, "api_key", key.ID, nil, logged)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const apiKeyColumns = `api_keys.id, api_keys.user_id, api_keys.name, api_keys.prefix, api_keys.hash,
+               api_keys.permissions, api_keys.created_at, api_keys.last_used_at`
+
+func scanAPIKey(row interface{ Scan(...interface{}) error }, key *APIKey, extra ...interface{}) error {
+	dest := append([]interface{}{
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.Hash,
+		pq.Array((*[]string)(&key.Permissions)),
+		&key.CreatedAt,
+		&key.LastUsedAt,
+	}, extra...)
+	return row.Scan(dest...)
+}
+
+// Get a specific API key by ID.
+func (m APIKeyModel) Get(id string) (*APIKey, error) {
+	query := `
+        SELECT ` + apiKeyColumns + `
+        FROM api_keys
+        WHERE id = $1`
+
+	var key APIKey
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := scanAPIKey(m.DB.QueryRowContext(ctx, query, id), &key)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &key, nil
+}
+
+// GetForKey returns the API key matching a plaintext key, along with its
+// owner.
+func (m APIKeyModel) GetForKey(plaintext string) (*APIKey, *User, error) {
+	query := `
+        SELECT ` + apiKeyColumns + `,
+               users.id, users.email, users.password_hash, users.role, users.activated, users.totp_enabled,
+               users.version, users.created_at
+        FROM api_keys
+        INNER JOIN users ON users.id = api_keys.user_id
+        WHERE api_keys.hash = $1`
+
+	hash := sha256.Sum256([]byte(plaintext))
+
+	var key APIKey
+	var user User
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := scanAPIKey(m.DB.QueryRowContext(ctx, query, hash[:]), &key,
+		&user.ID,
+		&user.Email,
+		&user.Password.hash,
+		&user.Role,
+		&user.Activated,
+		&user.MFAEnabled,
+		&user.Version,
+		&user.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil, ErrRecordNotFound
+		default:
+			return nil, nil, err
+		}
+	}
+	return &key, &user, nil
+}
+
+// GetAllForUser returns a user's API keys, newest first.
+func (m APIKeyModel) GetAllForUser(userID string) ([]*APIKey, error) {
+	query := `
+        SELECT ` + apiKeyColumns + `
+        FROM api_keys
+        WHERE user_id = $1
+        ORDER BY created_at DESC, id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*APIKey{}
+	for rows.Next() {
+		var key APIKey
+		err := scanAPIKey(rows, &key)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, &key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Touch records that a key has just been used. As with tokens, last_used_at
+// is only moved on if it is more than a minute old.
+func (m APIKeyModel) Touch(id string) error {
+	query := `
+        UPDATE api_keys
+        SET last_used_at = NOW()
+        WHERE id = $1
+        AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, id)
+	return err
+}
+
+// Delete revokes an API key.
+func (m APIKeyModel) Delete(actor AuditActor, id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	n, err := deleteAPIKeys(ctx, tx, actor, "id = $1", id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrRecordNotFound
+	}
+	return tx.Commit()
+}
+
+// deleteAPIKeys deletes and audits the API keys matching where, a condition
+// on $1, which is arg, and returns how many there were.
+func deleteAPIKeys(ctx context.Context, q queryer, actor AuditActor, where, arg string) (int, error) {
+	query := `
+        DELETE FROM api_keys
+        WHERE ` + where + `
+        RETURNING ` + apiKeyColumns
+
+	rows, err := q.QueryContext(ctx, query, arg)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	keys := []*APIKey{}
+	for rows.Next() {
+		var key APIKey
+		err := scanAPIKey(rows, &key)
+		if err != nil {
+			return 0, err
+		}
+		keys = append(keys, &key)
+	}
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	for _, key := range keys {
+		err = audit(ctx, q, actor, AuditActionDelete, "api_key", key.ID, key, nil)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(keys), nil
+}
@@ -0,0 +1,102 @@
+package data
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/amari03/test1/internal/validator"
+	"github.com/stretchr/testify/require"
+)
+
+// setupAPIKeysTestDB adds an api_keys table to the users test DB.
+func setupAPIKeysTestDB(t *testing.T) *sql.DB {
+	db := setupUsersTestDB(t)
+
+	createTableSQL := `
+    CREATE TABLE IF NOT EXISTS api_keys (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+        user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        name TEXT NOT NULL,
+        prefix TEXT NOT NULL,
+        hash BYTEA NOT NULL UNIQUE,
+        permissions TEXT[] NOT NULL,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        last_used_at TIMESTAMPTZ
+    );`
+	_, err := db.Exec(createTableSQL)
+	require.NoError(t, err)
+
+	// Registered after setupUsersTestDB's cleanup, so it runs first.
+	t.Cleanup(func() {
+		_, err := db.Exec("DROP TABLE IF EXISTS api_keys;")
+		require.NoError(t, err)
+	})
+
+	return db
+}
+
+func TestAPIKeyModel(t *testing.T) {
+	db := setupAPIKeysTestDB(t)
+	users := UserModel{DB: db}
+	m := APIKeyModel{DB: db}
+
+	user := newTestUser(t)
+	require.NoError(t, users.Insert(AuditActor{}, user))
+
+	key := &APIKey{UserID: user.ID, Name: "HR sync", Permissions: Permissions{"officers:read"}}
+	require.NoError(t, m.Insert(AuditActor{}, key))
+	require.NotEmpty(t, key.ID)
+	require.Equal(t, key.Plaintext[:apiKeyPrefixLength], key.Prefix)
+
+	fetched, owner, err := m.GetForKey(key.Plaintext)
+	require.NoError(t, err)
+	require.Equal(t, key.ID, fetched.ID)
+	require.Equal(t, user.ID, owner.ID)
+	require.Equal(t, Permissions{"officers:read"}, fetched.Permissions)
+	require.Empty(t, fetched.Plaintext)
+
+	_, _, err = m.GetForKey("not-a-key")
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	require.NoError(t, m.Touch(key.ID))
+	keys, err := m.GetAllForUser(user.ID)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	require.NotNil(t, keys[0].LastUsedAt)
+
+	require.NoError(t, m.Delete(AuditActor{}, key.ID))
+	require.ErrorIs(t, m.Delete(AuditActor{}, key.ID), ErrRecordNotFound)
+	_, _, err = m.GetForKey(key.Plaintext)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestValidateAPIKey(t *testing.T) {
+	contributor := &User{Role: "contributor"}
+
+	v := validator.New()
+	ValidateAPIKey(v, &APIKey{Name: "Regional script", Permissions: Permissions{"officers:read", "attendance:write"}}, contributor)
+	require.True(t, v.Valid())
+
+	tests := []struct {
+		name        string
+		permissions Permissions
+		want        string
+	}{
+		{"none", Permissions{}, "must contain at least one permission"},
+		{"unknown", Permissions{"officers:fly"}, "must only contain known permissions"},
+		{"beyond the owner's role", Permissions{"users:admin"}, "must not contain permissions your role doesn't have"},
+		{"duplicate", Permissions{"officers:read", "officers:read"}, "must not contain duplicate values"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateAPIKey(v, &APIKey{Name: "Regional script", Permissions: tt.permissions}, contributor)
+			require.Equal(t, tt.want, v.Errors["permissions"])
+		})
+	}
+
+	v = validator.New()
+	ValidateAPIKey(v, &APIKey{Permissions: Permissions{"officers:read"}}, contributor)
+	require.Contains(t, v.Errors, "name")
+}
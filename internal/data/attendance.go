@@ -31,38 +31,190 @@ func ValidateAttendance(v *validator.Validator, attendance *Attendance) {
     v.Check(attendance.CreditedHours >= 0, "credited_hours", "must be zero or greater")
 }
 
-func (m AttendanceModel) Insert(attendance *Attendance) error {
+// Insert records an officer's attendance. It returns ErrRecordNotFound if
+// the session doesn't exist, ErrAttendanceLocked if it is completed, and a
+// *ScheduleConflictError if the officer is already in a session at the same
+// time, unless force is set; then the override is recorded in the audit log.
+func (m AttendanceModel) Insert(actor AuditActor, attendance *Attendance, force bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockSchedule(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	check, err := sessionSchedule(ctx, tx, attendance.SessionID)
+	if err != nil {
+		return err
+	}
+	check.Location = ""
+	check.OfficerIDs = []string{attendance.OfficerID}
+
+	conflicts, err := checkSchedule(ctx, tx, check, force)
+	if err != nil {
+		return err
+	}
+
+	err = insertAttendance(ctx, tx, attendance)
+	if err != nil {
+		return err
+	}
+
+	err = audit(ctx, tx, actor, AuditActionInsert, "attendance", attendance.ID, nil, attendance)
+	if err != nil {
+		return err
+	}
+	err = auditOverride(ctx, tx, actor, "attendance", attendance.ID, conflicts)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertAttendance inserts an attendance record using q, which may be a
+// transaction.
+func insertAttendance(ctx context.Context, q queryer, attendance *Attendance) error {
+	err := checkAttendanceOpen(ctx, q, attendance.SessionID)
+	if err != nil {
+		return err
+	}
+
 	query := `
         INSERT INTO attendance (officer_id, session_id, status, credited_hours)
         VALUES ($1, $2, $3, $4)
         RETURNING id, created_at, version`
 
 	args := []interface{}{attendance.OfficerID, attendance.SessionID, attendance.Status, attendance.CreditedHours}
+
+	return q.QueryRowContext(ctx, query, args...).Scan(&attendance.ID, &attendance.CreatedAt, &attendance.Version)
+}
+
+// checkAttendanceOpen returns ErrAttendanceLocked if a session is completed,
+// as its attendance can no longer change. The session's row is locked until
+// the end of the transaction, so it can't be completed in the meantime. A
+// session that doesn't exist is left for the caller to report.
+func checkAttendanceOpen(ctx context.Context, q queryer, sessionID string) error {
+	var status string
+	err := q.QueryRowContext(ctx, `SELECT status FROM sessions WHERE id = $1 FOR SHARE`, sessionID).Scan(&status)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil
+		default:
+			return err
+		}
+	}
+	if status == SessionStatusCompleted {
+		return ErrAttendanceLocked
+	}
+	return nil
+}
+
+// InsertRoster pre-populates a session's attendance from its enrollments:
+// every enrolled officer without an attendance record gets one, marked as
+// attended with the session's default credit, so that only the exceptions
+// need recording afterwards. It returns the records it created, or
+// ErrAttendanceLocked if the session is completed.
+func (m AttendanceModel) InsertRoster(actor AuditActor, sessionID string) ([]*Attendance, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(&attendance.ID, &attendance.CreatedAt, &attendance.Version)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	hours, err := sessionDefaultCreditHours(ctx, tx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkAttendanceOpen(ctx, tx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+        INSERT INTO attendance (officer_id, session_id, status, credited_hours)
+        SELECT officer_id, session_id, 'attended', $2
+        FROM session_enrollments
+        WHERE session_id = $1 AND status = 'enrolled'
+        ORDER BY created_at, id
+        ON CONFLICT (officer_id, session_id) DO NOTHING
+        RETURNING ` + attendanceColumns
+
+	rows, err := tx.QueryContext(ctx, query, sessionID, hours)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []*Attendance{}
+	for rows.Next() {
+		var attendance Attendance
+		if err := scanAttendance(rows, &attendance); err != nil {
+			return nil, err
+		}
+		records = append(records, &attendance)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for _, attendance := range records {
+		err = audit(ctx, tx, actor, AuditActionInsert, "attendance", attendance.ID, nil, attendance)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+const attendanceColumns = `id, officer_id, session_id, status, credited_hours, created_at, version`
+
+func scanAttendance(row interface{ Scan(...interface{}) error }, attendance *Attendance) error {
+	return row.Scan(
+		&attendance.ID,
+		&attendance.OfficerID,
+		&attendance.SessionID,
+		&attendance.Status,
+		&attendance.CreditedHours,
+		&attendance.CreatedAt,
+		&attendance.Version,
+	)
 }
 
 func (m AttendanceModel) Get(id string) (*Attendance, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return getAttendance(ctx, m.DB, id)
+}
+
+// getAttendance fetches an attendance record using q, which may be a
+// transaction.
+func getAttendance(ctx context.Context, q queryer, id string) (*Attendance, error) {
 	query := `
-        SELECT id, officer_id, session_id, status, credited_hours, created_at, version
+        SELECT ` + attendanceColumns + `
         FROM attendance
         WHERE id = $1`
 
 	var record Attendance
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(
-		&record.ID,
-		&record.OfficerID,
-		&record.SessionID,
-		&record.Status,
-		&record.CreditedHours,
-		&record.CreatedAt,
-		&record.Version,
-	)
+	err := scanAttendance(q.QueryRowContext(ctx, query, id), &record)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -74,8 +226,9 @@ func (m AttendanceModel) Get(id string) (*Attendance, error) {
 	return &record, nil
 }
 
-
-func (m AttendanceModel) Update(attendance *Attendance) error {
+// Update changes an attendance record. It returns ErrAttendanceLocked if the
+// session is completed.
+func (m AttendanceModel) Update(actor AuditActor, attendance *Attendance) error {
 	query := `
         UPDATE attendance
         SET status = $1, credited_hours = $2, version = version + 1
@@ -91,7 +244,28 @@ func (m AttendanceModel) Update(attendance *Attendance) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&attendance.Version)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = checkAttendanceOpen(ctx, tx, attendance.SessionID)
+	if err != nil {
+		return err
+	}
+
+	before, err := getAttendance(ctx, tx, attendance.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRecordNotFound):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&attendance.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -100,30 +274,89 @@ func (m AttendanceModel) Update(attendance *Attendance) error {
 			return err
 		}
 	}
-	return nil
+
+	err = audit(ctx, tx, actor, AuditActionUpdate, "attendance", attendance.ID, before, attendance)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// Delete a specific attendance record by ID.
-func (m AttendanceModel) Delete(id string) error {
+// Delete a specific attendance record by ID. It returns ErrAttendanceLocked
+// if the session is completed.
+func (m AttendanceModel) Delete(actor AuditActor, id string) error {
 	if id == "" {
 		return ErrRecordNotFound
 	}
-	query := `DELETE FROM attendance WHERE id = $1`
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, id)
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	rowsAffected, err := result.RowsAffected()
+	defer tx.Rollback()
+
+	var sessionID string
+	err = tx.QueryRowContext(ctx, `SELECT session_id FROM attendance WHERE id = $1`, id).Scan(&sessionID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	err = checkAttendanceOpen(ctx, tx, sessionID)
 	if err != nil {
 		return err
 	}
-	if rowsAffected == 0 {
+
+	n, err := deleteAttendance(ctx, tx, actor, "id = $1", id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
 		return ErrRecordNotFound
 	}
-	return nil
+	return tx.Commit()
+}
+
+// deleteAttendance deletes and audits the attendance records matching where,
+// a condition on $1, which is arg, and returns how many there were.
+func deleteAttendance(ctx context.Context, q queryer, actor AuditActor, where, arg string) (int, error) {
+	query := `
+        DELETE FROM attendance
+        WHERE ` + where + `
+        RETURNING ` + attendanceColumns
+
+	rows, err := q.QueryContext(ctx, query, arg)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	records := []*Attendance{}
+	for rows.Next() {
+		var attendance Attendance
+		if err := scanAttendance(rows, &attendance); err != nil {
+			return 0, err
+		}
+		records = append(records, &attendance)
+	}
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	for _, attendance := range records {
+		err = audit(ctx, q, actor, AuditActionDelete, "attendance", attendance.ID, attendance, nil)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(records), nil
 }
 
 func (m AttendanceModel) GetAll(officerID string, sessionID string, filters Filters) ([]*Attendance, Metadata, error) {
@@ -27,7 +27,7 @@ func setupAttendanceTestDB(t *testing.T) (*sql.DB, string, string) {
 	// Create dependency tables in order
 	db.Exec(`CREATE TABLE IF NOT EXISTS users (id UUID PRIMARY KEY DEFAULT gen_random_uuid(), email TEXT UNIQUE NOT NULL);`)
 	db.Exec(`CREATE TABLE IF NOT EXISTS courses (id UUID PRIMARY KEY DEFAULT gen_random_uuid(), title TEXT NOT NULL, category TEXT NOT NULL, default_credit_hours NUMERIC NOT NULL, created_by_user_id UUID NOT NULL REFERENCES users(id), version INTEGER NOT NULL DEFAULT 1);`)
-	db.Exec(`CREATE TABLE IF NOT EXISTS sessions (id UUID PRIMARY KEY DEFAULT gen_random_uuid(), course_id UUID NOT NULL REFERENCES courses(id), start_datetime TIMESTAMPTZ NOT NULL, end_datetime TIMESTAMPTZ NOT NULL, location_text TEXT NOT NULL, version INTEGER NOT NULL DEFAULT 1);`)
+	db.Exec(`CREATE TABLE IF NOT EXISTS sessions (id UUID PRIMARY KEY DEFAULT gen_random_uuid(), course_id UUID NOT NULL REFERENCES courses(id), start_datetime TIMESTAMPTZ NOT NULL, end_datetime TIMESTAMPTZ NOT NULL, location_text TEXT NOT NULL, status TEXT NOT NULL DEFAULT 'scheduled', version INTEGER NOT NULL DEFAULT 1);`)
 	db.Exec(`CREATE TABLE IF NOT EXISTS officers (id UUID PRIMARY KEY DEFAULT gen_random_uuid(), first_name TEXT NOT NULL, last_name TEXT NOT NULL, sex TEXT NOT NULL, rank_code TEXT NOT NULL, version INTEGER NOT NULL DEFAULT 1);`)
 
 	// Create the attendance table
@@ -45,6 +45,9 @@ func setupAttendanceTestDB(t *testing.T) (*sql.DB, string, string) {
 	_, err = db.Exec(createTableSQL)
 	require.NoError(t, err)
 
+	// Recording attendance checks the officer's enrollments for clashes.
+	db.Exec(`CREATE TABLE IF NOT EXISTS session_enrollments (id UUID PRIMARY KEY DEFAULT gen_random_uuid(), officer_id UUID NOT NULL, session_id UUID NOT NULL, status TEXT NOT NULL);`)
+
 	// Insert dummy data to get valid IDs
 	var userID, courseID, sessionID, officerID string
 	err = db.QueryRow(`INSERT INTO users (email) VALUES ('attendanceuser@example.com') ON CONFLICT (email) DO NOTHING RETURNING id;`).Scan(&userID)
@@ -61,6 +64,7 @@ func setupAttendanceTestDB(t *testing.T) (*sql.DB, string, string) {
 
 	// Cleanup in reverse order
 	t.Cleanup(func() {
+		db.Exec("DROP TABLE IF EXISTS session_enrollments;")
 		db.Exec("DROP TABLE IF EXISTS attendance;")
 		db.Exec("DROP TABLE IF EXISTS officers;")
 		db.Exec("DROP TABLE IF EXISTS sessions;")
@@ -69,6 +73,9 @@ func setupAttendanceTestDB(t *testing.T) (*sql.DB, string, string) {
 		db.Close()
 	})
 
+	// Registered after the cleanup above, so it runs first.
+	createAuditEventsTable(t, db)
+
 	return db, officerID, sessionID
 }
 
@@ -88,7 +95,7 @@ func TestAttendanceModel_Insert(t *testing.T) {
 
 	attendance := newTestAttendance(t, officerID, sessionID)
 
-	err := m.Insert(attendance)
+	err := m.Insert(AuditActor{}, attendance, false)
 	require.NoError(t, err)
 
 	// Check populated fields
@@ -109,7 +116,7 @@ func TestAttendanceModel_Get(t *testing.T) {
 	m := AttendanceModel{DB: db}
 
 	attendance := newTestAttendance(t, officerID, sessionID)
-	err := m.Insert(attendance)
+	err := m.Insert(AuditActor{}, attendance, false)
 	require.NoError(t, err)
 
 	// Test successful Get
@@ -134,14 +141,14 @@ func TestAttendanceModel_Update(t *testing.T) {
 	m := AttendanceModel{DB: db}
 
 	attendance := newTestAttendance(t, officerID, sessionID)
-	err := m.Insert(attendance)
+	err := m.Insert(AuditActor{}, attendance, false)
 	require.NoError(t, err)
 
 	// Update fields
 	attendance.Status = "excused"
 	attendance.CreditedHours = 0
 
-	err = m.Update(attendance)
+	err = m.Update(AuditActor{}, attendance)
 	require.NoError(t, err)
 	require.Equal(t, int32(2), attendance.Version)
 
@@ -154,7 +161,7 @@ func TestAttendanceModel_Update(t *testing.T) {
 
 	// Test edit conflict
 	attendance.Version = 1
-	err = m.Update(attendance)
+	err = m.Update(AuditActor{}, attendance)
 	require.ErrorIs(t, err, ErrEditConflict)
 }
 
@@ -163,11 +170,11 @@ func TestAttendanceModel_Delete(t *testing.T) {
 	m := AttendanceModel{DB: db}
 
 	attendance := newTestAttendance(t, officerID, sessionID)
-	err := m.Insert(attendance)
+	err := m.Insert(AuditActor{}, attendance, false)
 	require.NoError(t, err)
 
 	// Test successful delete
-	err = m.Delete(attendance.ID)
+	err = m.Delete(AuditActor{}, attendance.ID)
 	require.NoError(t, err)
 
 	// Verify it's gone
@@ -175,10 +182,37 @@ func TestAttendanceModel_Delete(t *testing.T) {
 	require.ErrorIs(t, err, ErrRecordNotFound)
 
 	// Test deleting non-existent record
-	err = m.Delete("f47ac10b-58cc-4372-a567-0e02b2c3d479")
+	err = m.Delete(AuditActor{}, "f47ac10b-58cc-4372-a567-0e02b2c3d479")
 	require.ErrorIs(t, err, ErrRecordNotFound)
 }
 
+func TestAttendanceModel_Locked(t *testing.T) {
+	db, officerID, sessionID := setupAttendanceTestDB(t)
+	m := AttendanceModel{DB: db}
+
+	attendance := newTestAttendance(t, officerID, sessionID)
+	err := m.Insert(AuditActor{}, attendance, false)
+	require.NoError(t, err)
+
+	_, err = db.Exec(`UPDATE sessions SET status = 'completed' WHERE id = $1`, sessionID)
+	require.NoError(t, err)
+
+	// Once the session is completed, its attendance can't change.
+	attendance.Status = "absent"
+	require.ErrorIs(t, m.Update(AuditActor{}, attendance), ErrAttendanceLocked)
+	require.ErrorIs(t, m.Delete(AuditActor{}, attendance.ID), ErrAttendanceLocked)
+
+	var otherID string
+	err = db.QueryRow(`INSERT INTO officers (first_name, last_name, sex, rank_code) VALUES ('Jane', 'Roe', 'female', 'CONSTABLE') RETURNING id`).Scan(&otherID)
+	require.NoError(t, err)
+	err = m.Insert(AuditActor{}, newTestAttendance(t, otherID, sessionID), false)
+	require.ErrorIs(t, err, ErrAttendanceLocked)
+
+	fetched, err := m.Get(attendance.ID)
+	require.NoError(t, err)
+	require.Equal(t, "attended", fetched.Status)
+}
+
 func TestAttendanceModel_GetAll(t *testing.T) {
 	db, officerID1, sessionID1 := setupAttendanceTestDB(t)
 	m := AttendanceModel{DB: db}
@@ -194,9 +228,12 @@ func TestAttendanceModel_GetAll(t *testing.T) {
 	att1 := Attendance{OfficerID: officerID1, SessionID: sessionID1, Status: "attended", CreditedHours: 8}
 	att2 := Attendance{OfficerID: officerID2, SessionID: sessionID1, Status: "attended", CreditedHours: 8}
 	att3 := Attendance{OfficerID: officerID1, SessionID: sessionID2, Status: "absent", CreditedHours: 0}
-	require.NoError(t, m.Insert(&att1))
-	require.NoError(t, m.Insert(&att2))
-	require.NoError(t, m.Insert(&att3))
+	err = m.Insert(AuditActor{}, &att1, false)
+	require.NoError(t, err)
+	err = m.Insert(AuditActor{}, &att2, false)
+	require.NoError(t, err)
+	err = m.Insert(AuditActor{}, &att3, false)
+	require.NoError(t, err)
 
 	safelist := []string{"id", "status", "-id", "-status"}
 	filters := Filters{Page: 1, PageSize: 20, Sort: "id", SortSafelist: safelist}
@@ -0,0 +1,244 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Audit actions, one for each kind of write a model makes, plus override for
+// a write that went ahead in spite of scheduling conflicts. An override
+// event's After holds the conflicts.
+const (
+	AuditActionInsert   = "insert"
+	AuditActionUpdate   = "update"
+	AuditActionDelete   = "delete"
+	AuditActionOverride = "override"
+)
+
+// AuditEntityTypes are the kinds of record whose changes are audited. Tokens,
+// background jobs and login failures are short-lived bookkeeping rather than
+// data anyone edits, so they are left out.
+var AuditEntityTypes = []string{
+	"officer",
+	"course",
+	"session",
+	"session_series",
+	"session_facilitator",
+	"session_enrollment",
+	"session_feedback",
+	"facilitator",
+	"attendance",
+	"region",
+	"formation",
+	"posting",
+	"rank",
+	"compliance_requirement",
+	"import_job",
+	"user",
+	"api_key",
+}
+
+// importEntityTypes maps an import type to the entity type of its rows.
+var importEntityTypes = map[string]string{
+	"officers":   "officer",
+	"courses":    "course",
+	"sessions":   "session",
+	"attendance": "attendance",
+}
+
+// AuditEvent records one change to one record: who made it, through which
+// request, and the record as JSON before and after. Before is null for an
+// insert and After is null for a delete.
+type AuditEvent struct {
+	ID          int64           `json:"id"`
+	ActorUserID *string         `json:"actor_user_id"`
+	APIKeyID    *string         `json:"api_key_id,omitempty"`
+	Action      string          `json:"action"`
+	EntityType  string          `json:"entity_type"`
+	EntityID    string          `json:"entity_id"`
+	Before      json.RawMessage `json:"before"`
+	After       json.RawMessage `json:"after"`
+	RequestID   *string         `json:"request_id"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// NewAuditEvent builds an event for a change, encoding before and after as
+// JSON the same way the API shows them. Either may be nil.
+func NewAuditEvent(action, entityType, entityID string, before, after interface{}) (*AuditEvent, error) {
+	event := &AuditEvent{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+	}
+
+	var err error
+	if before != nil {
+		event.Before, err = json.Marshal(before)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if after != nil {
+		event.After, err = json.Marshal(after)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return event, nil
+}
+
+// AuditActor is who made a change and through which request. Any of them may
+// be nil: a change made from the command line has no actor at all.
+type AuditActor struct {
+	UserID    *string
+	APIKeyID  *string
+	RequestID *string
+}
+
+// audit records a change in the audit log using q, which should be the
+// transaction making the change, so that the change is only committed along
+// with its audit event. before is nil for inserts and after is nil for deletes.
+func audit(ctx context.Context, q queryer, actor AuditActor, action, entityType, entityID string, before, after interface{}) error {
+	event, err := NewAuditEvent(action, entityType, entityID, before, after)
+	if err != nil {
+		return err
+	}
+	event.ActorUserID = actor.UserID
+	event.APIKeyID = actor.APIKeyID
+	event.RequestID = actor.RequestID
+	return insertAuditEvent(ctx, q, event)
+}
+
+// auditOverride records that a booking went ahead in spite of scheduling
+// conflicts, if there were any.
+func auditOverride(ctx context.Context, q queryer, actor AuditActor, entityType, entityID string, conflicts []ScheduleConflict) error {
+	if len(conflicts) == 0 {
+		return nil
+	}
+	return audit(ctx, q, actor, AuditActionOverride, entityType, entityID, nil, conflicts)
+}
+
+// AuditEventModel stores the audit log. Events are never updated or deleted.
+type AuditEventModel struct {
+	DB *sql.DB
+}
+
+func (m AuditEventModel) Insert(event *AuditEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return insertAuditEvent(ctx, m.DB, event)
+}
+
+// insertAuditEvent is shared with the import transaction, so that imported
+// rows are audited along with being inserted.
+func insertAuditEvent(ctx context.Context, q queryer, event *AuditEvent) error {
+	query := `
+        INSERT INTO audit_events (actor_user_id, api_key_id, action, entity_type, entity_id, before, after, request_id)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        RETURNING id, created_at`
+
+	args := []interface{}{
+		event.ActorUserID,
+		event.APIKeyID,
+		event.Action,
+		event.EntityType,
+		event.EntityID,
+		jsonbArg(event.Before),
+		jsonbArg(event.After),
+		event.RequestID,
+	}
+
+	return q.QueryRowContext(ctx, query, args...).Scan(&event.ID, &event.CreatedAt)
+}
+
+// jsonbArg passes JSON to a JSONB column, or NULL if there is none.
+func jsonbArg(js json.RawMessage) interface{} {
+	if js == nil {
+		return nil
+	}
+	return string(js)
+}
+
+// AuditEventFilter narrows down the audit log. Empty fields match everything.
+type AuditEventFilter struct {
+	ActorUserID string
+	Action      string
+	EntityType  string
+	EntityID    string
+	RequestID   string
+	From        *time.Time
+	To          *time.Time
+}
+
+// GetAll returns a page of audit events matching the filter.
+func (m AuditEventModel) GetAll(filter AuditEventFilter, filters Filters) ([]*AuditEvent, Metadata, error) {
+	query := fmt.Sprintf(`
+        SELECT count(*) OVER(), id, actor_user_id, api_key_id, action, entity_type, entity_id,
+               before, after, request_id, created_at
+        FROM audit_events
+        WHERE (actor_user_id::text = $1 OR $1 = '')
+        AND (action = $2 OR $2 = '')
+        AND (entity_type = $3 OR $3 = '')
+        AND (entity_id = $4 OR $4 = '')
+        AND (request_id = $5 OR $5 = '')
+        AND (created_at >= $6 OR $6::timestamptz IS NULL)
+        AND (created_at < $7 OR $7::timestamptz IS NULL)
+        ORDER BY %s %s, id DESC
+        LIMIT $8 OFFSET $9`, filters.sortColumn(), filters.sortDirection())
+
+	args := []interface{}{
+		filter.ActorUserID,
+		filter.Action,
+		filter.EntityType,
+		filter.EntityID,
+		filter.RequestID,
+		filter.From,
+		filter.To,
+		filters.limit(),
+		filters.offset(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := int64(0)
+	events := []*AuditEvent{}
+
+	for rows.Next() {
+		var event AuditEvent
+		err := rows.Scan(
+			&totalRecords,
+			&event.ID,
+			&event.ActorUserID,
+			&event.APIKeyID,
+			&event.Action,
+			&event.EntityType,
+			&event.EntityID,
+			(*[]byte)(&event.Before),
+			(*[]byte)(&event.After),
+			&event.RequestID,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		events = append(events, &event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return events, metadata, nil
+}
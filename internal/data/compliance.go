@@ -0,0 +1,503 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/amari03/test1/internal/validator"
+	"github.com/lib/pq"
+)
+
+// Requirement kinds.
+const (
+	RequirementKindCreditHours = "credit_hours"
+	RequirementKindCourse      = "course"
+)
+
+// Compliance statuses returned by EvaluateCompliance.
+const (
+	ComplianceCompliant    = "compliant"
+	ComplianceOnTrack      = "on_track"
+	ComplianceAtRisk       = "at_risk"
+	ComplianceNonCompliant = "non_compliant"
+)
+
+// ComplianceRequirement is an annual in-service training requirement. A nil
+// RankCode applies the requirement to every rank. A course requirement must
+// name a mandatory course.
+type ComplianceRequirement struct {
+	ID            string     `json:"id"`
+	Name          string     `json:"name"`
+	Kind          string     `json:"kind"`
+	RankCode      *string    `json:"rank_code,omitempty"`
+	Category      *string    `json:"category,omitempty"`
+	RequiredHours *float64   `json:"required_hours,omitempty"`
+	CourseID      *string    `json:"course_id,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty"`
+	Version       int32      `json:"version"`
+}
+
+type ComplianceRequirementModel struct {
+	DB *sql.DB
+}
+
+func ValidateComplianceRequirement(v *validator.Validator, req *ComplianceRequirement) {
+	v.Check(req.Name != "", "name", "must be provided")
+	v.Check(len(req.Name) <= 255, "name", "must not exceed 255 bytes")
+	v.Check(validator.In(req.Kind, RequirementKindCreditHours, RequirementKindCourse), "kind", "must be credit_hours or course")
+
+	switch req.Kind {
+	case RequirementKindCreditHours:
+		v.Check(req.RequiredHours != nil, "required_hours", "must be provided")
+		v.Check(req.RequiredHours == nil || *req.RequiredHours > 0, "required_hours", "must be greater than zero")
+		v.Check(req.RequiredHours == nil || *req.RequiredHours < 10000, "required_hours", "must be less than 10,000")
+		v.Check(req.Category == nil || validator.In(*req.Category, "mandatory", "elective", "instructor"), "category", "invalid category type")
+		v.Check(req.CourseID == nil, "course_id", "must not be set for a credit_hours requirement")
+	case RequirementKindCourse:
+		v.Check(req.CourseID != nil, "course_id", "must be provided")
+		v.Check(req.RequiredHours == nil, "required_hours", "must not be set for a course requirement")
+		v.Check(req.Category == nil, "category", "must not be set for a course requirement")
+	}
+}
+
+// Insert a new compliance requirement.
+func (m ComplianceRequirementModel) Insert(actor AuditActor, req *ComplianceRequirement) error {
+	query := `
+        INSERT INTO compliance_requirements (name, kind, rank_code, category, required_hours, course_id)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id, created_at, version`
+
+	args := []interface{}{req.Name, req.Kind, req.RankCode, req.Category, req.RequiredHours, req.CourseID}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&req.ID, &req.CreatedAt, &req.Version)
+	if err != nil {
+		return err
+	}
+
+	err = audit(ctx, tx, actor, AuditActionInsert, "compliance_requirement", req.ID, nil, req)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const complianceRequirementColumns = `id, name, kind, rank_code, category, required_hours, course_id,
+               created_at, updated_at, version`
+
+func scanComplianceRequirement(row interface{ Scan(...interface{}) error }, req *ComplianceRequirement) error {
+	return row.Scan(
+		&req.ID,
+		&req.Name,
+		&req.Kind,
+		&req.RankCode,
+		&req.Category,
+		&req.RequiredHours,
+		&req.CourseID,
+		&req.CreatedAt,
+		&req.UpdatedAt,
+		&req.Version,
+	)
+}
+
+// Get a specific compliance requirement by ID.
+func (m ComplianceRequirementModel) Get(id string) (*ComplianceRequirement, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return getComplianceRequirement(ctx, m.DB, id)
+}
+
+// getComplianceRequirement fetches a compliance requirement using q, which may
+// be a transaction.
+func getComplianceRequirement(ctx context.Context, q queryer, id string) (*ComplianceRequirement, error) {
+	query := `
+        SELECT ` + complianceRequirementColumns + `
+        FROM compliance_requirements
+        WHERE id = $1`
+
+	var req ComplianceRequirement
+	err := scanComplianceRequirement(q.QueryRowContext(ctx, query, id), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &req, nil
+}
+
+// Update a specific compliance requirement.
+func (m ComplianceRequirementModel) Update(actor AuditActor, req *ComplianceRequirement) error {
+	query := `
+        UPDATE compliance_requirements
+        SET name = $1, kind = $2, rank_code = $3, category = $4, required_hours = $5, course_id = $6,
+            updated_at = NOW(), version = version + 1
+        WHERE id = $7 AND version = $8
+        RETURNING updated_at, version`
+
+	args := []interface{}{req.Name, req.Kind, req.RankCode, req.Category, req.RequiredHours, req.CourseID, req.ID, req.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getComplianceRequirement(ctx, tx, req.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRecordNotFound):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&req.UpdatedAt, &req.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	err = audit(ctx, tx, actor, AuditActionUpdate, "compliance_requirement", req.ID, before, req)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete a specific compliance requirement.
+func (m ComplianceRequirementModel) Delete(actor AuditActor, id string) error {
+	if id == "" {
+		return ErrRecordNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	n, err := deleteComplianceRequirements(ctx, tx, actor, "id = $1", id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrRecordNotFound
+	}
+	return tx.Commit()
+}
+
+// deleteComplianceRequirements deletes and audits the requirements matching
+// where, a condition on $1, which is arg, and returns how many there were.
+// Deleting a rank or course uses it to remove their requirements before the
+// database would cascade, so that each one is audited.
+func deleteComplianceRequirements(ctx context.Context, q queryer, actor AuditActor, where, arg string) (int, error) {
+	query := `
+        DELETE FROM compliance_requirements
+        WHERE ` + where + `
+        RETURNING ` + complianceRequirementColumns
+
+	rows, err := q.QueryContext(ctx, query, arg)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	reqs := []*ComplianceRequirement{}
+	for rows.Next() {
+		var req ComplianceRequirement
+		err := scanComplianceRequirement(rows, &req)
+		if err != nil {
+			return 0, err
+		}
+		reqs = append(reqs, &req)
+	}
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	for _, req := range reqs {
+		err = audit(ctx, q, actor, AuditActionDelete, "compliance_requirement", req.ID, req, nil)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(reqs), nil
+}
+
+// GetAll returns a paginated list of compliance requirements, filterable by
+// kind and rank. Filtering by rank also returns requirements for every rank.
+func (m ComplianceRequirementModel) GetAll(kind, rankCode string, filters Filters) ([]*ComplianceRequirement, Metadata, error) {
+	query := fmt.Sprintf(`
+        SELECT count(*) OVER(), id, name, kind, rank_code, category, required_hours, course_id,
+               created_at, updated_at, version
+        FROM compliance_requirements
+        WHERE (kind = $1 OR $1 = '')
+        AND (rank_code = $2 OR rank_code IS NULL OR $2 = '')
+        ORDER BY %s %s, id ASC
+        LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, kind, rankCode, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := int64(0)
+	reqs := []*ComplianceRequirement{}
+
+	for rows.Next() {
+		var req ComplianceRequirement
+		err := rows.Scan(
+			&totalRecords,
+			&req.ID,
+			&req.Name,
+			&req.Kind,
+			&req.RankCode,
+			&req.Category,
+			&req.RequiredHours,
+			&req.CourseID,
+			&req.CreatedAt,
+			&req.UpdatedAt,
+			&req.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		reqs = append(reqs, &req)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return reqs, metadata, nil
+}
+
+// List returns every compliance requirement, for use when evaluating officers.
+func (m ComplianceRequirementModel) List() ([]*ComplianceRequirement, error) {
+	query := `
+        SELECT id, name, kind, rank_code, category, required_hours, course_id, created_at, updated_at, version
+        FROM compliance_requirements
+        ORDER BY name ASC, id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reqs := []*ComplianceRequirement{}
+
+	for rows.Next() {
+		var req ComplianceRequirement
+		err := rows.Scan(
+			&req.ID,
+			&req.Name,
+			&req.Kind,
+			&req.RankCode,
+			&req.Category,
+			&req.RequiredHours,
+			&req.CourseID,
+			&req.CreatedAt,
+			&req.UpdatedAt,
+			&req.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		reqs = append(reqs, &req)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reqs, nil
+}
+
+// CourseProgress is an officer's credited hours for one course within a year,
+// and whether they attended at least one of its sessions.
+type CourseProgress struct {
+	CourseID      string
+	Category      string
+	CreditedHours float64
+	Attended      bool
+}
+
+// GetProgress returns the course progress for each of the given officers for
+// sessions starting in the given calendar year, keyed by officer ID.
+func (m ComplianceRequirementModel) GetProgress(officerIDs []string, year int) (map[string][]*CourseProgress, error) {
+	query := `
+        SELECT a.officer_id, c.id, c.category, SUM(a.credited_hours), bool_or(a.status = 'attended')
+        FROM attendance a
+        INNER JOIN sessions s ON s.id = a.session_id
+        INNER JOIN courses c ON c.id = s.course_id
+        WHERE a.officer_id = ANY($1)
+        AND s.start_datetime >= $2 AND s.start_datetime < $3
+        GROUP BY a.officer_id, c.id, c.category`
+
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(1, 0, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(officerIDs), from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	progress := make(map[string][]*CourseProgress)
+
+	for rows.Next() {
+		var officerID string
+		var p CourseProgress
+		err := rows.Scan(&officerID, &p.CourseID, &p.Category, &p.CreditedHours, &p.Attended)
+		if err != nil {
+			return nil, err
+		}
+		progress[officerID] = append(progress[officerID], &p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return progress, nil
+}
+
+// RequirementResult is how an officer stands against a single requirement.
+// HoursGap is only meaningful for credit_hours requirements.
+type RequirementResult struct {
+	RequirementID string  `json:"requirement_id"`
+	Name          string  `json:"name"`
+	Kind          string  `json:"kind"`
+	Category      *string `json:"category,omitempty"`
+	RequiredHours float64 `json:"required_hours,omitempty"`
+	CreditedHours float64 `json:"credited_hours,omitempty"`
+	HoursGap      float64 `json:"hours_gap,omitempty"`
+	CourseID      *string `json:"course_id,omitempty"`
+	Met           bool    `json:"met"`
+}
+
+// OfficerCompliance is the result of evaluating an officer against every
+// requirement that applies to their rank. HoursGap is the largest shortfall
+// across the credit_hours requirements, i.e. the fewest extra hours the
+// officer still needs.
+type OfficerCompliance struct {
+	Officer        *Officer             `json:"officer"`
+	Status         string               `json:"status"`
+	HoursGap       float64              `json:"hours_gap"`
+	MissingCourses []string             `json:"missing_courses"`
+	Requirements   []*RequirementResult `json:"requirements"`
+}
+
+// EvaluateCompliance checks an officer's progress for a year against the
+// requirements, with untilDeadline left until the year ends. An officer who
+// meets every requirement is compliant. One who does not is on track while the
+// deadline is further off than atRiskWindow, at risk once it is that close,
+// and non-compliant once it has passed.
+func EvaluateCompliance(officer *Officer, requirements []*ComplianceRequirement, progress []*CourseProgress, untilDeadline, atRiskWindow time.Duration) *OfficerCompliance {
+	unmetStatus := ComplianceOnTrack
+	switch {
+	case untilDeadline <= 0:
+		unmetStatus = ComplianceNonCompliant
+	case untilDeadline <= atRiskWindow:
+		unmetStatus = ComplianceAtRisk
+	}
+
+	result := &OfficerCompliance{
+		Officer:        officer,
+		Status:         ComplianceCompliant,
+		MissingCourses: []string{},
+		Requirements:   []*RequirementResult{},
+	}
+
+	for _, req := range requirements {
+		if req.RankCode != nil && *req.RankCode != officer.RankCode {
+			continue
+		}
+
+		r := &RequirementResult{
+			RequirementID: req.ID,
+			Name:          req.Name,
+			Kind:          req.Kind,
+			Category:      req.Category,
+			CourseID:      req.CourseID,
+		}
+
+		switch req.Kind {
+		case RequirementKindCreditHours:
+			r.RequiredHours = *req.RequiredHours
+			for _, p := range progress {
+				if req.Category == nil || *req.Category == p.Category {
+					r.CreditedHours += p.CreditedHours
+				}
+			}
+			r.CreditedHours = roundHours(r.CreditedHours)
+			r.HoursGap = roundHours(math.Max(r.RequiredHours-r.CreditedHours, 0))
+			r.Met = r.HoursGap == 0
+			result.HoursGap = math.Max(result.HoursGap, r.HoursGap)
+		case RequirementKindCourse:
+			for _, p := range progress {
+				if p.CourseID == *req.CourseID && p.Attended {
+					r.Met = true
+				}
+			}
+			if !r.Met {
+				result.MissingCourses = append(result.MissingCourses, *req.CourseID)
+			}
+		}
+
+		if !r.Met {
+			result.Status = unmetStatus
+		}
+
+		result.Requirements = append(result.Requirements, r)
+	}
+
+	return result
+}
+
+// roundHours rounds to the single decimal place that credit hours are stored
+// with, hiding floating point noise from summing them.
+func roundHours(hours float64) float64 {
+	return math.Round(hours*10) / 10
+}
@@ -35,7 +35,30 @@ func ValidateCourse(v *validator.Validator, course *Course) {
 }
 
 // Insert a new course record into the database.
-func (m CourseModel) Insert(course *Course) error {
+func (m CourseModel) Insert(actor AuditActor, course *Course) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = insertCourse(ctx, tx, course)
+	if err != nil {
+		return err
+	}
+
+	err = audit(ctx, tx, actor, AuditActionInsert, "course", course.ID, nil, course)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertCourse inserts a course using q, which may be a transaction.
+func insertCourse(ctx context.Context, q queryer, course *Course) error {
 	query := `
         INSERT INTO courses (title, category, default_credit_hours, description, created_by_user_id)
         VALUES ($1, $2, $3, $4, $5)
@@ -49,26 +72,14 @@ func (m CourseModel) Insert(course *Course) error {
 		course.CreatedByUserID,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(&course.ID, &course.CreatedAt, &course.Version)
+	return q.QueryRowContext(ctx, query, args...).Scan(&course.ID, &course.CreatedAt, &course.Version)
 }
 
+const courseColumns = `id, title, category, default_credit_hours, description, created_by_user_id,
+               created_at, updated_at, version`
 
-// Get a specific course by ID.
-func (m CourseModel) Get(id string) (*Course, error) {
-	query := `
-        SELECT id, title, category, default_credit_hours, description, created_by_user_id,
-               created_at, updated_at, version
-        FROM courses
-        WHERE id = $1`
-
-	var course Course
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+func scanCourse(row interface{ Scan(...interface{}) error }, course *Course) error {
+	return row.Scan(
 		&course.ID,
 		&course.Title,
 		&course.Category,
@@ -79,7 +90,25 @@ func (m CourseModel) Get(id string) (*Course, error) {
 		&course.UpdatedAt,
 		&course.Version,
 	)
+}
+
+// Get a specific course by ID.
+func (m CourseModel) Get(id string) (*Course, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return getCourse(ctx, m.DB, id)
+}
+
+// getCourse fetches a course using q, which may be a transaction.
+func getCourse(ctx context.Context, q queryer, id string) (*Course, error) {
+	query := `
+        SELECT ` + courseColumns + `
+        FROM courses
+        WHERE id = $1`
 
+	var course Course
+	err := scanCourse(q.QueryRowContext(ctx, query, id), &course)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -92,7 +121,7 @@ func (m CourseModel) Get(id string) (*Course, error) {
 }
 
 // Update a specific course record.
-func (m CourseModel) Update(course *Course) error {
+func (m CourseModel) Update(actor AuditActor, course *Course) error {
 	query := `
         UPDATE courses
         SET title = $1, category = $2, default_credit_hours = $3, description = $4,
@@ -112,7 +141,23 @@ func (m CourseModel) Update(course *Course) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&course.UpdatedAt, &course.Version)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getCourse(ctx, tx, course.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRecordNotFound):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&course.UpdatedAt, &course.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -121,35 +166,99 @@ func (m CourseModel) Update(course *Course) error {
 			return err
 		}
 	}
-	return nil
+
+	err = audit(ctx, tx, actor, AuditActionUpdate, "course", course.ID, before, course)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// Delete a specific course by ID.
-func (m CourseModel) Delete(id string) error {
+// Delete a specific course by ID, along with its sessions, series and
+// compliance requirements. Those are deleted one by one rather than left to
+// cascade, so that each of them is audited too. A course with any attendance
+// recorded against its sessions returns ErrRecordInUse, so that officers'
+// training history is kept.
+func (m CourseModel) Delete(actor AuditActor, id string) error {
 	if id == "" {
 		return ErrRecordNotFound
 	}
 	query := `
         DELETE FROM courses
-        WHERE id = $1`
+        WHERE id = $1
+        RETURNING ` + courseColumns
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, id)
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	// The sessions are locked so that no attendance can be recorded for them
+	// once they have been checked.
+	rows, err := tx.QueryContext(ctx, `
+        SELECT EXISTS (SELECT 1 FROM attendance a WHERE a.session_id = s.id)
+        FROM sessions s
+        WHERE s.course_id = $1
+        FOR UPDATE OF s`, id)
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
+
+	hasAttendance := false
+	for rows.Next() {
+		var sessionHasAttendance bool
+		err := rows.Scan(&sessionHasAttendance)
+		if err != nil {
+			return err
+		}
+		hasAttendance = hasAttendance || sessionHasAttendance
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
 
-	if rowsAffected == 0 {
-		return ErrRecordNotFound
+	if hasAttendance {
+		return ErrRecordInUse
+	}
+
+	_, err = deleteComplianceRequirements(ctx, tx, actor, "course_id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	_, err = deleteSessions(ctx, tx, actor, "course_id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	_, err = deleteSeries(ctx, tx, actor, "course_id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	var course Course
+	err = scanCourse(tx.QueryRowContext(ctx, query, id), &course)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	err = audit(ctx, tx, actor, AuditActionDelete, "course", course.ID, &course, nil)
+	if err != nil {
+		return err
 	}
-	return nil
+	return tx.Commit()
 }
 
 // GetAll returns a slice of all courses, with filtering.
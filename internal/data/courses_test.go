@@ -71,6 +71,9 @@ func setupCoursesTestDB(t *testing.T) (*sql.DB, string) {
 		db.Close()
 	})
 
+	// Registered after the cleanup above, so it runs first.
+	createAuditEventsTable(t, db)
+
 	return db, dummyUserID
 }
 
@@ -91,7 +94,7 @@ func TestCourseModel_Insert(t *testing.T) {
 
 	course := newTestCourse(t, userID)
 
-	err := m.Insert(course)
+	err := m.Insert(AuditActor{}, course)
 	require.NoError(t, err)
 
 	// Check that the database populated the ID, CreatedAt, and Version fields.
@@ -113,7 +116,7 @@ func TestCourseModel_Get(t *testing.T) {
 
 	// First, insert a record to test Get.
 	course := newTestCourse(t, userID)
-	err := m.Insert(course)
+	err := m.Insert(AuditActor{}, course)
 	require.NoError(t, err)
 
 	// Test successful Get.
@@ -142,14 +145,14 @@ func TestCourseModel_Update(t *testing.T) {
 
 	// Insert a record to test Update.
 	course := newTestCourse(t, userID)
-	err := m.Insert(course)
+	err := m.Insert(AuditActor{}, course)
 	require.NoError(t, err)
 
 	// Update some fields.
 	course.Title = "Advanced Defensive Driving"
 	course.Category = "elective"
 
-	err = m.Update(course)
+	err = m.Update(AuditActor{}, course)
 	require.NoError(t, err)
 
 	// Check that the version incremented and UpdatedAt is set.
@@ -167,7 +170,7 @@ func TestCourseModel_Update(t *testing.T) {
 	// Test for edit conflict (optimistic locking).
 	// Try to update again with the old version number (version 1).
 	course.Version = 1
-	err = m.Update(course)
+	err = m.Update(AuditActor{}, course)
 	require.Error(t, err)
 	require.True(t, errors.Is(err, ErrEditConflict))
 }
@@ -176,14 +179,33 @@ func TestCourseModel_Delete(t *testing.T) {
 	db, userID := setupCoursesTestDB(t)
 	m := CourseModel{DB: db}
 
+	// Deleting a course deletes everything that belongs to it.
+	createComplianceRequirementsTable(t, db)
+	createSessionsTable(t, db)
+	createSessionSeriesTable(t, db)
+	createSessionRecordTables(t, db)
+
 	// Insert a record to test Delete.
 	course := newTestCourse(t, userID)
-	err := m.Insert(course)
+	err := m.Insert(AuditActor{}, course)
+	require.NoError(t, err)
+
+	var sessionID string
+	err = db.QueryRow(`INSERT INTO sessions (course_id, start_datetime, end_datetime, location_text) VALUES ($1, NOW(), NOW() + INTERVAL '1 hour', 'Room 1') RETURNING id`, course.ID).Scan(&sessionID)
+	require.NoError(t, err)
+
+	// A course whose sessions have attendance can't be deleted.
+	_, err = db.Exec(`INSERT INTO attendance (officer_id, session_id) VALUES (gen_random_uuid(), $1)`, sessionID)
+	require.NoError(t, err)
+	err = m.Delete(AuditActor{}, course.ID)
+	require.ErrorIs(t, err, ErrRecordInUse)
+	_, err = db.Exec(`DELETE FROM attendance WHERE session_id = $1`, sessionID)
 	require.NoError(t, err)
 
-	// Test successful deletion.
-	err = m.Delete(course.ID)
+	// Test successful deletion, which audits the course's session as well.
+	err = m.Delete(AuditActor{}, course.ID)
 	require.NoError(t, err)
+	require.Len(t, auditEvents(t, db, AuditEventFilter{Action: AuditActionDelete, EntityType: "session", EntityID: sessionID}), 1)
 
 	// Verify it's gone.
 	_, err = m.Get(course.ID)
@@ -192,7 +214,7 @@ func TestCourseModel_Delete(t *testing.T) {
 
 	// Test deleting a non-existent record.
 	nonExistentID := "f47ac10b-58cc-4372-a567-0e02b2c3d479"
-	err = m.Delete(nonExistentID)
+	err = m.Delete(AuditActor{}, nonExistentID)
 	require.Error(t, err)
 	require.True(t, errors.Is(err, ErrRecordNotFound))
 }
@@ -205,9 +227,9 @@ func TestCourseModel_GetAll(t *testing.T) {
 	course1 := Course{Title: "Firearms Safety", Category: "mandatory", DefaultCreditHours: 16, CreatedByUserID: userID}
 	course2 := Course{Title: "Community Policing", Category: "elective", DefaultCreditHours: 8, CreatedByUserID: userID}
 	course3 := Course{Title: "Advanced First Aid", Category: "mandatory", DefaultCreditHours: 24, CreatedByUserID: userID}
-	require.NoError(t, m.Insert(&course1))
-	require.NoError(t, m.Insert(&course2))
-	require.NoError(t, m.Insert(&course3))
+	require.NoError(t, m.Insert(AuditActor{}, &course1))
+	require.NoError(t, m.Insert(AuditActor{}, &course2))
+	require.NoError(t, m.Insert(AuditActor{}, &course3))
 
 	// Define a standard filter safelist.
 	safelist := []string{"id", "title", "category", "-id", "-title", "-category"}
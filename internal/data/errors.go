@@ -1,9 +1,32 @@
 package data
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
 
 var (
-	ErrRecordNotFound = errors.New("record not found")
-	ErrEditConflict   = errors.New("edit conflict")
-	ErrDuplicateEmail = errors.New("duplicate email")
-)
\ No newline at end of file
+	ErrRecordNotFound  = errors.New("record not found")
+	ErrEditConflict    = errors.New("edit conflict")
+	ErrDuplicateEmail  = errors.New("duplicate email")
+	ErrDuplicateRecord = errors.New("duplicate record")
+	ErrRecordInUse     = errors.New("record in use")
+	ErrTokenReused     = errors.New("token reused")
+
+	ErrInvalidTransition = errors.New("invalid status transition")
+	ErrAttendanceLocked  = errors.New("attendance locked")
+	ErrEnrollmentClosed  = errors.New("enrollment closed")
+)
+
+// PostgreSQL error codes we translate into our own errors.
+const (
+	pqForeignKeyViolation = "23503"
+	pqUniqueViolation     = "23505"
+)
+
+// isPQError reports whether err is a PostgreSQL error with the given code.
+func isPQError(err error, code string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && string(pqErr.Code) == code
+}
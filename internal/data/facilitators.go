@@ -14,6 +14,7 @@ type Facilitator struct {
     FirstName string  `json:"first_name"`
     LastName  string  `json:"last_name"`
     Notes     *string `json:"notes,omitempty"`
+    Email     *string `json:"email,omitempty"`
     Version   int32   `json:"version"`
 }
 
@@ -24,40 +25,71 @@ type FacilitatorModel struct {
 func ValidateFacilitator(v *validator.Validator, facilitator *Facilitator) {
     v.Check(facilitator.FirstName != "", "first_name", "must be provided")
     v.Check(facilitator.LastName != "", "last_name", "must be provided")
+    // The email address is only used to tell the facilitator about their
+    // sessions.
+    if facilitator.Email != nil {
+        v.Check(validator.Matches(*facilitator.Email, validator.EmailRX), "email", "must be a valid email address")
+    }
 }
 
-func (m FacilitatorModel) Insert(facilitator *Facilitator) error {
+func (m FacilitatorModel) Insert(actor AuditActor, facilitator *Facilitator) error {
 	query := `
-        INSERT INTO facilitators (first_name, last_name, notes)
-        VALUES ($1, $2, $3)
+        INSERT INTO facilitators (first_name, last_name, notes, email)
+        VALUES ($1, $2, $3, $4)
         RETURNING id, version`
 
-	args := []interface{}{facilitator.FirstName, facilitator.LastName, facilitator.Notes}
+	args := []interface{}{facilitator.FirstName, facilitator.LastName, facilitator.Notes, facilitator.Email}
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(&facilitator.ID, &facilitator.Version)
-}
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-// Get a specific facilitator by ID.
-func (m FacilitatorModel) Get(id string) (*Facilitator, error) {
-	query := `
-        SELECT id, first_name, last_name, notes, version
-        FROM facilitators
-        WHERE id = $1`
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&facilitator.ID, &facilitator.Version)
+	if err != nil {
+		return err
+	}
 
-	var facilitator Facilitator
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+	err = audit(ctx, tx, actor, AuditActionInsert, "facilitator", facilitator.ID, nil, facilitator)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
 
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+const facilitatorColumns = `id, first_name, last_name, notes, email, version`
+
+func scanFacilitator(row interface{ Scan(...interface{}) error }, facilitator *Facilitator) error {
+	return row.Scan(
 		&facilitator.ID,
 		&facilitator.FirstName,
 		&facilitator.LastName,
 		&facilitator.Notes,
+		&facilitator.Email,
 		&facilitator.Version,
 	)
+}
+
+// Get a specific facilitator by ID.
+func (m FacilitatorModel) Get(id string) (*Facilitator, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return getFacilitator(ctx, m.DB, id)
+}
+
+// getFacilitator fetches a facilitator using q, which may be a transaction.
+func getFacilitator(ctx context.Context, q queryer, id string) (*Facilitator, error) {
+	query := `
+        SELECT ` + facilitatorColumns + `
+        FROM facilitators
+        WHERE id = $1`
 
+	var facilitator Facilitator
+	err := scanFacilitator(q.QueryRowContext(ctx, query, id), &facilitator)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -70,17 +102,18 @@ func (m FacilitatorModel) Get(id string) (*Facilitator, error) {
 }
 
 // Update a specific facilitator by ID.
-func (m FacilitatorModel) Update(facilitator *Facilitator) error {
+func (m FacilitatorModel) Update(actor AuditActor, facilitator *Facilitator) error {
 	query := `
         UPDATE facilitators
-        SET first_name = $1, last_name = $2, notes = $3, version = version + 1
-        WHERE id = $4 AND version = $5
+        SET first_name = $1, last_name = $2, notes = $3, email = $4, version = version + 1
+        WHERE id = $5 AND version = $6
         RETURNING version`
 
 	args := []interface{}{
 		facilitator.FirstName,
 		facilitator.LastName,
 		facilitator.Notes,
+		facilitator.Email,
 		facilitator.ID,
 		facilitator.Version,
 	}
@@ -88,7 +121,23 @@ func (m FacilitatorModel) Update(facilitator *Facilitator) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&facilitator.Version)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getFacilitator(ctx, tx, facilitator.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRecordNotFound):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&facilitator.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -97,41 +146,66 @@ func (m FacilitatorModel) Update(facilitator *Facilitator) error {
 			return err
 		}
 	}
-	return nil
+
+	err = audit(ctx, tx, actor, AuditActionUpdate, "facilitator", facilitator.ID, before, facilitator)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// Delete a specific facilitator by ID.
-func (m FacilitatorModel) Delete(id string) error {
+// Delete a specific facilitator by ID, along with their session assignments
+// and the feedback about them, which are audited as they go.
+func (m FacilitatorModel) Delete(actor AuditActor, id string) error {
 	if id == "" {
 		return ErrRecordNotFound
 	}
 	query := `
         DELETE FROM facilitators
-        WHERE id = $1`
+        WHERE id = $1
+        RETURNING ` + facilitatorColumns
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, id)
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	_, err = deleteSessionFacilitators(ctx, tx, actor, "facilitator_id = $1", id)
 	if err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return ErrRecordNotFound
+	_, err = deleteSessionFeedback(ctx, tx, actor, "facilitator_id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	var facilitator Facilitator
+	err = scanFacilitator(tx.QueryRowContext(ctx, query, id), &facilitator)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	err = audit(ctx, tx, actor, AuditActionDelete, "facilitator", facilitator.ID, &facilitator, nil)
+	if err != nil {
+		return err
 	}
-	return nil
+	return tx.Commit()
 }
 
 // GetAll returns a slice of all facilitators.
 func (m FacilitatorModel) GetAll(firstName string, lastName string, filters Filters) ([]*Facilitator, Metadata, error) {
 	query := fmt.Sprintf(`
-        SELECT count(*) OVER(), id, first_name, last_name, notes, version
+        SELECT count(*) OVER(), id, first_name, last_name, notes, email, version
         FROM facilitators
         WHERE (to_tsvector('simple', first_name) @@ plainto_tsquery('simple', $1) OR $1 = '')
         AND (to_tsvector('simple', last_name) @@ plainto_tsquery('simple', $2) OR $2 = '')
@@ -159,6 +233,7 @@ func (m FacilitatorModel) GetAll(firstName string, lastName string, filters Filt
 			&facilitator.FirstName,
 			&facilitator.LastName,
 			&facilitator.Notes,
+			&facilitator.Email,
 			&facilitator.Version,
 		)
 		if err != nil {
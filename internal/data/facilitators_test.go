@@ -34,6 +34,7 @@ func setupFacilitatorsTestDB(t *testing.T) *sql.DB {
         first_name TEXT NOT NULL,
         last_name TEXT NOT NULL,
         notes TEXT,
+        email TEXT,
         version INTEGER NOT NULL DEFAULT 1
     );`
 	_, err = db.Exec(createTableSQL)
@@ -46,6 +47,9 @@ func setupFacilitatorsTestDB(t *testing.T) *sql.DB {
 		db.Close()
 	})
 
+	// Registered after the cleanup above, so it runs first.
+	createAuditEventsTable(t, db)
+
 	return db
 }
 
@@ -65,7 +69,7 @@ func TestFacilitatorModel_Insert(t *testing.T) {
 
 	facilitator := newTestFacilitator(t)
 
-	err := m.Insert(facilitator)
+	err := m.Insert(AuditActor{}, facilitator)
 	require.NoError(t, err)
 
 	// Check that the database populated the ID and Version fields.
@@ -87,7 +91,7 @@ func TestFacilitatorModel_Get(t *testing.T) {
 
 	// First, insert a record to test Get.
 	facilitator := newTestFacilitator(t)
-	err := m.Insert(facilitator)
+	err := m.Insert(AuditActor{}, facilitator)
 	require.NoError(t, err)
 
 	// Test successful Get.
@@ -115,14 +119,14 @@ func TestFacilitatorModel_Update(t *testing.T) {
 
 	// Insert a record to test Update.
 	facilitator := newTestFacilitator(t)
-	err := m.Insert(facilitator)
+	err := m.Insert(AuditActor{}, facilitator)
 	require.NoError(t, err)
 
 	// Update some fields.
 	facilitator.FirstName = "John"
 	facilitator.Notes = nil // Test updating to a NULL value
 
-	err = m.Update(facilitator)
+	err = m.Update(AuditActor{}, facilitator)
 	require.NoError(t, err)
 
 	// Check that the version incremented.
@@ -138,7 +142,7 @@ func TestFacilitatorModel_Update(t *testing.T) {
 	// Test for edit conflict (optimistic locking).
 	// Try to update again with the old version number (version 1).
 	facilitator.Version = 1
-	err = m.Update(facilitator)
+	err = m.Update(AuditActor{}, facilitator)
 	require.Error(t, err)
 	require.True(t, errors.Is(err, ErrEditConflict))
 }
@@ -147,13 +151,16 @@ func TestFacilitatorModel_Delete(t *testing.T) {
 	db := setupFacilitatorsTestDB(t)
 	m := FacilitatorModel{DB: db}
 
+	// Deleting a facilitator deletes their records in sessions too.
+	createSessionRecordTables(t, db)
+
 	// Insert a record to test Delete.
 	facilitator := newTestFacilitator(t)
-	err := m.Insert(facilitator)
+	err := m.Insert(AuditActor{}, facilitator)
 	require.NoError(t, err)
 
 	// Test successful deletion.
-	err = m.Delete(facilitator.ID)
+	err = m.Delete(AuditActor{}, facilitator.ID)
 	require.NoError(t, err)
 
 	// Verify it's gone.
@@ -163,7 +170,7 @@ func TestFacilitatorModel_Delete(t *testing.T) {
 
 	// Test deleting a non-existent record.
 	nonExistentID := "f47ac10b-58cc-4372-a567-0e02b2c3d479"
-	err = m.Delete(nonExistentID)
+	err = m.Delete(AuditActor{}, nonExistentID)
 	require.Error(t, err)
 	require.True(t, errors.Is(err, ErrRecordNotFound))
 }
@@ -176,9 +183,9 @@ func TestFacilitatorModel_GetAll(t *testing.T) {
 	fac1 := Facilitator{FirstName: "Alice", LastName: "Williams"}
 	fac2 := Facilitator{FirstName: "Bob", LastName: "Johnson"}
 	fac3 := Facilitator{FirstName: "Charlie", LastName: "Williams"}
-	require.NoError(t, m.Insert(&fac1))
-	require.NoError(t, m.Insert(&fac2))
-	require.NoError(t, m.Insert(&fac3))
+	require.NoError(t, m.Insert(AuditActor{}, &fac1))
+	require.NoError(t, m.Insert(AuditActor{}, &fac2))
+	require.NoError(t, m.Insert(AuditActor{}, &fac3))
 
 	// Define a standard filter safelist.
 	safelist := []string{"id", "first_name", "last_name", "-id", "-first_name", "-last_name"}
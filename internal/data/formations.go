@@ -0,0 +1,236 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/amari03/test1/internal/validator"
+)
+
+type Formation struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	RegionID string `json:"region_id"`
+	Version  int32  `json:"version"`
+}
+
+type FormationModel struct {
+	DB *sql.DB
+}
+
+func ValidateFormation(v *validator.Validator, formation *Formation) {
+	v.Check(formation.ID != "", "id", "must be provided")
+	v.Check(len(formation.ID) <= 50, "id", "must not exceed 50 bytes")
+	v.Check(formation.Name != "", "name", "must be provided")
+	v.Check(len(formation.Name) <= 100, "name", "must not exceed 100 bytes")
+	v.Check(formation.RegionID != "", "region_id", "must be provided")
+}
+
+// Insert a new formation record. Formation IDs are chosen by the client.
+func (m FormationModel) Insert(actor AuditActor, formation *Formation) error {
+	query := `
+        INSERT INTO formations (id, name, region_id)
+        VALUES ($1, $2, $3)
+        RETURNING version`
+
+	args := []interface{}{formation.ID, formation.Name, formation.RegionID}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&formation.Version)
+	if err != nil {
+		if isPQError(err, pqUniqueViolation) {
+			return ErrDuplicateRecord
+		}
+		return err
+	}
+
+	err = audit(ctx, tx, actor, AuditActionInsert, "formation", formation.ID, nil, formation)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Get a specific formation by ID.
+func (m FormationModel) Get(id string) (*Formation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return getFormation(ctx, m.DB, id)
+}
+
+// getFormation fetches a formation using q, which may be a transaction.
+func getFormation(ctx context.Context, q queryer, id string) (*Formation, error) {
+	query := `
+        SELECT id, name, region_id, version
+        FROM formations
+        WHERE id = $1`
+
+	var formation Formation
+	err := q.QueryRowContext(ctx, query, id).Scan(
+		&formation.ID,
+		&formation.Name,
+		&formation.RegionID,
+		&formation.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &formation, nil
+}
+
+// Update a specific formation record. The ID itself cannot be changed.
+func (m FormationModel) Update(actor AuditActor, formation *Formation) error {
+	query := `
+        UPDATE formations
+        SET name = $1, region_id = $2, version = version + 1
+        WHERE id = $3 AND version = $4
+        RETURNING version`
+
+	args := []interface{}{
+		formation.Name,
+		formation.RegionID,
+		formation.ID,
+		formation.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getFormation(ctx, tx, formation.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRecordNotFound):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&formation.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	err = audit(ctx, tx, actor, AuditActionUpdate, "formation", formation.ID, before, formation)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete a specific formation by ID. Officers and sessions assigned to the
+// formation are unassigned by the database.
+func (m FormationModel) Delete(actor AuditActor, id string) error {
+	if id == "" {
+		return ErrRecordNotFound
+	}
+	query := `
+        DELETE FROM formations
+        WHERE id = $1
+        RETURNING id, name, region_id, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var formation Formation
+	err = tx.QueryRowContext(ctx, query, id).Scan(
+		&formation.ID,
+		&formation.Name,
+		&formation.RegionID,
+		&formation.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	err = audit(ctx, tx, actor, AuditActionDelete, "formation", formation.ID, &formation, nil)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetAll returns a paginated list of formations, filterable by name and region.
+func (m FormationModel) GetAll(name string, regionID string, filters Filters) ([]*Formation, Metadata, error) {
+	query := fmt.Sprintf(`
+        SELECT count(*) OVER(), id, name, region_id, version
+        FROM formations
+        WHERE (name ILIKE '%%' || $1 || '%%' OR $1 = '')
+        AND (region_id = $2 OR $2 = '')
+        ORDER BY %s %s, id ASC
+        LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{name, regionID, filters.limit(), filters.offset()}
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := int64(0)
+	formations := []*Formation{}
+
+	for rows.Next() {
+		var formation Formation
+		err := rows.Scan(
+			&totalRecords,
+			&formation.ID,
+			&formation.Name,
+			&formation.RegionID,
+			&formation.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		formations = append(formations, &formation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return formations, metadata, nil
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/amari03/test1/internal/validator"
+	"github.com/lib/pq"
 )
 
 type ImportJob struct {
@@ -15,6 +16,9 @@ type ImportJob struct {
 	Type            string     `json:"type"`
 	Status          string     `json:"status"`
 	ErrorMessage    *string    `json:"error_message,omitempty"` // ADDED
+	FilePath        string     `json:"-"`
+	DryRun          bool       `json:"dry_run"`
+	RowsImported    int        `json:"rows_imported"`
 	CreatedByUserID string     `json:"created_by_user_id"`
 	CreatedAt       time.Time  `json:"created_at"`
 	FinishedAt      *time.Time `json:"finished_at,omitempty"` // ADDED
@@ -27,49 +31,71 @@ type ImportJobModel struct {
 
 func ValidateImportJob(v *validator.Validator, job *ImportJob) {
 	v.Check(job.Type != "", "type", "must be provided")
-	// v.Check(validator.In(job.Type, "officers", "attendance", "courses"), "type", "must be 'officers', 'attendance', or 'courses'")
-    // You can add the check above if you know the types
+	v.Check(validator.In(job.Type, ImportTypes...), "type", "must be one of officers, courses, sessions or attendance")
 }
 
 // Insert a new import job record.
-func (m ImportJobModel) Insert(job *ImportJob) error {
+func (m ImportJobModel) Insert(actor AuditActor, job *ImportJob) error {
 	query := `
-        INSERT INTO import_jobs (type, status, created_by_user_id)
-        VALUES ($1, $2, $3)
-        RETURNING id, created_at, version` // REMOVED file_path
+        INSERT INTO import_jobs (type, status, file_path, dry_run, created_by_user_id)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, created_at, version`
 
 	// Default status to "pending"
-	job.Status = "pending"
+	job.Status = ImportStatusPending
 
-	args := []interface{}{job.Type, job.Status, job.CreatedByUserID} // REMOVED file_path
+	args := []interface{}{job.Type, job.Status, job.FilePath, job.DryRun, job.CreatedByUserID}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(&job.ID, &job.CreatedAt, &job.Version)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&job.ID, &job.CreatedAt, &job.Version)
+	if err != nil {
+		return err
+	}
+
+	err = audit(ctx, tx, actor, AuditActionInsert, "import_job", job.ID, nil, job)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // Get a specific import job by ID.
 func (m ImportJobModel) Get(id string) (*ImportJob, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return getImportJob(ctx, m.DB, id)
+}
+
+// getImportJob fetches an import job using q, which may be a transaction.
+func getImportJob(ctx context.Context, q queryer, id string) (*ImportJob, error) {
 	query := `
-        SELECT id, type, status, error_message, created_by_user_id,
+        SELECT id, type, status, error_message, file_path, dry_run, rows_imported, created_by_user_id,
                created_at, finished_at, version
         FROM import_jobs
-        WHERE id = $1` // UPDATED fields
+        WHERE id = $1`
 
 	var job ImportJob
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+	err := q.QueryRowContext(ctx, query, id).Scan(
 		&job.ID,
 		&job.Type,
 		&job.Status,
-		&job.ErrorMessage, // UPDATED
+		&job.ErrorMessage,
+		&job.FilePath,
+		&job.DryRun,
+		&job.RowsImported,
 		&job.CreatedByUserID,
 		&job.CreatedAt,
-		&job.FinishedAt, // UPDATED
+		&job.FinishedAt,
 		&job.Version,
 	)
 
@@ -87,7 +113,7 @@ func (m ImportJobModel) Get(id string) (*ImportJob, error) {
 // GetAll returns a paginated list of import jobs, filterable by type and status.
 func (m ImportJobModel) GetAll(jobType string, status string, filters Filters) ([]*ImportJob, Metadata, error) {
 	query := fmt.Sprintf(`
-        SELECT count(*) OVER(), id, type, status, error_message, created_by_user_id,
+        SELECT count(*) OVER(), id, type, status, error_message, file_path, dry_run, rows_imported, created_by_user_id,
                created_at, finished_at, version
         FROM import_jobs
         WHERE (LOWER(type) = LOWER($1) OR $1 = '')
@@ -117,6 +143,9 @@ func (m ImportJobModel) GetAll(jobType string, status string, filters Filters) (
 			&job.Type,
 			&job.Status,
 			&job.ErrorMessage, // UPDATED
+			&job.FilePath,
+			&job.DryRun,
+			&job.RowsImported,
 			&job.CreatedByUserID,
 			&job.CreatedAt,
 			&job.FinishedAt, // UPDATED
@@ -135,4 +164,232 @@ func (m ImportJobModel) GetAll(jobType string, status string, filters Filters) (
 	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
 
 	return jobs, metadata, nil
+}
+
+// Start moves a job to running. Jobs that are already running or have failed
+// can be started again, as the job queue retries them after server errors.
+// It returns ErrEditConflict if the job has already succeeded.
+func (m ImportJobModel) Start(job *ImportJob) error {
+	query := `
+        UPDATE import_jobs
+        SET status = $1, version = version + 1
+        WHERE id = $2 AND status <> $3
+        RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getImportJob(ctx, tx, job.ID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, query, ImportStatusRunning, job.ID, ImportStatusSucceeded).Scan(&job.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	job.Status = ImportStatusRunning
+
+	err = audit(ctx, tx, importActor(job), AuditActionUpdate, "import_job", job.ID, before, job)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Finish records the outcome of a running job along with any row errors,
+// replacing the errors from an earlier run. A nil errorMessage marks the job as
+// succeeded, anything else as failed.
+func (m ImportJobModel) Finish(job *ImportJob, rowsImported int, errorMessage *string, rowErrors []ImportRowError) error {
+	query := `
+        UPDATE import_jobs
+        SET status = $1, error_message = $2, rows_imported = $3, finished_at = NOW(), version = version + 1
+        WHERE id = $4
+        RETURNING finished_at, version`
+
+	status := ImportStatusSucceeded
+	if errorMessage != nil {
+		status = ImportStatusFailed
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getImportJob(ctx, tx, job.ID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, query, status, errorMessage, rowsImported, job.ID).Scan(&job.FinishedAt, &job.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	job.Status = status
+	job.ErrorMessage = errorMessage
+	job.RowsImported = rowsImported
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM import_job_errors WHERE import_job_id = $1`, job.ID)
+	if err != nil {
+		return err
+	}
+
+	if len(rowErrors) > 0 {
+		rowNumbers := make([]int64, len(rowErrors))
+		columns := make([]string, len(rowErrors))
+		messages := make([]string, len(rowErrors))
+		for i, rowError := range rowErrors {
+			rowNumbers[i] = int64(rowError.Row)
+			columns[i] = rowError.Column
+			messages[i] = rowError.Message
+		}
+
+		insertQuery := `
+            INSERT INTO import_job_errors (import_job_id, row_number, column_name, message)
+            SELECT $1, * FROM unnest($2::integer[], $3::text[], $4::text[])`
+
+		_, err = tx.ExecContext(ctx, insertQuery, job.ID, pq.Array(rowNumbers), pq.Array(columns), pq.Array(messages))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = audit(ctx, tx, importActor(job), AuditActionUpdate, "import_job", job.ID, before, job)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UnfinishedFilePaths returns the uploaded files of jobs that are still to
+// run: those pending or running whose queued job hasn't been dead-lettered.
+// Any other file in the import directory is no longer needed.
+func (m ImportJobModel) UnfinishedFilePaths() ([]string, error) {
+	query := `
+        SELECT i.file_path
+        FROM import_jobs i
+        WHERE i.status IN ($1, $2)
+        AND NOT EXISTS (
+            SELECT 1 FROM jobs j
+            WHERE j.kind = $3 AND j.status = $4 AND j.payload->>'import_job_id' = i.id::text
+        )`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, ImportStatusPending, ImportStatusRunning, JobKindImport, JobStatusDead)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	paths := []string{}
+	for rows.Next() {
+		var path string
+		err := rows.Scan(&path)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// GetErrors returns a paginated list of a job's row errors, in file order.
+func (m ImportJobModel) GetErrors(jobID string, filters Filters) ([]*ImportRowError, Metadata, error) {
+	query := `
+        SELECT count(*) OVER(), row_number, column_name, message
+        FROM import_job_errors
+        WHERE import_job_id = $1
+        ORDER BY row_number ASC, id ASC
+        LIMIT $2 OFFSET $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, jobID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := int64(0)
+	rowErrors := []*ImportRowError{}
+
+	for rows.Next() {
+		var rowError ImportRowError
+		err := rows.Scan(&totalRecords, &rowError.Row, &rowError.Column, &rowError.Message)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		rowErrors = append(rowErrors, &rowError)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return rowErrors, metadata, nil
+}
+
+// GetAllErrors returns every row error for a job, in file order, for
+// downloading as a report.
+func (m ImportJobModel) GetAllErrors(jobID string) ([]*ImportRowError, error) {
+	query := `
+        SELECT row_number, column_name, message
+        FROM import_job_errors
+        WHERE import_job_id = $1
+        ORDER BY row_number ASC, id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rowErrors := []*ImportRowError{}
+
+	for rows.Next() {
+		var rowError ImportRowError
+		err := rows.Scan(&rowError.Row, &rowError.Column, &rowError.Message)
+		if err != nil {
+			return nil, err
+		}
+		rowErrors = append(rowErrors, &rowError)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rowErrors, nil
 }
\ No newline at end of file
@@ -0,0 +1,427 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amari03/test1/internal/validator"
+	"github.com/lib/pq"
+)
+
+// ImportTypes are the kinds of file an import job can load.
+var ImportTypes = []string{"officers", "courses", "sessions", "attendance"}
+
+// Import job statuses. Jobs move from pending to running and then to either
+// succeeded or failed.
+const (
+	ImportStatusPending   = "pending"
+	ImportStatusRunning   = "running"
+	ImportStatusSucceeded = "succeeded"
+	ImportStatusFailed    = "failed"
+)
+
+// importTimeout bounds how long a single import transaction may run.
+const importTimeout = 5 * time.Minute
+
+// errFormationNotInRegion is returned when inserting an imported officer whose
+// formation belongs to a different region from the one given.
+var errFormationNotInRegion = errors.New("formation not in region")
+
+// importColumns lists the CSV columns accepted for each import type. Which of
+// them are required is left to the matching Validate* function.
+var importColumns = map[string][]string{
+	"officers":   {"regulation_number", "first_name", "last_name", "sex", "rank_code", "region_id", "formation_id", "posting_id", "email"},
+	"courses":    {"title", "category", "default_credit_hours", "description"},
+	"sessions":   {"course_id", "start_datetime", "end_datetime", "location_text"},
+	"attendance": {"officer_id", "session_id", "status", "credited_hours"},
+}
+
+// ImportRowError is a problem with a single row of an import file. Row is the
+// line in the file, with the header on line 1. Column is empty when the
+// problem is not tied to one column.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportError is returned by Run when the file is malformed, or rows in it
+// are invalid or could not be written. Nothing from the file is imported.
+type ImportError struct {
+	Rows []ImportRowError
+}
+
+func (e *ImportError) Error() string {
+	first := e.Rows[0]
+
+	msg := fmt.Sprintf("row %d: ", first.Row)
+	if first.Column != "" {
+		msg += first.Column + " "
+	}
+	msg += first.Message
+
+	if len(e.Rows) > 1 {
+		msg += fmt.Sprintf(" (and %d more errors)", len(e.Rows)-1)
+	}
+	return msg
+}
+
+// importRow is a parsed and validated row waiting to be inserted. insert
+// returns the new record's ID.
+type importRow struct {
+	line   int
+	record interface{}
+	insert func(ctx context.Context, q queryer) (string, error)
+}
+
+// Run loads the CSV in r into the table matching the job's type, in a single
+// transaction so that the file is imported completely or not at all. Every
+// valid row is inserted under its own savepoint so that database errors, such
+// as a reference to a missing officer, are collected for the whole file
+// rather than stopping at the first. If any row is at fault an *ImportError
+// lists them all and nothing is committed. Dry runs do the same checks and
+// then always roll back. It returns the number of rows imported, or for a dry
+// run the number that would have been.
+func (m ImportJobModel) Run(job *ImportJob, r io.Reader) (int, error) {
+	rows, rowErrors, err := parseImportFile(job.Type, job.CreatedByUserID, r)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), importTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for _, row := range rows {
+		_, err := tx.ExecContext(ctx, "SAVEPOINT import_row")
+		if err != nil {
+			return 0, err
+		}
+
+		id, err := row.insert(ctx, tx)
+		if err == nil {
+			err = auditImportRow(ctx, tx, job, id, row.record)
+		}
+		if err != nil {
+			var pqErr *pq.Error
+			switch {
+			case errors.As(err, &pqErr):
+				rowErrors = append(rowErrors, importDBError(job.Type, row.line, pqErr))
+			case errors.Is(err, ErrAttendanceLocked):
+				rowErrors = append(rowErrors, ImportRowError{Row: row.line, Column: "session_id", Message: "the session is completed, so its attendance can't change"})
+			case errors.Is(err, errFormationNotInRegion):
+				rowErrors = append(rowErrors, ImportRowError{Row: row.line, Column: "formation_id", Message: "must belong to the chosen region"})
+			default:
+				return 0, err
+			}
+
+			_, err = tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT import_row")
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, "RELEASE SAVEPOINT import_row")
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if len(rowErrors) > 0 {
+		sort.SliceStable(rowErrors, func(i, j int) bool { return rowErrors[i].Row < rowErrors[j].Row })
+		return 0, &ImportError{Rows: rowErrors}
+	}
+
+	if job.DryRun {
+		return len(rows), nil
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	return len(rows), nil
+}
+
+// auditImportRow records an imported row in the audit log, as a change made
+// by whoever uploaded the file.
+func auditImportRow(ctx context.Context, q queryer, job *ImportJob, id string, record interface{}) error {
+	return audit(ctx, q, importActor(job), AuditActionInsert, importEntityTypes[job.Type], id, nil, record)
+}
+
+// importActor is who the changes a job makes in the background are recorded
+// against: whoever uploaded its file.
+func importActor(job *ImportJob) AuditActor {
+	var actor AuditActor
+	if job.CreatedByUserID != "" {
+		actor.UserID = &job.CreatedByUserID
+	}
+	return actor
+}
+
+// parseImportFile reads a CSV file of the given type. The first row must be a
+// header naming the columns. Problems with the file's contents are returned as
+// row errors alongside the rows that parsed cleanly; the returned error is
+// only set for failures that are not the file's fault.
+func parseImportFile(jobType, createdByUserID string, r io.Reader) ([]importRow, []ImportRowError, error) {
+	allowed, ok := importColumns[jobType]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown import type %q", jobType)
+	}
+
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		switch {
+		case errors.Is(err, io.EOF):
+			return nil, []ImportRowError{{Row: 1, Message: "the file is empty"}}, nil
+		case errors.As(err, &parseErr):
+			return nil, []ImportRowError{{Row: parseErr.Line, Message: parseErr.Err.Error()}}, nil
+		default:
+			return nil, nil, err
+		}
+	}
+
+	for i, name := range header {
+		// Spreadsheet exports often start with a byte order mark.
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !slices.Contains(allowed, name) {
+			return nil, []ImportRowError{{Row: 1, Column: name, Message: "is not a known column"}}, nil
+		}
+		if slices.Contains(header[:i], name) {
+			return nil, []ImportRowError{{Row: 1, Column: name, Message: "appears more than once"}}, nil
+		}
+		header[i] = name
+	}
+
+	rows := []importRow{}
+	rowErrors := []ImportRowError{}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, nil, err
+			}
+			if errors.Is(parseErr.Err, csv.ErrFieldCount) {
+				rowErrors = append(rowErrors, ImportRowError{Row: parseErr.StartLine, Message: fmt.Sprintf("must have %d columns", len(header))})
+				continue
+			}
+			// Anything else, such as a stray quote, leaves the reader unable
+			// to find where the next row starts.
+			rowErrors = append(rowErrors, ImportRowError{Row: parseErr.Line, Message: parseErr.Err.Error()})
+			return nil, rowErrors, nil
+		}
+		line, _ := reader.FieldPos(0)
+
+		fields := make(map[string]string, len(header))
+		for i, name := range header {
+			fields[name] = strings.TrimSpace(record[i])
+		}
+
+		v := validator.New()
+		parsed, insert, err := parseImportRow(jobType, createdByUserID, fields, v)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !v.Valid() {
+			rowErrors = append(rowErrors, validatorRowErrors(line, v)...)
+			continue
+		}
+
+		rows = append(rows, importRow{line: line, record: parsed, insert: insert})
+	}
+
+	if len(rows) == 0 && len(rowErrors) == 0 {
+		rowErrors = append(rowErrors, ImportRowError{Row: 1, Message: "the file has no rows to import"})
+	}
+
+	return rows, rowErrors, nil
+}
+
+// parseImportRow builds the record described by a row and validates it with
+// the same Validate* function the API uses. It returns the record and a
+// function that inserts it.
+func parseImportRow(jobType, createdByUserID string, fields map[string]string, v *validator.Validator) (interface{}, func(ctx context.Context, q queryer) (string, error), error) {
+	switch jobType {
+	case "officers":
+		officer := &Officer{
+			RegulationNumber: optionalField(fields["regulation_number"]),
+			FirstName:        fields["first_name"],
+			LastName:         fields["last_name"],
+			Sex:              fields["sex"],
+			RankCode:         fields["rank_code"],
+			RegionID:         optionalField(fields["region_id"]),
+			FormationID:      optionalField(fields["formation_id"]),
+			PostingID:        optionalField(fields["posting_id"]),
+			Email:            optionalField(fields["email"]),
+		}
+		ValidateOfficer(v, officer)
+		return officer, func(ctx context.Context, q queryer) (string, error) {
+			// Missing regions and formations are left to the foreign keys.
+			if officer.RegionID != nil && officer.FormationID != nil {
+				var regionID string
+				err := q.QueryRowContext(ctx, `SELECT region_id FROM formations WHERE id = $1`, *officer.FormationID).Scan(&regionID)
+				switch {
+				case errors.Is(err, sql.ErrNoRows):
+				case err != nil:
+					return "", err
+				case regionID != *officer.RegionID:
+					return "", errFormationNotInRegion
+				}
+			}
+			err := insertOfficer(ctx, q, officer)
+			return officer.ID, err
+		}, nil
+
+	case "courses":
+		course := &Course{
+			Title:              fields["title"],
+			Category:           fields["category"],
+			DefaultCreditHours: floatField(v, fields, "default_credit_hours"),
+			Description:        fields["description"],
+			CreatedByUserID:    createdByUserID,
+		}
+		ValidateCourse(v, course)
+		return course, func(ctx context.Context, q queryer) (string, error) {
+			err := insertCourse(ctx, q, course)
+			return course.ID, err
+		}, nil
+
+	case "sessions":
+		session := &Session{
+			CourseID: fields["course_id"],
+			Start:    timeField(v, fields, "start_datetime"),
+			End:      timeField(v, fields, "end_datetime"),
+			Location: fields["location_text"],
+			Status:   SessionStatusScheduled,
+		}
+		ValidateSession(v, session)
+		return session, func(ctx context.Context, q queryer) (string, error) {
+			err := insertSession(ctx, q, session)
+			return session.ID, err
+		}, nil
+
+	case "attendance":
+		attendance := &Attendance{
+			OfficerID:     fields["officer_id"],
+			SessionID:     fields["session_id"],
+			Status:        fields["status"],
+			CreditedHours: floatField(v, fields, "credited_hours"),
+		}
+		ValidateAttendance(v, attendance)
+		defaultCredit := fields["credited_hours"] == ""
+		return attendance, func(ctx context.Context, q queryer) (string, error) {
+			// A blank credited_hours cell means the session's default credit.
+			// If the session doesn't exist, the insert reports it.
+			if defaultCredit {
+				hours, err := sessionDefaultCreditHours(ctx, q, attendance.SessionID)
+				if err != nil && !errors.Is(err, ErrRecordNotFound) {
+					return "", err
+				}
+				attendance.CreditedHours = hours
+			}
+			err := insertAttendance(ctx, q, attendance)
+			return attendance.ID, err
+		}, nil
+	}
+
+	return nil, nil, fmt.Errorf("unknown import type %q", jobType)
+}
+
+// optionalField returns nil for an empty cell.
+func optionalField(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// floatField parses a numeric cell, recording a validation error if it is not
+// a number. Empty cells are zero.
+func floatField(v *validator.Validator, fields map[string]string, key string) float64 {
+	if fields[key] == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(fields[key], 64)
+	if err != nil {
+		v.AddError(key, "must be a number")
+		return 0
+	}
+	return f
+}
+
+// timeField parses an RFC 3339 timestamp cell, recording a validation error if
+// it is malformed. Empty cells are the zero time.
+func timeField(v *validator.Validator, fields map[string]string, key string) time.Time {
+	if fields[key] == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, fields[key])
+	if err != nil {
+		v.AddError(key, "must be an RFC 3339 timestamp, e.g. 2025-01-31T09:00:00Z")
+		return time.Time{}
+	}
+	return t
+}
+
+// validatorRowErrors converts a validator's errors into row errors, ordered by
+// column so that results are stable.
+func validatorRowErrors(line int, v *validator.Validator) []ImportRowError {
+	columns := make([]string, 0, len(v.Errors))
+	for column := range v.Errors {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	rowErrors := make([]ImportRowError, len(columns))
+	for i, column := range columns {
+		rowErrors[i] = ImportRowError{Row: line, Column: column, Message: v.Errors[column]}
+	}
+	return rowErrors
+}
+
+// importDBError describes a database error raised while inserting a row. The
+// column is recovered from the constraint name where Postgres' default naming
+// makes that possible, e.g. officers_rank_code_fkey.
+func importDBError(jobType string, line int, pqErr *pq.Error) ImportRowError {
+	rowError := ImportRowError{Row: line, Column: pqErr.Column, Message: pqErr.Message}
+
+	var suffix string
+	switch string(pqErr.Code) {
+	case pqForeignKeyViolation:
+		suffix = "_fkey"
+		rowError.Message = "must reference an existing record"
+	case pqUniqueViolation:
+		suffix = "_key"
+		rowError.Message = "a matching record already exists"
+	default:
+		return rowError
+	}
+
+	column := strings.TrimSuffix(strings.TrimPrefix(pqErr.Constraint, pqErr.Table+"_"), suffix)
+	if slices.Contains(importColumns[jobType], column) {
+		rowError.Column = column
+	}
+	return rowError
+}
@@ -0,0 +1,283 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Job kinds.
+const (
+	JobKindEmail  = "email"
+	JobKindImport = "import"
+)
+
+// Job statuses. A failed job goes back to queued until it runs out of
+// attempts, when it becomes dead and waits for an admin to retry it.
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusDead      = "dead"
+)
+
+// Job is a unit of background work. The payload is not included in API
+// responses as it can hold personal details such as email addresses, and it
+// is cleared once the job has succeeded. Emails carrying a token only hold
+// the token's ID; the plaintext is made when the email is sent.
+type Job struct {
+	ID          string          `json:"id"`
+	Kind        string          `json:"kind"`
+	Payload     json.RawMessage `json:"-"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"`
+	LockedAt    *time.Time      `json:"locked_at,omitempty"`
+	LastError   *string         `json:"last_error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   *time.Time      `json:"updated_at,omitempty"`
+	FinishedAt  *time.Time      `json:"finished_at,omitempty"`
+	Version     int32           `json:"version"`
+}
+
+type JobModel struct {
+	DB *sql.DB
+}
+
+// Backoff returns how long to wait before retrying a job that has failed the
+// given number of times: 10 seconds, doubling with each attempt up to an hour.
+func Backoff(attempts int) time.Duration {
+	const (
+		base     = 10 * time.Second
+		maxDelay = time.Hour
+	)
+
+	delay := base
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxDelay {
+			return maxDelay
+		}
+	}
+	return delay
+}
+
+const jobColumns = `id, kind, payload, status, attempts, max_attempts, run_at, locked_at, last_error,
+               created_at, updated_at, finished_at, version`
+
+func scanJob(row interface{ Scan(...interface{}) error }, job *Job, extra ...interface{}) error {
+	dest := append(extra,
+		&job.ID,
+		&job.Kind,
+		(*[]byte)(&job.Payload),
+		&job.Status,
+		&job.Attempts,
+		&job.MaxAttempts,
+		&job.RunAt,
+		&job.LockedAt,
+		&job.LastError,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&job.FinishedAt,
+		&job.Version,
+	)
+	return row.Scan(dest...)
+}
+
+// Insert queues a new job to run as soon as a worker is free.
+func (m JobModel) Insert(job *Job) error {
+	query := `
+        INSERT INTO jobs (kind, payload, max_attempts)
+        VALUES ($1, $2, $3)
+        RETURNING ` + jobColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return scanJob(m.DB.QueryRowContext(ctx, query, job.Kind, string(job.Payload), job.MaxAttempts), job)
+}
+
+// Get a specific job by ID.
+func (m JobModel) Get(id string) (*Job, error) {
+	query := `
+        SELECT ` + jobColumns + `
+        FROM jobs
+        WHERE id = $1`
+
+	var job Job
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := scanJob(m.DB.QueryRowContext(ctx, query, id), &job)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &job, nil
+}
+
+// Claim locks the next due job for a worker, marking it running and counting
+// the attempt. Jobs left running for longer than lockTimeout, e.g. because
+// the process died, are treated as due again; if that pushes Attempts past
+// MaxAttempts the caller should fail the job rather than run it. It returns
+// ErrRecordNotFound when there is nothing to do.
+func (m JobModel) Claim(lockTimeout time.Duration) (*Job, error) {
+	query := `
+        UPDATE jobs
+        SET status = 'running', attempts = attempts + 1, locked_at = NOW(),
+            updated_at = NOW(), version = version + 1
+        WHERE id = (
+            SELECT id
+            FROM jobs
+            WHERE (status = 'queued' AND run_at <= NOW())
+            OR (status = 'running' AND locked_at < NOW() - make_interval(secs => $1))
+            ORDER BY run_at ASC
+            FOR UPDATE SKIP LOCKED
+            LIMIT 1
+        )
+        RETURNING ` + jobColumns
+
+	var job Job
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := scanJob(m.DB.QueryRowContext(ctx, query, lockTimeout.Seconds()), &job)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &job, nil
+}
+
+// Complete marks a claimed job as succeeded and clears its payload, which is
+// no longer needed. It returns ErrEditConflict if the job has been claimed
+// again since, which only happens after its lock timed out.
+func (m JobModel) Complete(job *Job) error {
+	query := `
+        UPDATE jobs
+        SET status = 'succeeded', payload = '{}', locked_at = NULL, updated_at = NOW(), finished_at = NOW(),
+            version = version + 1
+        WHERE id = $1 AND version = $2
+        RETURNING ` + jobColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := scanJob(m.DB.QueryRowContext(ctx, query, job.ID, job.Version), job)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Fail records a failed attempt. The job is queued again after its backoff,
+// or dead-lettered once it has used all of its attempts.
+func (m JobModel) Fail(job *Job, lastError string) error {
+	query := `
+        UPDATE jobs
+        SET status = $1, run_at = $2, last_error = $3, locked_at = NULL, updated_at = NOW(),
+            finished_at = CASE WHEN $1 = 'dead' THEN NOW() END, version = version + 1
+        WHERE id = $4 AND version = $5
+        RETURNING ` + jobColumns
+
+	status := JobStatusQueued
+	if job.Attempts >= job.MaxAttempts {
+		status = JobStatusDead
+	}
+	runAt := time.Now().Add(Backoff(job.Attempts))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := scanJob(m.DB.QueryRowContext(ctx, query, status, runAt, lastError, job.ID, job.Version), job)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Retry queues a dead job to run again straight away with a fresh set of
+// attempts. It returns ErrEditConflict if the job is not dead.
+func (m JobModel) Retry(job *Job) error {
+	query := `
+        UPDATE jobs
+        SET status = 'queued', attempts = 0, run_at = NOW(), updated_at = NOW(), finished_at = NULL,
+            version = version + 1
+        WHERE id = $1 AND version = $2 AND status = 'dead'
+        RETURNING ` + jobColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := scanJob(m.DB.QueryRowContext(ctx, query, job.ID, job.Version), job)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAll returns a paginated list of jobs, filterable by kind and status.
+func (m JobModel) GetAll(kind, status string, filters Filters) ([]*Job, Metadata, error) {
+	query := fmt.Sprintf(`
+        SELECT count(*) OVER(), `+jobColumns+`
+        FROM jobs
+        WHERE (kind = $1 OR $1 = '')
+        AND (status = $2 OR $2 = '')
+        ORDER BY %s %s, id ASC
+        LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, kind, status, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := int64(0)
+	jobs := []*Job{}
+
+	for rows.Next() {
+		var job Job
+		err := scanJob(rows, &job, &totalRecords)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		jobs = append(jobs, &job)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return jobs, metadata, nil
+}
@@ -0,0 +1,151 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// LockoutPolicy decides how long a client has to wait after failing to log
+// in. The first few failures are free, then each one doubles the wait up to
+// MaxDelay, and once MaxAttempts is reached the key is locked out for
+// LockoutDuration. Failures are forgotten after a quiet Window.
+type LockoutPolicy struct {
+	FreeAttempts    int
+	MaxAttempts     int
+	MaxDelay        time.Duration
+	LockoutDuration time.Duration
+	Window          time.Duration
+}
+
+// Delay returns how long to block a key that has failed the given number of
+// times.
+func (p LockoutPolicy) Delay(failures int) time.Duration {
+	switch {
+	case failures >= p.MaxAttempts:
+		return p.LockoutDuration
+	case failures <= p.FreeAttempts:
+		return 0
+	}
+
+	delay := time.Second
+	for i := p.FreeAttempts + 1; i < failures; i++ {
+		delay *= 2
+		if delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	return delay
+}
+
+// LoginEmailKey and LoginIPKey return the keys failed logins are counted
+// under.
+func LoginEmailKey(email string) string {
+	return "email:" + strings.ToLower(email)
+}
+
+func LoginIPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// LoginFailureModel tracks failed logins.
+type LoginFailureModel struct {
+	DB *sql.DB
+}
+
+// Wait returns how long the client has to wait before it may try to log in
+// under any of the keys.
+func (m LoginFailureModel) Wait(keys ...string) (time.Duration, error) {
+	query := `
+        SELECT EXTRACT(EPOCH FROM MAX(blocked_until) - NOW())
+        FROM login_failures
+        WHERE key = ANY($1)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var seconds sql.NullFloat64
+	err := m.DB.QueryRowContext(ctx, query, pq.Array(keys)).Scan(&seconds)
+	if err != nil || !seconds.Valid || seconds.Float64 <= 0 {
+		return 0, err
+	}
+	return time.Duration(seconds.Float64 * float64(time.Second)), nil
+}
+
+// RecordFailure counts a failed login under a key and blocks it according to
+// the policy. lockedUntil is set only by the failure that triggers a lockout,
+// so that the owner is told about it once.
+func (m LoginFailureModel) RecordFailure(key string, policy LockoutPolicy) (lockedUntil *time.Time, err error) {
+	query := `
+        INSERT INTO login_failures (key, failures, last_failed_at)
+        VALUES ($1, 1, NOW())
+        ON CONFLICT (key) DO UPDATE
+        SET failures = CASE
+                WHEN login_failures.last_failed_at < NOW() - make_interval(secs => $2) THEN 1
+                ELSE login_failures.failures + 1
+            END,
+            last_failed_at = NOW()
+        RETURNING failures`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var failures int
+	err = tx.QueryRowContext(ctx, query, key, policy.Window.Seconds()).Scan(&failures)
+	if err != nil {
+		return nil, err
+	}
+
+	blockedUntil := time.Now().Add(policy.Delay(failures))
+	_, err = tx.ExecContext(ctx, `UPDATE login_failures SET blocked_until = $1 WHERE key = $2`, blockedUntil, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	if failures == policy.MaxAttempts {
+		return &blockedUntil, nil
+	}
+	return nil, nil
+}
+
+// Reset forgets the failures under a key, after a successful login or when
+// an admin unlocks an account.
+func (m LoginFailureModel) Reset(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `DELETE FROM login_failures WHERE key = $1`, key)
+	return err
+}
+
+// DeleteStale deletes keys that are no longer blocked and haven't failed for
+// longer than window, which should be the longest policy Window. It returns
+// how many there were.
+func (m LoginFailureModel) DeleteStale(window time.Duration) (int64, error) {
+	query := `
+        DELETE FROM login_failures
+        WHERE blocked_until < NOW()
+        AND last_failed_at < NOW() - make_interval(secs => $1)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, window.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
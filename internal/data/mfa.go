@@ -0,0 +1,278 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// recoveryCodeCount is how many single-use recovery codes a user gets when
+// they enable two-factor authentication.
+const recoveryCodeCount = 10
+
+// MFAModel manages users' TOTP secrets and recovery codes.
+type MFAModel struct {
+	DB *sql.DB
+}
+
+// BeginTOTP stores a new secret for a user who is enrolling. TOTP isn't
+// enabled until they confirm a code from their app. It returns
+// ErrEditConflict if the user already has TOTP enabled.
+func (m MFAModel) BeginTOTP(userID, secret string) error {
+	query := `
+        UPDATE users
+        SET totp_secret = $1, totp_last_step = 0
+        WHERE id = $2 AND NOT totp_enabled`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, secret, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrEditConflict
+	}
+	return nil
+}
+
+// CheckTOTP checks a code against the user's secret. An accepted code's time
+// step is recorded so that the code can't be used a second time. It returns
+// false for a wrong or reused code, or if the user has no secret.
+func (m MFAModel) CheckTOTP(userID, code string, now time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var secret sql.NullString
+	err := m.DB.QueryRowContext(ctx, `SELECT totp_secret FROM users WHERE id = $1`, userID).Scan(&secret)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return false, ErrRecordNotFound
+		default:
+			return false, err
+		}
+	}
+	if !secret.Valid {
+		return false, nil
+	}
+
+	step, ok, err := MatchTOTP(secret.String, code, now)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	query := `
+        UPDATE users
+        SET totp_last_step = $1
+        WHERE id = $2 AND totp_last_step < $1`
+
+	result, err := m.DB.ExecContext(ctx, query, step, userID)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected == 1, nil
+}
+
+// EnableTOTP turns TOTP on for a user who has confirmed their secret and
+// returns their recovery codes.
+func (m MFAModel) EnableTOTP(actor AuditActor, userID string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := getUser(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE users SET totp_enabled = true WHERE id = $1 AND totp_secret IS NOT NULL`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := replaceRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = auditMFAChange(ctx, tx, actor, before)
+	if err != nil {
+		return nil, err
+	}
+	return codes, tx.Commit()
+}
+
+// ReplaceRecoveryCodes invalidates a user's recovery codes and returns a new
+// set. The codes aren't part of the user's record, so the audit event's
+// before and after are the same; it records that the codes were replaced.
+func (m MFAModel) ReplaceRecoveryCodes(actor AuditActor, userID string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := getUser(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := replaceRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = auditMFAChange(ctx, tx, actor, before)
+	if err != nil {
+		return nil, err
+	}
+	return codes, tx.Commit()
+}
+
+// auditMFAChange audits a change to a user's two-factor authentication made
+// using q, reading the user as they now are. before is the user as read
+// earlier in the same transaction.
+func auditMFAChange(ctx context.Context, q queryer, actor AuditActor, before *User) error {
+	after, err := getUser(ctx, q, before.ID)
+	if err != nil {
+		return err
+	}
+	return audit(ctx, q, actor, AuditActionUpdate, "user", before.ID, before, after)
+}
+
+func replaceRecoveryCodes(ctx context.Context, q queryer, userID string) ([]string, error) {
+	_, err := q.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([][]byte, recoveryCodeCount)
+	for i := range codes {
+		codes[i], err = newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		hash := sha256.Sum256([]byte(normalizeRecoveryCode(codes[i])))
+		hashes[i] = hash[:]
+	}
+
+	query := `
+        INSERT INTO mfa_recovery_codes (user_id, hash)
+        SELECT $1, unnest($2::bytea[])`
+
+	_, err = q.ExecContext(ctx, query, userID, pq.Array(hashes))
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// UseRecoveryCode marks one of a user's recovery codes as used. It returns
+// false if the code is wrong or has already been used.
+func (m MFAModel) UseRecoveryCode(userID, code string) (bool, error) {
+	query := `
+        UPDATE mfa_recovery_codes
+        SET used_at = NOW()
+        WHERE user_id = $1 AND hash = $2 AND used_at IS NULL`
+
+	hash := sha256.Sum256([]byte(normalizeRecoveryCode(code)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, hash[:])
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// Disable turns TOTP off for a user and deletes their secret and recovery
+// codes.
+func (m MFAModel) Disable(actor AuditActor, userID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getUser(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        UPDATE users
+        SET totp_secret = NULL, totp_enabled = false, totp_last_step = 0
+        WHERE id = $1`
+
+	_, err = tx.ExecContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	err = auditMFAChange(ctx, tx, actor, before)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// newRecoveryCode returns a random code such as "k3j9a-x2m4q".
+func newRecoveryCode() (string, error) {
+	randomBytes := make([]byte, 7)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(totpEncoding.EncodeToString(randomBytes))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// normalizeRecoveryCode lets users type recovery codes in either case and
+// with or without the dash.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,101 @@
+package data
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMFAModel(t *testing.T) {
+	db := setupUsersTestDB(t)
+
+	_, err := db.Exec(`
+    CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+        id BIGSERIAL PRIMARY KEY,
+        user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        hash BYTEA NOT NULL,
+        used_at TIMESTAMPTZ,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );`)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := db.Exec("DROP TABLE IF EXISTS mfa_recovery_codes;")
+		require.NoError(t, err)
+	})
+
+	users := UserModel{DB: db}
+	m := MFAModel{DB: db}
+
+	user := newTestUser(t)
+	require.NoError(t, users.Insert(AuditActor{}, user))
+
+	// No secret yet, so no code can match.
+	ok, err := m.CheckTOTP(user.ID, "123456", time.Now())
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, m.BeginTOTP(user.ID, rfc6238Secret))
+
+	now := time.Now()
+	code, err := TOTPCode(rfc6238Secret, now)
+	require.NoError(t, err)
+	ok, err = m.CheckTOTP(user.ID, code, now)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// The same code can't be used twice.
+	ok, err = m.CheckTOTP(user.ID, code, now)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	codes, err := m.EnableTOTP(AuditActor{}, user.ID)
+	require.NoError(t, err)
+	require.Len(t, codes, recoveryCodeCount)
+
+	fetched, err := users.Get(user.ID)
+	require.NoError(t, err)
+	require.True(t, fetched.MFAEnabled)
+
+	// Enrolling again needs TOTP to be turned off first.
+	require.ErrorIs(t, m.BeginTOTP(user.ID, rfc6238Secret), ErrEditConflict)
+
+	// Recovery codes work once each, and are replaced as a set.
+	ok, err = m.UseRecoveryCode(user.ID, codes[0])
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = m.UseRecoveryCode(user.ID, codes[0])
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	newCodes, err := m.ReplaceRecoveryCodes(AuditActor{}, user.ID)
+	require.NoError(t, err)
+	ok, err = m.UseRecoveryCode(user.ID, codes[1])
+	require.NoError(t, err)
+	require.False(t, ok)
+	ok, err = m.UseRecoveryCode(user.ID, newCodes[1])
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.NoError(t, m.Disable(AuditActor{}, user.ID))
+	fetched, err = users.Get(user.ID)
+	require.NoError(t, err)
+	require.False(t, fetched.MFAEnabled)
+
+	// Enabling, replacing the recovery codes and disabling are each audited
+	// against the user as they were before.
+	events := auditEvents(t, db, AuditEventFilter{Action: AuditActionUpdate, EntityType: "user", EntityID: user.ID})
+	require.Len(t, events, 3)
+
+	// Newest first: disabled, then recovery codes replaced, then enabled.
+	var before, after User
+	require.NoError(t, json.Unmarshal(events[0].Before, &before))
+	require.NoError(t, json.Unmarshal(events[0].After, &after))
+	require.True(t, before.MFAEnabled)
+	require.False(t, after.MFAEnabled)
+	require.NoError(t, json.Unmarshal(events[2].Before, &before))
+	require.NoError(t, json.Unmarshal(events[2].After, &after))
+	require.False(t, before.MFAEnabled)
+	require.True(t, after.MFAEnabled)
+}
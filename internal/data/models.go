@@ -2,9 +2,18 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 )
 
+// queryer is satisfied by both *sql.DB and *sql.Tx, so that insert helpers can
+// be shared between the models and the import transaction.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
 // Models struct holds all data models for the application.
 type Models struct {
 	Users    UserModel
@@ -17,6 +26,18 @@ type Models struct {
 	SessionFacilitators SessionFacilitatorModel
 	SessionFeedback     SessionFeedbackModel
 	ImportJobs          ImportJobModel
+	Regions             RegionModel
+	Formations          FormationModel
+	Postings            PostingModel
+	Ranks               RankModel
+	ComplianceRequirements ComplianceRequirementModel
+	Jobs                   JobModel
+	APIKeys                APIKeyModel
+	MFA                    MFAModel
+	LoginFailures          LoginFailureModel
+	AuditEvents            AuditEventModel
+	SessionEnrollments     SessionEnrollmentModel
+	SessionSeries          SessionSeriesModel
 }
 
 // NewModels initializes and returns a Models struct.
@@ -32,5 +53,17 @@ func NewModels(db *sql.DB) Models {
 		SessionFacilitators: SessionFacilitatorModel{DB: db},
 		SessionFeedback:     SessionFeedbackModel{DB: db},
 		ImportJobs:          ImportJobModel{DB: db},
+		Regions:             RegionModel{DB: db},
+		Formations:          FormationModel{DB: db},
+		Postings:            PostingModel{DB: db},
+		Ranks:               RankModel{DB: db},
+		ComplianceRequirements: ComplianceRequirementModel{DB: db},
+		Jobs:                   JobModel{DB: db},
+		APIKeys:                APIKeyModel{DB: db},
+		MFA:                    MFAModel{DB: db},
+		LoginFailures:          LoginFailureModel{DB: db},
+		AuditEvents:            AuditEventModel{DB: db},
+		SessionEnrollments:     SessionEnrollmentModel{DB: db},
+		SessionSeries:          SessionSeriesModel{DB: db},
 	}
 }
\ No newline at end of file
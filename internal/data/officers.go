@@ -20,6 +20,7 @@ type Officer struct {
 	RegionID         *string    `json:"region_id,omitempty"`
 	FormationID      *string    `json:"formation_id,omitempty"`
 	PostingID        *string    `json:"posting_id,omitempty"`
+	Email            *string    `json:"email,omitempty"`
 	CreatedAt        time.Time  `json:"created_at"`
 	UpdatedAt        *time.Time `json:"updated_at,omitempty"`
 	ArchivedAt       *time.Time `json:"archived_at,omitempty"`
@@ -38,46 +39,99 @@ func ValidateOfficer(v *validator.Validator, officer *Officer) {
 	v.Check(officer.Sex != "", "sex", "must be provided")
 	v.Check(validator.In(officer.Sex, "male", "female", "unknown"), "sex", "must be male, female, or unknown")
 	v.Check(officer.RankCode != "", "rank_code", "must be provided")
+	v.Check(officer.FormationID == nil || officer.RegionID != nil, "region_id", "must be provided when formation_id is set")
+	// The email address is only used to tell the officer about their sessions.
+	if officer.Email != nil {
+		v.Check(validator.Matches(*officer.Email, validator.EmailRX), "email", "must be a valid email address")
+	}
 }
 
 // Insert a new officer record into the database.
-func (m OfficerModel) Insert(officer *Officer) error {
-	query := `
-        INSERT INTO officers (regulation_number, first_name, last_name, sex, rank_code)
-        VALUES ($1, $2, $3, $4, $5)
-        RETURNING id, created_at, version`
+func (m OfficerModel) Insert(actor AuditActor, officer *Officer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	args := []interface{}{officer.RegulationNumber, &officer.FirstName, &officer.LastName, &officer.Sex, &officer.RankCode}
+	err = insertOfficer(ctx, tx, officer)
+	if err != nil {
+		return err
+	}
 
-	return m.DB.QueryRow(query, args...).Scan(&officer.ID, &officer.CreatedAt, &officer.Version)
+	err = audit(ctx, tx, actor, AuditActionInsert, "officer", officer.ID, nil, officer)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// Get a specific officer by ID.
-func (m OfficerModel) Get(id string) (*Officer, error) {
+// insertOfficer inserts an officer using q, which may be a transaction.
+func insertOfficer(ctx context.Context, q queryer, officer *Officer) error {
 	query := `
-        SELECT id, regulation_number, first_name, last_name, sex, rank_code,
-               created_at, updated_at, version
-        FROM officers
-        WHERE id = $1`
+        INSERT INTO officers (regulation_number, first_name, last_name, sex, rank_code,
+                              region_id, formation_id, posting_id, email)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        RETURNING id, created_at, version`
 
-	var officer Officer
-	
-    // Use a context with a 3-second timeout.
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+	args := []interface{}{
+		officer.RegulationNumber,
+		officer.FirstName,
+		officer.LastName,
+		officer.Sex,
+		officer.RankCode,
+		officer.RegionID,
+		officer.FormationID,
+		officer.PostingID,
+		officer.Email,
+	}
+
+	return q.QueryRowContext(ctx, query, args...).Scan(&officer.ID, &officer.CreatedAt, &officer.Version)
+}
 
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+const officerColumns = `id, regulation_number, first_name, last_name, sex, rank_code,
+               region_id, formation_id, posting_id, email, created_at, updated_at, archived_at, version`
+
+func scanOfficer(row interface{ Scan(...interface{}) error }, officer *Officer) error {
+	return row.Scan(
 		&officer.ID,
 		&officer.RegulationNumber,
 		&officer.FirstName,
 		&officer.LastName,
 		&officer.Sex,
 		&officer.RankCode,
+		&officer.RegionID,
+		&officer.FormationID,
+		&officer.PostingID,
+		&officer.Email,
 		&officer.CreatedAt,
 		&officer.UpdatedAt,
-		&officer.Version, // Scan the version
+		&officer.ArchivedAt,
+		&officer.Version,
 	)
+}
+
+// Get a specific officer by ID.
+func (m OfficerModel) Get(id string) (*Officer, error) {
+    // Use a context with a 3-second timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return getOfficer(ctx, m.DB, id)
+}
+
+// getOfficer fetches an officer using q, which may be a transaction.
+func getOfficer(ctx context.Context, q queryer, id string) (*Officer, error) {
+	query := `
+        SELECT ` + officerColumns + `
+        FROM officers
+        WHERE id = $1`
 
+	var officer Officer
+	err := scanOfficer(q.QueryRowContext(ctx, query, id), &officer)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -90,12 +144,13 @@ func (m OfficerModel) Get(id string) (*Officer, error) {
 }
 
 // Update a specific officer record.
-func (m OfficerModel) Update(officer *Officer) error {
+func (m OfficerModel) Update(actor AuditActor, officer *Officer) error {
 	query := `
         UPDATE officers
         SET regulation_number = $1, first_name = $2, last_name = $3, sex = $4, rank_code = $5,
+            region_id = $6, formation_id = $7, posting_id = $8, email = $9,
             updated_at = NOW(), version = version + 1
-        WHERE id = $6 AND version = $7
+        WHERE id = $10 AND version = $11
         RETURNING updated_at, version`
 
 	args := []interface{}{
@@ -104,16 +159,70 @@ func (m OfficerModel) Update(officer *Officer) error {
 		officer.LastName,
 		officer.Sex,
 		officer.RankCode,
+		officer.RegionID,
+		officer.FormationID,
+		officer.PostingID,
+		officer.Email,
 		officer.ID,
 		officer.Version, // Add the version for optimistic locking
 	}
-	
+
+	return m.update(actor, officer, query, args, &officer.UpdatedAt, &officer.Version)
+}
+
+// Archive marks an officer as archived. Archived officers keep their attendance
+// and feedback history but are hidden from listings by default.
+func (m OfficerModel) Archive(actor AuditActor, officer *Officer) error {
+	query := `
+        UPDATE officers
+        SET archived_at = NOW(), updated_at = NOW(), version = version + 1
+        WHERE id = $1 AND version = $2
+        RETURNING archived_at, updated_at, version`
+
+	args := []interface{}{officer.ID, officer.Version}
+
+	return m.update(actor, officer, query, args, &officer.ArchivedAt, &officer.UpdatedAt, &officer.Version)
+}
+
+// Unarchive restores an archived officer.
+func (m OfficerModel) Unarchive(actor AuditActor, officer *Officer) error {
+	query := `
+        UPDATE officers
+        SET archived_at = NULL, updated_at = NOW(), version = version + 1
+        WHERE id = $1 AND version = $2
+        RETURNING archived_at, updated_at, version`
+
+	args := []interface{}{officer.ID, officer.Version}
+
+	return m.update(actor, officer, query, args, &officer.ArchivedAt, &officer.UpdatedAt, &officer.Version)
+}
+
+// update runs one of the officer updates above, scanning what it returns into
+// dest, and audits the change. The officer as it was is read in the same
+// transaction; the version check means it is exactly what the update changed.
+func (m OfficerModel) update(actor AuditActor, officer *Officer, query string, args []interface{}, dest ...interface{}) error {
     // Use a context with a 3-second timeout.
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getOfficer(ctx, tx, officer.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRecordNotFound):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
 	// Use QueryRowContext and check for ErrNoRows to detect edit conflicts.
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&officer.UpdatedAt, &officer.Version)
+	err = tx.QueryRowContext(ctx, query, args...).Scan(dest...)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -122,11 +231,18 @@ func (m OfficerModel) Update(officer *Officer) error {
 			return err
 		}
 	}
-	return nil
+
+	err = audit(ctx, tx, actor, AuditActionUpdate, "officer", officer.ID, before, officer)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// Delete a specific officer record from the database.
-func (m OfficerModel) Delete(id string) error {
+// Delete a specific officer record from the database. This also removes the
+// officer's attendance, feedback and enrollments, so prefer Archive. They are
+// deleted here rather than left to cascade, so that each is audited.
+func (m OfficerModel) Delete(actor AuditActor, id string) error {
 	// The id should be a valid UUID, but for now we'll assume it is.
 	// We'll return an error if the ID is somehow empty.
 	if id == "" {
@@ -135,50 +251,72 @@ func (m OfficerModel) Delete(id string) error {
 
 	query := `
         DELETE FROM officers
-        WHERE id = $1`
+        WHERE id = $1
+        RETURNING ` + officerColumns
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	// Use ExecContext for DELETE operations. This returns a sql.Result object
-	// which contains information about how many rows were affected.
-	result, err := m.DB.ExecContext(ctx, query, id)
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	// Check how many rows were affected.
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
+	for _, deleteChildren := range []func(context.Context, queryer, AuditActor, string, string) (int, error){
+		deleteAttendance,
+		deleteSessionFeedback,
+		deleteSessionEnrollments,
+	} {
+		_, err = deleteChildren(ctx, tx, actor, "officer_id = $1", id)
+		if err != nil {
+			return err
+		}
 	}
 
-	// If no rows were affected, it means our WHERE clause didn't match any records.
-	// We can then return our custom ErrRecordNotFound error.
-	if rowsAffected == 0 {
-		return ErrRecordNotFound
+	// If no rows were deleted, it means our WHERE clause didn't match any
+	// records, so we return our custom ErrRecordNotFound error.
+	var officer Officer
+	err = scanOfficer(tx.QueryRowContext(ctx, query, id), &officer)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
 	}
 
-	return nil
+	err = audit(ctx, tx, actor, AuditActionDelete, "officer", officer.ID, &officer, nil)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// GetAll returns a paginated and filtered list of officers.
-func (m OfficerModel) GetAll(firstName string, lastName string, rankCode string, filters Filters) ([]*Officer, Metadata, error) {
+// GetAll returns a paginated and filtered list of officers. The region, formation
+// and posting filters match exactly and are ignored when empty. Archived officers
+// are only included when includeArchived is true.
+func (m OfficerModel) GetAll(firstName string, lastName string, rankCode string, regionID string, formationID string, postingID string, includeArchived bool, filters Filters) ([]*Officer, Metadata, error) {
 	// Use a window function to get the total number of records.
 	query := fmt.Sprintf(`
         SELECT count(*) OVER(), id, regulation_number, first_name, last_name, sex, rank_code,
-               created_at, updated_at, version
+               region_id, formation_id, posting_id, email, created_at, updated_at, archived_at, version
         FROM officers
         WHERE (to_tsvector('simple', first_name) @@ plainto_tsquery('simple', $1) OR $1 = '')
         AND (to_tsvector('simple', last_name) @@ plainto_tsquery('simple', $2) OR $2 = '')
         AND (LOWER(rank_code) = LOWER($3) OR $3 = '')
+        AND (region_id = $4 OR $4 = '')
+        AND (formation_id = $5 OR $5 = '')
+        AND (posting_id = $6 OR $6 = '')
+        AND (archived_at IS NULL OR $7)
         ORDER BY %s %s, id ASC
-        LIMIT $4 OFFSET $5`, filters.sortColumn(), filters.sortDirection())
+        LIMIT $8 OFFSET $9`, filters.sortColumn(), filters.sortDirection())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []interface{}{firstName, lastName, rankCode, filters.limit(), filters.offset()}
+	args := []interface{}{firstName, lastName, rankCode, regionID, formationID, postingID, includeArchived, filters.limit(), filters.offset()}
 
 	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -199,8 +337,13 @@ func (m OfficerModel) GetAll(firstName string, lastName string, rankCode string,
 			&officer.LastName,
 			&officer.Sex,
 			&officer.RankCode,
+			&officer.RegionID,
+			&officer.FormationID,
+			&officer.PostingID,
+			&officer.Email,
 			&officer.CreatedAt,
 			&officer.UpdatedAt,
+			&officer.ArchivedAt,
 			&officer.Version,
 		)
 		if err != nil {
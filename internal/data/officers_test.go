@@ -45,9 +45,10 @@ func setupTestDB(t *testing.T) *sql.DB {
         last_name TEXT NOT NULL,
         sex TEXT NOT NULL,
         rank_code TEXT NOT NULL,
-        region_id UUID,
-        formation_id UUID,
-        posting_id UUID,
+        region_id TEXT,
+        formation_id TEXT,
+        posting_id TEXT,
+        email TEXT,
         created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
         updated_at TIMESTAMPTZ,
         archived_at TIMESTAMPTZ,
@@ -63,6 +64,9 @@ func setupTestDB(t *testing.T) *sql.DB {
 		db.Close()
 	})
 
+	// Registered after the cleanup above, so it runs first.
+	createAuditEventsTable(t, db)
+
 	return db
 }
 
@@ -84,7 +88,7 @@ func TestOfficerModel_Insert(t *testing.T) {
 
 	officer := newTestOfficer(t)
 
-	err := m.Insert(officer)
+	err := m.Insert(AuditActor{}, officer)
 	require.NoError(t, err)
 
 	// Check that the database populated the ID and CreatedAt fields.
@@ -105,7 +109,7 @@ func TestOfficerModel_Get(t *testing.T) {
 
 	// First, insert a record to test Get.
 	officer := newTestOfficer(t)
-	err := m.Insert(officer)
+	err := m.Insert(AuditActor{}, officer)
 	require.NoError(t, err)
 
 	// Test successful Get.
@@ -136,7 +140,7 @@ func TestOfficerModel_Update(t *testing.T) {
 
 	// Insert a record to test Update.
 	officer := newTestOfficer(t)
-	err := m.Insert(officer)
+	err := m.Insert(AuditActor{}, officer)
 	require.NoError(t, err)
 
 	// Update some fields.
@@ -145,7 +149,7 @@ func TestOfficerModel_Update(t *testing.T) {
 	newRegNum := "67890"
 	officer.RegulationNumber = &newRegNum
 
-	err = m.Update(officer)
+	err = m.Update(AuditActor{}, officer)
 	require.NoError(t, err)
 
 	// Check that the version incremented and UpdatedAt is set.
@@ -164,7 +168,7 @@ func TestOfficerModel_Update(t *testing.T) {
 	// Test for edit conflict (optimistic locking).
 	// Try to update again with the old version number (version 1).
 	officer.Version = 1
-	err = m.Update(officer)
+	err = m.Update(AuditActor{}, officer)
 	require.Error(t, err)
 	require.True(t, errors.Is(err, ErrEditConflict))
 }
@@ -173,13 +177,16 @@ func TestOfficerModel_Delete(t *testing.T) {
 	db := setupTestDB(t)
 	m := OfficerModel{DB: db}
 
+	// Deleting an officer deletes their records in sessions too.
+	createSessionRecordTables(t, db)
+
 	// Insert a record to test Delete.
 	officer := newTestOfficer(t)
-	err := m.Insert(officer)
+	err := m.Insert(AuditActor{}, officer)
 	require.NoError(t, err)
 
 	// Test successful deletion.
-	err = m.Delete(officer.ID)
+	err = m.Delete(AuditActor{}, officer.ID)
 	require.NoError(t, err)
 
 	// Verify it's gone.
@@ -189,7 +196,7 @@ func TestOfficerModel_Delete(t *testing.T) {
 
 	// Test deleting a non-existent record.
 	nonExistentID := "f47ac10b-58cc-4372-a567-0e02b2c3d479"
-	err = m.Delete(nonExistentID)
+	err = m.Delete(AuditActor{}, nonExistentID)
 	require.Error(t, err)
 	require.True(t, errors.Is(err, ErrRecordNotFound))
 }
@@ -202,35 +209,35 @@ func TestOfficerModel_GetAll(t *testing.T) {
 	officer1 := Officer{FirstName: "Alice", LastName: "Smith", Sex: "female", RankCode: "CONSTABLE"}
 	officer2 := Officer{FirstName: "Bob", LastName: "Jones", Sex: "male", RankCode: "SERGEANT"}
 	officer3 := Officer{FirstName: "Charlie", LastName: "Smith", Sex: "male", RankCode: "CONSTABLE"}
-	require.NoError(t, m.Insert(&officer1))
-	require.NoError(t, m.Insert(&officer2))
-	require.NoError(t, m.Insert(&officer3))
+	require.NoError(t, m.Insert(AuditActor{}, &officer1))
+	require.NoError(t, m.Insert(AuditActor{}, &officer2))
+	require.NoError(t, m.Insert(AuditActor{}, &officer3))
 
 	// Define a standard filter safelist.
 	safelist := []string{"id", "first_name", "last_name", "rank_code", "-id", "-first_name", "-last_name", "-rank_code"}
 
 	// Test case 1: Get all records with default pagination.
 	filters := Filters{Page: 1, PageSize: 20, Sort: "id", SortSafelist: safelist}
-	allOfficers, metadata, err := m.GetAll("", "", "", filters)
+	allOfficers, metadata, err := m.GetAll("", "", "", "", "", "", false, filters)
 	require.NoError(t, err)
 	require.Len(t, allOfficers, 3)
 	require.Equal(t, int64(3), metadata.TotalRecords)
 
 	// Test case 2: Filter by first_name.
-	filteredOfficers, metadata, err := m.GetAll("Alice", "", "", filters)
+	filteredOfficers, metadata, err := m.GetAll("Alice", "", "", "", "", "", false, filters)
 	require.NoError(t, err)
 	require.Len(t, filteredOfficers, 1)
 	require.Equal(t, "Alice", filteredOfficers[0].FirstName)
 	require.Equal(t, int64(1), metadata.TotalRecords)
 
 	// Test case 3: Filter by last_name.
-	filteredOfficers, metadata, err = m.GetAll("", "Smith", "", filters)
+	filteredOfficers, metadata, err = m.GetAll("", "Smith", "", "", "", "", false, filters)
 	require.NoError(t, err)
 	require.Len(t, filteredOfficers, 2)
 	require.Equal(t, int64(2), metadata.TotalRecords)
 
 	// Test case 4: Filter by rank_code.
-	filteredOfficers, metadata, err = m.GetAll("", "", "SERGEANT", filters)
+	filteredOfficers, metadata, err = m.GetAll("", "", "SERGEANT", "", "", "", false, filters)
 	require.NoError(t, err)
 	require.Len(t, filteredOfficers, 1)
 	require.Equal(t, "Bob", filteredOfficers[0].FirstName)
@@ -238,7 +245,7 @@ func TestOfficerModel_GetAll(t *testing.T) {
 
 	// Test case 5: Sorting (descending by first_name).
 	filters.Sort = "-first_name"
-	sortedOfficers, _, err := m.GetAll("", "", "", filters)
+	sortedOfficers, _, err := m.GetAll("", "", "", "", "", "", false, filters)
 	require.NoError(t, err)
 	require.Len(t, sortedOfficers, 3)
 	require.Equal(t, "Charlie", sortedOfficers[0].FirstName) // Charlie, Bob, Alice
@@ -249,7 +256,7 @@ func TestOfficerModel_GetAll(t *testing.T) {
 	filters.Page = 2
 	filters.PageSize = 2
 	filters.Sort = "first_name" // Sort ASC for predictable pagination
-	paginatedOfficers, metadata, err := m.GetAll("", "", "", filters)
+	paginatedOfficers, metadata, err := m.GetAll("", "", "", "", "", "", false, filters)
 	require.NoError(t, err)
 	require.Len(t, paginatedOfficers, 1)
 	require.Equal(t, "Charlie", paginatedOfficers[0].FirstName) // Page 1: Alice, Bob. Page 2: Charlie
@@ -258,6 +265,98 @@ func TestOfficerModel_GetAll(t *testing.T) {
 	require.Equal(t, 2, metadata.LastPage)
 }
 
+func TestOfficerModel_Assignments(t *testing.T) {
+	db := setupTestDB(t)
+	m := OfficerModel{DB: db}
+
+	region, formation, posting := "EAST", "BZC", "RACOON"
+
+	// Insert an officer with a full assignment and read it back.
+	officer := newTestOfficer(t)
+	officer.RegionID = &region
+	officer.FormationID = &formation
+	officer.PostingID = &posting
+	require.NoError(t, m.Insert(AuditActor{}, officer))
+
+	fetched, err := m.Get(officer.ID)
+	require.NoError(t, err)
+	require.Equal(t, region, *fetched.RegionID)
+	require.Equal(t, formation, *fetched.FormationID)
+	require.Equal(t, posting, *fetched.PostingID)
+
+	// Clearing the posting persists as NULL.
+	fetched.PostingID = nil
+	require.NoError(t, m.Update(AuditActor{}, fetched))
+	fetched, err = m.Get(officer.ID)
+	require.NoError(t, err)
+	require.Nil(t, fetched.PostingID)
+
+	// An unassigned officer should not match the region filter.
+	other := Officer{FirstName: "Bob", LastName: "Jones", Sex: "male", RankCode: "PC"}
+	require.NoError(t, m.Insert(AuditActor{}, &other))
+
+	safelist := []string{"id", "-id"}
+	filters := Filters{Page: 1, PageSize: 20, Sort: "id", SortSafelist: safelist}
+
+	officers, metadata, err := m.GetAll("", "", "", region, "", "", false, filters)
+	require.NoError(t, err)
+	require.Len(t, officers, 1)
+	require.Equal(t, officer.ID, officers[0].ID)
+	require.Equal(t, int64(1), metadata.TotalRecords)
+
+	officers, _, err = m.GetAll("", "", "", "", formation, "", false, filters)
+	require.NoError(t, err)
+	require.Len(t, officers, 1)
+
+	officers, _, err = m.GetAll("", "", "", "", "", posting, false, filters)
+	require.NoError(t, err)
+	require.Len(t, officers, 0)
+}
+
+func TestOfficerModel_Archive(t *testing.T) {
+	db := setupTestDB(t)
+	m := OfficerModel{DB: db}
+
+	officer := newTestOfficer(t)
+	require.NoError(t, m.Insert(AuditActor{}, officer))
+
+	err := m.Archive(AuditActor{}, officer)
+	require.NoError(t, err)
+	require.NotNil(t, officer.ArchivedAt)
+	require.Equal(t, int32(2), officer.Version)
+
+	// Archived officers are hidden from listings unless asked for.
+	safelist := []string{"id", "-id"}
+	filters := Filters{Page: 1, PageSize: 20, Sort: "id", SortSafelist: safelist}
+
+	officers, _, err := m.GetAll("", "", "", "", "", "", false, filters)
+	require.NoError(t, err)
+	require.Len(t, officers, 0)
+
+	officers, _, err = m.GetAll("", "", "", "", "", "", true, filters)
+	require.NoError(t, err)
+	require.Len(t, officers, 1)
+	require.NotNil(t, officers[0].ArchivedAt)
+
+	// They can still be fetched directly.
+	fetched, err := m.Get(officer.ID)
+	require.NoError(t, err)
+	require.NotNil(t, fetched.ArchivedAt)
+
+	// Archiving with a stale version is an edit conflict.
+	stale := *fetched
+	stale.Version = 1
+	require.True(t, errors.Is(m.Archive(AuditActor{}, &stale), ErrEditConflict))
+
+	err = m.Unarchive(AuditActor{}, fetched)
+	require.NoError(t, err)
+	require.Nil(t, fetched.ArchivedAt)
+
+	officers, _, err = m.GetAll("", "", "", "", "", "", false, filters)
+	require.NoError(t, err)
+	require.Len(t, officers, 1)
+}
+
 func TestValidateOfficer(t *testing.T) {
 	v := validator.New()
 	officer := Officer{
@@ -275,6 +374,14 @@ func TestValidateOfficer(t *testing.T) {
 	require.Contains(t, v.Errors, "sex")
 	require.Contains(t, v.Errors, "rank_code")
 
+	// A formation without a region is rejected.
+	v = validator.New()
+	formation := "BZC"
+	officerWithFormation := newTestOfficer(t)
+	officerWithFormation.FormationID = &formation
+	ValidateOfficer(v, officerWithFormation)
+	require.Contains(t, v.Errors, "region_id")
+
 	// Test valid officer
 	v = validator.New()
 	validOfficer := newTestOfficer(t)
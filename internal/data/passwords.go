@@ -0,0 +1,313 @@
+package data
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/amari03/test1/internal/validator"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// A PasswordHasher hashes new passwords. Hashes are self-describing strings
+// that record the algorithm and parameters used, so a password can always be
+// checked whichever hasher is configured now, and hashes made with anything
+// else can be spotted and upgraded when the user next logs in.
+type PasswordHasher interface {
+	// Hash returns the hash of a password.
+	Hash(plaintext string) ([]byte, error)
+	// NeedsRehash reports whether a hash was made with a different
+	// algorithm or different parameters from this hasher's.
+	NeedsRehash(hash []byte) bool
+}
+
+// Argon2idHasher hashes passwords with argon2id (RFC 9106), encoded in the
+// PHC string format:
+//
+//	$argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+type Argon2idHasher struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idHasher uses the parameters recommended by the OWASP password
+// storage cheat sheet, with some headroom.
+var DefaultArgon2idHasher = Argon2idHasher{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+var errInvalidArgon2idHash = errors.New("invalid argon2id hash")
+
+func (h Argon2idHasher) Hash(plaintext string) ([]byte, error) {
+	salt := make([]byte, h.SaltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	key := argon2.IDKey([]byte(plaintext), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+
+	encoded := fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key))
+	return []byte(encoded), nil
+}
+
+func (h Argon2idHasher) NeedsRehash(hash []byte) bool {
+	params, salt, key, err := decodeArgon2idHash(hash)
+	if err != nil {
+		return true
+	}
+	return params.Memory != h.Memory ||
+		params.Iterations != h.Iterations ||
+		params.Parallelism != h.Parallelism ||
+		uint32(len(salt)) != h.SaltLength ||
+		uint32(len(key)) != h.KeyLength
+}
+
+// decodeArgon2idHash splits a PHC string into its parameters, salt and key.
+func decodeArgon2idHash(hash []byte) (params Argon2idHasher, salt, key []byte, err error) {
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, errInvalidArgon2idHash
+	}
+
+	var version int
+	_, err = fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return params, nil, nil, errInvalidArgon2idHash
+	}
+
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if err != nil {
+		return params, nil, nil, errInvalidArgon2idHash
+	}
+
+	salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, errInvalidArgon2idHash
+	}
+	key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, errInvalidArgon2idHash
+	}
+	return params, salt, key, nil
+}
+
+// BcryptMaxPasswordLength is the most bcrypt looks at: any bytes after it are
+// ignored.
+const BcryptMaxPasswordLength = 72
+
+// BcryptHasher hashes passwords with bcrypt. bcrypt only looks at the first
+// BcryptMaxPasswordLength bytes of a password, so a policy used with it
+// should not allow longer ones.
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h BcryptHasher) Hash(plaintext string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(plaintext), h.Cost)
+}
+
+func (h BcryptHasher) NeedsRehash(hash []byte) bool {
+	cost, err := bcrypt.Cost(hash)
+	return err != nil || cost != h.Cost
+}
+
+// comparePasswordHash checks a password against a hash made by any of the
+// supported hashers.
+func comparePasswordHash(hash []byte, plaintext string) (bool, error) {
+	if strings.HasPrefix(string(hash), "$argon2id$") {
+		params, salt, key, err := decodeArgon2idHash(hash)
+		if err != nil {
+			return false, err
+		}
+		other := argon2.IDKey([]byte(plaintext), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+		return subtle.ConstantTimeCompare(key, other) == 1, nil
+	}
+
+	err := bcrypt.CompareHashAndPassword(hash, []byte(plaintext))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// The hash CompareDummyPassword checks against, made on first use so that it
+// comes from the configured hasher.
+var (
+	dummyPasswordOnce sync.Once
+	dummyPasswordHash []byte
+)
+
+// CompareDummyPassword does the work of checking a password without there
+// being a user to check it against, so that a login for an unknown email
+// address takes as long as one with the wrong password.
+func CompareDummyPassword(plaintext string) {
+	dummyPasswordOnce.Do(func() {
+		dummyPasswordHash, _ = passwordHasher.Hash("dummy password")
+	})
+	comparePasswordHash(dummyPasswordHash, plaintext)
+}
+
+// PasswordPolicy is what a new password has to satisfy. Blocklist holds
+// lowercased passwords that are too common or known to have been breached.
+type PasswordPolicy struct {
+	MinLength int // bytes
+	MaxLength int // bytes
+	Blocklist map[string]struct{}
+}
+
+// DefaultPasswordPolicy is the policy for the default argon2id hasher. It
+// allows long passphrases while capping how much a single login has to hash.
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength: 8,
+	MaxLength: 256,
+}
+
+// The hasher and policy in use. They are set once at startup, with
+// SetPasswordHasher and SetPasswordPolicy, before anything reads them.
+var (
+	passwordHasher PasswordHasher = DefaultArgon2idHasher
+	passwordPolicy                = DefaultPasswordPolicy
+)
+
+// SetPasswordHasher sets the hasher used for new passwords.
+func SetPasswordHasher(h PasswordHasher) {
+	passwordHasher = h
+}
+
+// SetPasswordPolicy sets the policy new passwords are validated against.
+func SetPasswordPolicy(p PasswordPolicy) {
+	passwordPolicy = p
+}
+
+// LoadPasswordBlocklist reads a blocklist file with one password per line,
+// such as a list of the most common passwords. Blank lines and lines starting
+// with # are ignored, and matching is case-insensitive.
+func LoadPasswordBlocklist(path string) (map[string]struct{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	blocklist := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		blocklist[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return blocklist, nil
+}
+
+// PasswordConfig is the command-line configuration of the password hasher and
+// policy, shared by every command that sets passwords so that they all hash
+// and validate them the same way.
+type PasswordConfig struct {
+	Hasher            string
+	Argon2Memory      uint
+	Argon2Iterations  uint
+	Argon2Parallelism uint
+	BcryptCost        int
+	MinLength         int
+	MaxLength         int
+	BlocklistFile     string
+}
+
+// RegisterFlags defines the -password-* flags on fs.
+func (c *PasswordConfig) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Hasher, "password-hasher", "argon2id", "Hashing algorithm for new passwords (argon2id|bcrypt)")
+	fs.UintVar(&c.Argon2Memory, "password-argon2-memory", uint(DefaultArgon2idHasher.Memory), "argon2id memory in KiB")
+	fs.UintVar(&c.Argon2Iterations, "password-argon2-iterations", uint(DefaultArgon2idHasher.Iterations), "argon2id iterations")
+	fs.UintVar(&c.Argon2Parallelism, "password-argon2-parallelism", uint(DefaultArgon2idHasher.Parallelism), "argon2id parallelism")
+	fs.IntVar(&c.BcryptCost, "password-bcrypt-cost", 12, "bcrypt cost")
+	fs.IntVar(&c.MinLength, "password-min-length", DefaultPasswordPolicy.MinLength, "Minimum password length in bytes")
+	fs.IntVar(&c.MaxLength, "password-max-length", 0, "Maximum password length in bytes (default 256, or 72 with bcrypt, which ignores anything longer)")
+	fs.StringVar(&c.BlocklistFile, "password-blocklist", "", "File of common or breached passwords to reject, one per line")
+}
+
+// Apply checks the configuration, loads the blocklist and sets the hasher and
+// policy used for new passwords.
+func (c PasswordConfig) Apply() error {
+	switch c.Hasher {
+	case "argon2id":
+		hasher := DefaultArgon2idHasher
+		hasher.Memory = uint32(c.Argon2Memory)
+		hasher.Iterations = uint32(c.Argon2Iterations)
+		hasher.Parallelism = uint8(c.Argon2Parallelism)
+		if hasher.Memory < 8*uint32(hasher.Parallelism) || hasher.Iterations < 1 || hasher.Parallelism < 1 {
+			return errors.New("invalid argon2id parameters")
+		}
+		SetPasswordHasher(hasher)
+	case "bcrypt":
+		if c.BcryptCost < bcrypt.MinCost || c.BcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+		if c.MaxLength == 0 {
+			c.MaxLength = BcryptMaxPasswordLength
+		}
+		if c.MaxLength > BcryptMaxPasswordLength {
+			return fmt.Errorf("password-max-length must be at most %d with bcrypt", BcryptMaxPasswordLength)
+		}
+		SetPasswordHasher(BcryptHasher{Cost: c.BcryptCost})
+	default:
+		return fmt.Errorf("unknown password hasher %q", c.Hasher)
+	}
+
+	if c.MaxLength == 0 {
+		c.MaxLength = DefaultPasswordPolicy.MaxLength
+	}
+	if c.MinLength < 1 || c.MaxLength < c.MinLength {
+		return errors.New("invalid password length limits")
+	}
+
+	policy := PasswordPolicy{
+		MinLength: c.MinLength,
+		MaxLength: c.MaxLength,
+	}
+	if c.BlocklistFile != "" {
+		blocklist, err := LoadPasswordBlocklist(c.BlocklistFile)
+		if err != nil {
+			return err
+		}
+		policy.Blocklist = blocklist
+	}
+	SetPasswordPolicy(policy)
+	return nil
+}
+
+// Validate checks a new password against the policy.
+func (p PasswordPolicy) Validate(v *validator.Validator, password string) {
+	v.Check(password != "", "password", "must be provided")
+	v.Check(len(password) >= p.MinLength, "password", fmt.Sprintf("must be at least %d bytes long", p.MinLength))
+	v.Check(len(password) <= p.MaxLength, "password", fmt.Sprintf("must not be more than %d bytes long", p.MaxLength))
+
+	_, blocked := p.Blocklist[strings.ToLower(password)]
+	v.Check(!blocked, "password", "is too common or has appeared in a data breach, please choose another")
+}
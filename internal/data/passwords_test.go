@@ -0,0 +1,96 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/amari03/test1/internal/validator"
+	"github.com/stretchr/testify/require"
+)
+
+// testArgon2idHasher keeps the tests fast.
+var testArgon2idHasher = Argon2idHasher{
+	Memory:      1024,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+func TestArgon2idHasher(t *testing.T) {
+	hash, err := testArgon2idHasher.Hash("correct horse battery staple")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(hash), "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	// Each hash gets its own salt.
+	other, err := testArgon2idHasher.Hash("correct horse battery staple")
+	require.NoError(t, err)
+	require.NotEqual(t, hash, other)
+
+	match, err := comparePasswordHash(hash, "correct horse battery staple")
+	require.NoError(t, err)
+	require.True(t, match)
+
+	match, err = comparePasswordHash(hash, "correct horse battery stapler")
+	require.NoError(t, err)
+	require.False(t, match)
+
+	require.False(t, testArgon2idHasher.NeedsRehash(hash))
+	stronger := testArgon2idHasher
+	stronger.Iterations = 2
+	require.True(t, stronger.NeedsRehash(hash))
+
+	_, err = comparePasswordHash([]byte("$argon2id$v=19$m=1024$bad"), "x")
+	require.Error(t, err)
+}
+
+func TestBcryptHashes(t *testing.T) {
+	bcryptHasher := BcryptHasher{Cost: 4}
+	hash, err := bcryptHasher.Hash("password123")
+	require.NoError(t, err)
+
+	// Existing bcrypt hashes still match, but are upgraded to argon2id.
+	match, err := comparePasswordHash(hash, "password123")
+	require.NoError(t, err)
+	require.True(t, match)
+
+	require.False(t, bcryptHasher.NeedsRehash(hash))
+	require.True(t, BcryptHasher{Cost: 12}.NeedsRehash(hash))
+	require.True(t, testArgon2idHasher.NeedsRehash(hash))
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	err := os.WriteFile(path, []byte("# most common passwords\nPassword123\n\nqwertyuiop\n"), 0o644)
+	require.NoError(t, err)
+
+	blocklist, err := LoadPasswordBlocklist(path)
+	require.NoError(t, err)
+	require.Len(t, blocklist, 2)
+
+	policy := PasswordPolicy{MinLength: 10, MaxLength: 128, Blocklist: blocklist}
+
+	tests := []struct {
+		password string
+		valid    bool
+	}{
+		{"", false},
+		{"short", false},
+		{"password123", false}, // blocked, in any case
+		{"QWERTYUIOP", false},
+		{strings.Repeat("a", 129), false},
+		{strings.Repeat("a", 100), true},
+		{"a perfectly fine passphrase", true},
+	}
+
+	for _, tt := range tests {
+		v := validator.New()
+		policy.Validate(v, tt.password)
+		require.Equal(t, tt.valid, v.Valid(), tt.password)
+	}
+
+	_, err = LoadPasswordBlocklist(filepath.Join(t.TempDir(), "missing.txt"))
+	require.Error(t, err)
+}
@@ -0,0 +1,67 @@
+package data
+
+import "slices"
+
+// Roles a user can hold, from least to most privileged.
+var Roles = []string{"viewer", "contributor", "admin"}
+
+// Permissions is a set of permission codes such as "officers:write".
+type Permissions []string
+
+// Include reports whether the set contains the given code.
+func (p Permissions) Include(code string) bool {
+	return slices.Contains(p, code)
+}
+
+// Viewers can read training records. Contributors can also record training
+// and run imports. Admins manage reference data, compliance rules, users and
+// the job queue, read the audit log, and are the only ones who can hard
+// delete officers, courses or sessions or book over scheduling conflicts.
+var (
+	viewerPermissions = Permissions{
+		"officers:read",
+		"courses:read",
+		"sessions:read",
+		"facilitators:read",
+		"attendance:read",
+		"imports:read",
+		"lookups:read",
+		"compliance:read",
+	}
+
+	contributorPermissions = append(slices.Clone(viewerPermissions),
+		"officers:write",
+		"courses:write",
+		"sessions:write",
+		"facilitators:write",
+		"attendance:write",
+		"imports:write",
+	)
+
+	adminPermissions = append(slices.Clone(contributorPermissions),
+		"officers:delete",
+		"courses:delete",
+		"sessions:delete",
+		"lookups:write",
+		"compliance:write",
+		"users:admin",
+		"jobs:admin",
+		"audit:read",
+		"schedule:override",
+	)
+)
+
+// PermissionsForRole returns the permissions granted by a role. Unknown roles
+// get none.
+func PermissionsForRole(role string) Permissions {
+	switch role {
+	case "viewer":
+		return viewerPermissions
+	case "contributor":
+		return contributorPermissions
+	case "admin":
+		return adminPermissions
+	default:
+		return Permissions{}
+	}
+}
@@ -0,0 +1,37 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermissionsForRole(t *testing.T) {
+	tests := []struct {
+		role    string
+		code    string
+		granted bool
+	}{
+		{"viewer", "officers:read", true},
+		{"viewer", "officers:write", false},
+		{"contributor", "officers:write", true},
+		{"contributor", "officers:delete", false},
+		{"contributor", "lookups:write", false},
+		{"admin", "officers:delete", true},
+		{"contributor", "courses:delete", false},
+		{"admin", "courses:delete", true},
+		{"contributor", "sessions:delete", false},
+		{"admin", "sessions:delete", true},
+		{"admin", "users:admin", true},
+		{"admin", "compliance:read", true},
+		{"contributor", "audit:read", false},
+		{"admin", "audit:read", true},
+		{"contributor", "schedule:override", false},
+		{"admin", "schedule:override", true},
+		{"nobody", "officers:read", false},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.granted, PermissionsForRole(tt.role).Include(tt.code), "%s %s", tt.role, tt.code)
+	}
+}
@@ -0,0 +1,207 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/amari03/test1/internal/validator"
+)
+
+type Posting struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Version int32  `json:"version"`
+}
+
+type PostingModel struct {
+	DB *sql.DB
+}
+
+func ValidatePosting(v *validator.Validator, posting *Posting) {
+	v.Check(posting.ID != "", "id", "must be provided")
+	v.Check(len(posting.ID) <= 50, "id", "must not exceed 50 bytes")
+	v.Check(posting.Name != "", "name", "must be provided")
+	v.Check(len(posting.Name) <= 100, "name", "must not exceed 100 bytes")
+}
+
+// Insert a new posting record. Posting IDs are chosen by the client.
+func (m PostingModel) Insert(actor AuditActor, posting *Posting) error {
+	query := `
+        INSERT INTO postings (id, name)
+        VALUES ($1, $2)
+        RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, posting.ID, posting.Name).Scan(&posting.Version)
+	if err != nil {
+		if isPQError(err, pqUniqueViolation) {
+			return ErrDuplicateRecord
+		}
+		return err
+	}
+
+	err = audit(ctx, tx, actor, AuditActionInsert, "posting", posting.ID, nil, posting)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Get a specific posting by ID.
+func (m PostingModel) Get(id string) (*Posting, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return getPosting(ctx, m.DB, id)
+}
+
+// getPosting fetches a posting using q, which may be a transaction.
+func getPosting(ctx context.Context, q queryer, id string) (*Posting, error) {
+	query := `
+        SELECT id, name, version
+        FROM postings
+        WHERE id = $1`
+
+	var posting Posting
+	err := q.QueryRowContext(ctx, query, id).Scan(&posting.ID, &posting.Name, &posting.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &posting, nil
+}
+
+// Update a specific posting record. The ID itself cannot be changed.
+func (m PostingModel) Update(actor AuditActor, posting *Posting) error {
+	query := `
+        UPDATE postings
+        SET name = $1, version = version + 1
+        WHERE id = $2 AND version = $3
+        RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getPosting(ctx, tx, posting.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRecordNotFound):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, query, posting.Name, posting.ID, posting.Version).Scan(&posting.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	err = audit(ctx, tx, actor, AuditActionUpdate, "posting", posting.ID, before, posting)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete a specific posting by ID. Officers, sessions and facilitators
+// assigned to the posting are unassigned by the database.
+func (m PostingModel) Delete(actor AuditActor, id string) error {
+	if id == "" {
+		return ErrRecordNotFound
+	}
+	query := `
+        DELETE FROM postings
+        WHERE id = $1
+        RETURNING id, name, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var posting Posting
+	err = tx.QueryRowContext(ctx, query, id).Scan(&posting.ID, &posting.Name, &posting.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	err = audit(ctx, tx, actor, AuditActionDelete, "posting", posting.ID, &posting, nil)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetAll returns a paginated list of postings, filterable by name.
+func (m PostingModel) GetAll(name string, filters Filters) ([]*Posting, Metadata, error) {
+	query := fmt.Sprintf(`
+        SELECT count(*) OVER(), id, name, version
+        FROM postings
+        WHERE (name ILIKE '%%' || $1 || '%%' OR $1 = '')
+        ORDER BY %s %s, id ASC
+        LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, name, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := int64(0)
+	postings := []*Posting{}
+
+	for rows.Next() {
+		var posting Posting
+		err := rows.Scan(&totalRecords, &posting.ID, &posting.Name, &posting.Version)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		postings = append(postings, &posting)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return postings, metadata, nil
+}
@@ -0,0 +1,215 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/amari03/test1/internal/validator"
+)
+
+type Rank struct {
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+	Version int32  `json:"version"`
+}
+
+type RankModel struct {
+	DB *sql.DB
+}
+
+func ValidateRank(v *validator.Validator, rank *Rank) {
+	v.Check(rank.Code != "", "code", "must be provided")
+	v.Check(len(rank.Code) <= 20, "code", "must not exceed 20 bytes")
+	v.Check(rank.Name != "", "name", "must be provided")
+	v.Check(len(rank.Name) <= 100, "name", "must not exceed 100 bytes")
+}
+
+// Insert a new rank record.
+func (m RankModel) Insert(actor AuditActor, rank *Rank) error {
+	query := `
+        INSERT INTO ranks (code, name)
+        VALUES ($1, $2)
+        RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, rank.Code, rank.Name).Scan(&rank.Version)
+	if err != nil {
+		if isPQError(err, pqUniqueViolation) {
+			return ErrDuplicateRecord
+		}
+		return err
+	}
+
+	err = audit(ctx, tx, actor, AuditActionInsert, "rank", rank.Code, nil, rank)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Get a specific rank by code.
+func (m RankModel) Get(code string) (*Rank, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return getRank(ctx, m.DB, code)
+}
+
+// getRank fetches a rank using q, which may be a transaction.
+func getRank(ctx context.Context, q queryer, code string) (*Rank, error) {
+	query := `
+        SELECT code, name, version
+        FROM ranks
+        WHERE code = $1`
+
+	var rank Rank
+	err := q.QueryRowContext(ctx, query, code).Scan(&rank.Code, &rank.Name, &rank.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &rank, nil
+}
+
+// Update a specific rank record. The code itself cannot be changed.
+func (m RankModel) Update(actor AuditActor, rank *Rank) error {
+	query := `
+        UPDATE ranks
+        SET name = $1, version = version + 1
+        WHERE code = $2 AND version = $3
+        RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getRank(ctx, tx, rank.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRecordNotFound):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, query, rank.Name, rank.Code, rank.Version).Scan(&rank.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	err = audit(ctx, tx, actor, AuditActionUpdate, "rank", rank.Code, before, rank)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete a specific rank by code, along with any compliance requirements
+// for it. Ranks that are still held by officers cannot be deleted and
+// return ErrRecordInUse.
+func (m RankModel) Delete(actor AuditActor, code string) error {
+	if code == "" {
+		return ErrRecordNotFound
+	}
+	query := `
+        DELETE FROM ranks
+        WHERE code = $1
+        RETURNING code, name, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = deleteComplianceRequirements(ctx, tx, actor, "rank_code = $1", code)
+	if err != nil {
+		return err
+	}
+
+	var rank Rank
+	err = tx.QueryRowContext(ctx, query, code).Scan(&rank.Code, &rank.Name, &rank.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		case isPQError(err, pqForeignKeyViolation):
+			return ErrRecordInUse
+		default:
+			return err
+		}
+	}
+
+	err = audit(ctx, tx, actor, AuditActionDelete, "rank", rank.Code, &rank, nil)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetAll returns a paginated list of ranks, filterable by name.
+func (m RankModel) GetAll(name string, filters Filters) ([]*Rank, Metadata, error) {
+	query := fmt.Sprintf(`
+        SELECT count(*) OVER(), code, name, version
+        FROM ranks
+        WHERE (name ILIKE '%%' || $1 || '%%' OR $1 = '')
+        ORDER BY %s %s, code ASC
+        LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, name, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := int64(0)
+	ranks := []*Rank{}
+
+	for rows.Next() {
+		var rank Rank
+		err := rows.Scan(&totalRecords, &rank.Code, &rank.Name, &rank.Version)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		ranks = append(ranks, &rank)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return ranks, metadata, nil
+}
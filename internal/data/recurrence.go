@@ -0,0 +1,167 @@
+package data
+
+import (
+	"slices"
+	"time"
+
+	"github.com/amari03/test1/internal/validator"
+)
+
+// Recurrence frequencies, as in RFC 5545's FREQ.
+const (
+	FrequencyDaily   = "daily"
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+)
+
+// MaxSeriesOccurrences is the most sessions one series can create. Series are
+// materialized up front, so they have to end, and within reason.
+const MaxSeriesOccurrences = 200
+
+// weekdays maps RFC 5545's BYDAY codes to weekdays.
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// RecurrenceRule says when a series repeats, after the style of an RFC 5545
+// RRULE: every Interval days, weeks or months, on the days in ByDay for a
+// weekly rule, until a time or for a number of occurrences. Weeks start on
+// Monday, and a monthly rule repeats on the first occurrence's day of the
+// month, skipping months that don't have it.
+type RecurrenceRule struct {
+	Frequency string     `json:"freq"`
+	Interval  int        `json:"interval"`
+	ByDay     []string   `json:"by_day,omitempty"`
+	Until     *time.Time `json:"until,omitempty"`
+	Count     *int       `json:"count,omitempty"`
+}
+
+// ValidateRecurrenceRule checks a rule for a series whose first occurrence
+// starts at start.
+func ValidateRecurrenceRule(v *validator.Validator, rule RecurrenceRule, start time.Time) {
+	v.Check(validator.In(rule.Frequency, FrequencyDaily, FrequencyWeekly, FrequencyMonthly), "freq", "must be daily, weekly or monthly")
+	v.Check(rule.Interval > 0, "interval", "must be greater than zero")
+	v.Check(rule.Interval <= 99, "interval", "must not be more than 99")
+
+	if len(rule.ByDay) > 0 {
+		v.Check(rule.Frequency == FrequencyWeekly, "by_day", "can only be used with a weekly rule")
+		seen := make(map[string]bool)
+		for _, day := range rule.ByDay {
+			_, ok := weekdays[day]
+			v.Check(ok, "by_day", "must only contain MO, TU, WE, TH, FR, SA or SU")
+			v.Check(!seen[day], "by_day", "must not contain duplicate days")
+			seen[day] = true
+		}
+	}
+
+	v.Check(rule.Until != nil || rule.Count != nil, "until", "until or count must be provided")
+	v.Check(rule.Until == nil || rule.Count == nil, "until", "must not be provided with count")
+	if rule.Until != nil {
+		v.Check(!rule.Until.Before(start), "until", "must not be before start_datetime")
+	}
+	if rule.Count != nil {
+		v.Check(*rule.Count > 0, "count", "must be greater than zero")
+		v.Check(*rule.Count <= MaxSeriesOccurrences, "count", "must not be more than 200")
+	}
+}
+
+// Occurrences returns the start of each occurrence of a series that starts
+// at start, in order, counting start itself if it matches the rule. Times
+// of day are kept in start's location, so a weekly 9am session stays at 9am
+// when the clocks change. At most MaxSeriesOccurrences+1 are returned, so
+// that a rule that produces too many can be told apart, and none if the rule
+// is invalid.
+func (rule RecurrenceRule) Occurrences(start time.Time) []time.Time {
+	if rule.Interval < 1 || (rule.Until == nil && rule.Count == nil) {
+		return nil
+	}
+
+	limit := MaxSeriesOccurrences + 1
+	if rule.Count != nil && *rule.Count < limit {
+		limit = *rule.Count
+	}
+
+	year, month, day := start.Date()
+	hour, minute, sec := start.Clock()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, hour, minute, sec, start.Nanosecond(), start.Location())
+	}
+
+	// Each period (a day, a week or a month, times the interval) yields zero
+	// or more candidates. A monthly rule can skip a few months in a row, for
+	// the 31st, but never a whole year's worth of periods.
+	var candidates func(period int) []time.Time
+	switch rule.Frequency {
+	case FrequencyDaily:
+		candidates = func(period int) []time.Time {
+			return []time.Time{at(year, month, day+period*rule.Interval)}
+		}
+	case FrequencyWeekly:
+		days := []time.Weekday{start.Weekday()}
+		if len(rule.ByDay) > 0 {
+			days = days[:0]
+			for _, code := range rule.ByDay {
+				days = append(days, weekdays[code])
+			}
+		}
+		// Days from Monday, so that a week's candidates come out in order.
+		var offsets []int
+		for offset := 0; offset < 7; offset++ {
+			if slices.Contains(days, (time.Monday+time.Weekday(offset))%7) {
+				offsets = append(offsets, offset)
+			}
+		}
+		monday := day - (int(start.Weekday())+6)%7
+		candidates = func(period int) []time.Time {
+			times := make([]time.Time, 0, len(offsets))
+			for _, offset := range offsets {
+				times = append(times, at(year, month, monday+period*7*rule.Interval+offset))
+			}
+			return times
+		}
+	case FrequencyMonthly:
+		candidates = func(period int) []time.Time {
+			first := at(year, month+time.Month(period*rule.Interval), 1)
+			if daysIn(first.Year(), first.Month()) < day {
+				return nil
+			}
+			return []time.Time{at(first.Year(), first.Month(), day)}
+		}
+	default:
+		return nil
+	}
+
+	var occurrences []time.Time
+	for period, misses := 0, 0; len(occurrences) < limit && misses < 12; period++ {
+		times := candidates(period)
+		if len(times) == 0 {
+			misses++
+			continue
+		}
+		misses = 0
+		for _, t := range times {
+			if t.Before(start) {
+				continue
+			}
+			if rule.Until != nil && t.After(*rule.Until) {
+				return occurrences
+			}
+			occurrences = append(occurrences, t)
+			if len(occurrences) == limit {
+				break
+			}
+		}
+	}
+	return occurrences
+}
+
+// daysIn returns the number of days in a month.
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
@@ -0,0 +1,106 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/amari03/test1/internal/validator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecurrenceRule_Occurrences(t *testing.T) {
+	// Monday 3 November 2025, 9am.
+	start := time.Date(2025, time.November, 3, 9, 0, 0, 0, time.UTC)
+	day := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 9, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name  string
+		rule  RecurrenceRule
+		start time.Time
+		want  []time.Time
+	}{
+		{
+			name:  "daily",
+			rule:  RecurrenceRule{Frequency: FrequencyDaily, Interval: 2, Count: ptr(3)},
+			start: start,
+			want:  []time.Time{day(2025, 11, 3), day(2025, 11, 5), day(2025, 11, 7)},
+		},
+		{
+			name:  "weekly on the start's day",
+			rule:  RecurrenceRule{Frequency: FrequencyWeekly, Interval: 1, Until: ptr(day(2025, 11, 17))},
+			start: start,
+			want:  []time.Time{day(2025, 11, 3), day(2025, 11, 10), day(2025, 11, 17)},
+		},
+		{
+			name:  "every other week on two days",
+			rule:  RecurrenceRule{Frequency: FrequencyWeekly, Interval: 2, ByDay: []string{"FR", "MO"}, Count: ptr(4)},
+			start: start,
+			want:  []time.Time{day(2025, 11, 3), day(2025, 11, 7), day(2025, 11, 17), day(2025, 11, 21)},
+		},
+		{
+			name:  "weekly days before the start are skipped",
+			rule:  RecurrenceRule{Frequency: FrequencyWeekly, Interval: 1, ByDay: []string{"MO", "SU"}, Count: ptr(3)},
+			start: day(2025, 11, 5),
+			want:  []time.Time{day(2025, 11, 9), day(2025, 11, 10), day(2025, 11, 16)},
+		},
+		{
+			name:  "monthly skips months without the day",
+			rule:  RecurrenceRule{Frequency: FrequencyMonthly, Interval: 1, Count: ptr(3)},
+			start: day(2026, 1, 31),
+			want:  []time.Time{day(2026, 1, 31), day(2026, 3, 31), day(2026, 5, 31)},
+		},
+		{
+			name:  "until before the second occurrence",
+			rule:  RecurrenceRule{Frequency: FrequencyMonthly, Interval: 3, Until: ptr(day(2026, 2, 1))},
+			start: start,
+			want:  []time.Time{day(2025, 11, 3)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.rule.Occurrences(tt.start))
+		})
+	}
+
+	// The time of day stays put across a change of clocks.
+	ny, err := time.LoadLocation("America/New_York")
+	if err == nil {
+		rule := RecurrenceRule{Frequency: FrequencyWeekly, Interval: 1, Count: ptr(2)}
+		occurrences := rule.Occurrences(time.Date(2025, time.October, 27, 9, 0, 0, 0, ny))
+		require.Len(t, occurrences, 2)
+		require.Equal(t, 9, occurrences[1].Hour())
+		require.Equal(t, 3, occurrences[1].Day())
+	}
+
+	// A rule that never ends is cut off one past the limit.
+	rule := RecurrenceRule{Frequency: FrequencyDaily, Interval: 1, Until: ptr(start.AddDate(5, 0, 0))}
+	require.Len(t, rule.Occurrences(start), MaxSeriesOccurrences+1)
+}
+
+func TestValidateRecurrenceRule(t *testing.T) {
+	start := time.Date(2025, time.November, 3, 9, 0, 0, 0, time.UTC)
+
+	v := validator.New()
+	ValidateRecurrenceRule(v, RecurrenceRule{Frequency: FrequencyWeekly, Interval: 1, ByDay: []string{"MO", "TH"}, Count: ptr(10)}, start)
+	require.True(t, v.Valid())
+
+	v = validator.New()
+	ValidateRecurrenceRule(v, RecurrenceRule{Frequency: "yearly", Interval: 0}, start)
+	require.Contains(t, v.Errors, "freq")
+	require.Contains(t, v.Errors, "interval")
+	require.Contains(t, v.Errors, "until")
+
+	v = validator.New()
+	ValidateRecurrenceRule(v, RecurrenceRule{Frequency: FrequencyDaily, Interval: 1, ByDay: []string{"MO"}, Until: ptr(start), Count: ptr(MaxSeriesOccurrences + 1)}, start)
+	require.Contains(t, v.Errors, "by_day")
+	require.Contains(t, v.Errors, "until")
+	require.Contains(t, v.Errors, "count")
+
+	v = validator.New()
+	ValidateRecurrenceRule(v, RecurrenceRule{Frequency: FrequencyWeekly, Interval: 1, ByDay: []string{"MO", "XX"}, Until: ptr(start.Add(-time.Hour))}, start)
+	require.Contains(t, v.Errors, "by_day")
+	require.Contains(t, v.Errors, "until")
+}
@@ -0,0 +1,209 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/amari03/test1/internal/validator"
+)
+
+type Region struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Version int32  `json:"version"`
+}
+
+type RegionModel struct {
+	DB *sql.DB
+}
+
+func ValidateRegion(v *validator.Validator, region *Region) {
+	v.Check(region.ID != "", "id", "must be provided")
+	v.Check(len(region.ID) <= 50, "id", "must not exceed 50 bytes")
+	v.Check(region.Name != "", "name", "must be provided")
+	v.Check(len(region.Name) <= 100, "name", "must not exceed 100 bytes")
+}
+
+// Insert a new region record. Region IDs are chosen by the client.
+func (m RegionModel) Insert(actor AuditActor, region *Region) error {
+	query := `
+        INSERT INTO regions (id, name)
+        VALUES ($1, $2)
+        RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, region.ID, region.Name).Scan(&region.Version)
+	if err != nil {
+		if isPQError(err, pqUniqueViolation) {
+			return ErrDuplicateRecord
+		}
+		return err
+	}
+
+	err = audit(ctx, tx, actor, AuditActionInsert, "region", region.ID, nil, region)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Get a specific region by ID.
+func (m RegionModel) Get(id string) (*Region, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return getRegion(ctx, m.DB, id)
+}
+
+// getRegion fetches a region using q, which may be a transaction.
+func getRegion(ctx context.Context, q queryer, id string) (*Region, error) {
+	query := `
+        SELECT id, name, version
+        FROM regions
+        WHERE id = $1`
+
+	var region Region
+	err := q.QueryRowContext(ctx, query, id).Scan(&region.ID, &region.Name, &region.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &region, nil
+}
+
+// Update a specific region record. The ID itself cannot be changed.
+func (m RegionModel) Update(actor AuditActor, region *Region) error {
+	query := `
+        UPDATE regions
+        SET name = $1, version = version + 1
+        WHERE id = $2 AND version = $3
+        RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getRegion(ctx, tx, region.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRecordNotFound):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, query, region.Name, region.ID, region.Version).Scan(&region.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	err = audit(ctx, tx, actor, AuditActionUpdate, "region", region.ID, before, region)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete a specific region by ID. Regions that still have formations
+// cannot be deleted and return ErrRecordInUse.
+func (m RegionModel) Delete(actor AuditActor, id string) error {
+	if id == "" {
+		return ErrRecordNotFound
+	}
+	query := `
+        DELETE FROM regions
+        WHERE id = $1
+        RETURNING id, name, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var region Region
+	err = tx.QueryRowContext(ctx, query, id).Scan(&region.ID, &region.Name, &region.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		case isPQError(err, pqForeignKeyViolation):
+			return ErrRecordInUse
+		default:
+			return err
+		}
+	}
+
+	err = audit(ctx, tx, actor, AuditActionDelete, "region", region.ID, &region, nil)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetAll returns a paginated list of regions, filterable by name.
+func (m RegionModel) GetAll(name string, filters Filters) ([]*Region, Metadata, error) {
+	query := fmt.Sprintf(`
+        SELECT count(*) OVER(), id, name, version
+        FROM regions
+        WHERE (name ILIKE '%%' || $1 || '%%' OR $1 = '')
+        ORDER BY %s %s, id ASC
+        LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, name, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := int64(0)
+	regions := []*Region{}
+
+	for rows.Next() {
+		var region Region
+		err := rows.Scan(&totalRecords, &region.ID, &region.Name, &region.Version)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		regions = append(regions, &region)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return regions, metadata, nil
+}
@@ -0,0 +1,228 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Kinds of scheduling conflict: two sessions in the same place at once, or a
+// facilitator or officer booked into two sessions at once.
+const (
+	ConflictVenue       = "venue"
+	ConflictFacilitator = "facilitator"
+	ConflictOfficer     = "officer"
+)
+
+// ScheduleConflict is another session that overlaps the one being booked,
+// and why it clashes: it is at the same venue, or the facilitator or officer
+// being booked is already in it. When a series is booked, OccurrenceStart
+// says which of its sessions clashes.
+type ScheduleConflict struct {
+	Type            string     `json:"type"`
+	SessionID       string     `json:"session_id"`
+	Start           time.Time  `json:"start_datetime"`
+	End             time.Time  `json:"end_datetime"`
+	Location        string     `json:"location_text"`
+	FacilitatorID   *string    `json:"facilitator_id,omitempty"`
+	OfficerID       *string    `json:"officer_id,omitempty"`
+	OccurrenceStart *time.Time `json:"occurrence_start,omitempty"`
+}
+
+// ScheduleConflictError is returned when a write would double-book a venue,
+// facilitator or officer. The models that check for conflicts can be told to
+// go ahead anyway, in which case they return the conflicts they overrode
+// instead.
+type ScheduleConflictError struct {
+	Conflicts []ScheduleConflict
+}
+
+func (e *ScheduleConflictError) Error() string {
+	return fmt.Sprintf("%d scheduling conflicts", len(e.Conflicts))
+}
+
+// scheduleLockKey is the advisory lock taken while checking for conflicts.
+// Bookings are checked one at a time, so two overlapping ones made at the
+// same moment can't both get through.
+const scheduleLockKey = 0x5c4ed01e
+
+// lockSchedule takes the schedule lock until the end of tx. It must be taken
+// before any row locks, so that it is always taken in the same order.
+func lockSchedule(ctx context.Context, q queryer) error {
+	_, err := q.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, scheduleLockKey)
+	return err
+}
+
+// scheduleCheck describes a booking to check: a session's time, place and
+// status, and the facilitators and officers who will be in it. SessionID is
+// left out of the results, and is empty for a session that doesn't exist yet.
+type scheduleCheck struct {
+	SessionID      string
+	Start          time.Time
+	End            time.Time
+	Location       string
+	Status         string
+	FacilitatorIDs []string
+	OfficerIDs     []string
+}
+
+// holdsSchedule reports whether a session with the given status takes up its
+// time and place. Cancelled and postponed sessions don't, so they never clash.
+func holdsSchedule(status string) bool {
+	return status != SessionStatusCancelled && status != SessionStatusPostponed
+}
+
+// Sessions overlap if each starts before the other ends, so back-to-back
+// sessions don't clash.
+const overlapsCheck = `s.id::text <> $1 AND s.start_datetime < $3 AND s.end_datetime > $2
+                  AND s.status NOT IN ('cancelled', 'postponed')`
+
+// findScheduleConflicts returns the sessions that clash with a booking,
+// earliest first. Only the checks with something to compare are run: no
+// venue check without a location, and so on.
+func findScheduleConflicts(ctx context.Context, q queryer, check scheduleCheck) ([]ScheduleConflict, error) {
+	var queries []string
+	args := []interface{}{check.SessionID, check.Start, check.End}
+
+	if check.Location != "" {
+		args = append(args, check.Location)
+		queries = append(queries, fmt.Sprintf(`
+            SELECT 'venue', s.id, s.start_datetime, s.end_datetime, COALESCE(s.location_text, ''), NULL::uuid, NULL::uuid
+            FROM sessions s
+            WHERE %s AND lower(trim(s.location_text)) = lower(trim($%d))`, overlapsCheck, len(args)))
+	}
+
+	if len(check.FacilitatorIDs) > 0 {
+		args = append(args, pq.Array(check.FacilitatorIDs))
+		queries = append(queries, fmt.Sprintf(`
+            SELECT 'facilitator', s.id, s.start_datetime, s.end_datetime, COALESCE(s.location_text, ''), sf.facilitator_id, NULL::uuid
+            FROM session_facilitators sf
+            INNER JOIN sessions s ON s.id = sf.session_id
+            WHERE %s AND sf.facilitator_id = ANY($%d::uuid[])`, overlapsCheck, len(args)))
+	}
+
+	// An officer is in a session if they are enrolled in it or have
+	// attendance recorded for it. Being waitlisted doesn't count.
+	if len(check.OfficerIDs) > 0 {
+		args = append(args, pq.Array(check.OfficerIDs))
+		queries = append(queries, fmt.Sprintf(`
+            SELECT 'officer', s.id, s.start_datetime, s.end_datetime, COALESCE(s.location_text, ''), NULL::uuid, o.officer_id
+            FROM (
+                SELECT officer_id, session_id FROM attendance
+                UNION
+                SELECT officer_id, session_id FROM session_enrollments WHERE status = 'enrolled'
+            ) o
+            INNER JOIN sessions s ON s.id = o.session_id
+            WHERE %s AND o.officer_id = ANY($%d::uuid[])`, overlapsCheck, len(args)))
+	}
+
+	conflicts := []ScheduleConflict{}
+	if len(queries) == 0 {
+		return conflicts, nil
+	}
+
+	query := strings.Join(queries, "\n            UNION ALL") + "\n            ORDER BY 3, 1, 2"
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var conflict ScheduleConflict
+		err := rows.Scan(
+			&conflict.Type,
+			&conflict.SessionID,
+			&conflict.Start,
+			&conflict.End,
+			&conflict.Location,
+			&conflict.FacilitatorID,
+			&conflict.OfficerID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		conflicts = append(conflicts, conflict)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return conflicts, nil
+}
+
+// checkSchedule looks for conflicts with a booking; the caller must hold the
+// schedule lock. Unless force is set, any conflicts are returned as a
+// *ScheduleConflictError; otherwise they are returned for the caller to
+// record that they were overridden.
+func checkSchedule(ctx context.Context, q queryer, check scheduleCheck, force bool) ([]ScheduleConflict, error) {
+	if !holdsSchedule(check.Status) {
+		return []ScheduleConflict{}, nil
+	}
+
+	conflicts, err := findScheduleConflicts(ctx, q, check)
+	if err != nil {
+		return nil, err
+	}
+	if len(conflicts) > 0 && !force {
+		return nil, &ScheduleConflictError{Conflicts: conflicts}
+	}
+	return conflicts, nil
+}
+
+// sessionSchedule returns the check for an existing session's time, place and
+// status, locking its row until the end of the transaction.
+func sessionSchedule(ctx context.Context, q queryer, sessionID string) (scheduleCheck, error) {
+	check := scheduleCheck{SessionID: sessionID}
+
+	err := q.QueryRowContext(ctx, `
+        SELECT start_datetime, end_datetime, COALESCE(location_text, ''), status
+        FROM sessions
+        WHERE id = $1
+        FOR UPDATE`, sessionID).Scan(&check.Start, &check.End, &check.Location, &check.Status)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return check, ErrRecordNotFound
+		default:
+			return check, err
+		}
+	}
+	return check, nil
+}
+
+// sessionParticipants returns the facilitators assigned to a session and the
+// officers in it, so that they can be checked when the session moves.
+func sessionParticipants(ctx context.Context, q queryer, sessionID string) (facilitatorIDs, officerIDs []string, err error) {
+	query := `
+        SELECT 'facilitator', facilitator_id FROM session_facilitators WHERE session_id = $1
+        UNION
+        SELECT 'officer', officer_id FROM attendance WHERE session_id = $1
+        UNION
+        SELECT 'officer', officer_id FROM session_enrollments WHERE session_id = $1 AND status = 'enrolled'`
+
+	rows, err := q.QueryContext(ctx, query, sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var kind, id string
+		if err := rows.Scan(&kind, &id); err != nil {
+			return nil, nil, err
+		}
+		if kind == ConflictFacilitator {
+			facilitatorIDs = append(facilitatorIDs, id)
+		} else {
+			officerIDs = append(officerIDs, id)
+		}
+	}
+	return facilitatorIDs, officerIDs, rows.Err()
+}
@@ -0,0 +1,466 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/amari03/test1/internal/validator"
+)
+
+// Enrollment statuses. Officers are enrolled while a session has room and
+// waitlisted once it is full.
+const (
+	EnrollmentStatusEnrolled   = "enrolled"
+	EnrollmentStatusWaitlisted = "waitlisted"
+)
+
+// SessionEnrollment records that an officer is expected at a session.
+// WaitlistPosition is only set for waitlisted officers, starting at 1 for the
+// next in line.
+type SessionEnrollment struct {
+	ID               string     `json:"id"`
+	SessionID        string     `json:"session_id"`
+	OfficerID        string     `json:"officer_id"`
+	Status           string     `json:"status"`
+	WaitlistPosition *int       `json:"waitlist_position,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        *time.Time `json:"updated_at,omitempty"`
+	Version          int32      `json:"version"`
+}
+
+type SessionEnrollmentModel struct {
+	DB *sql.DB
+}
+
+func ValidateSessionEnrollment(v *validator.Validator, enrollment *SessionEnrollment) {
+	v.Check(enrollment.SessionID != "", "session_id", "must be provided")
+	v.Check(enrollment.OfficerID != "", "officer_id", "must be provided")
+}
+
+// enrollmentsWithPositions numbers each session's waitlist in the order
+// officers joined it.
+const enrollmentsWithPositions = `
+        SELECT id, session_id, officer_id, status,
+               CASE WHEN status = 'waitlisted'
+                    THEN row_number() OVER (PARTITION BY session_id, status ORDER BY created_at, id)
+               END AS waitlist_position,
+               created_at, updated_at, version
+        FROM session_enrollments`
+
+// Enroll adds an officer to a session, or to its waitlist if the session is
+// full or others are already waiting. The session row is locked while this
+// happens, so concurrent enrollments can't overfill it. It returns
+// ErrRecordNotFound if the session doesn't exist, ErrEnrollmentClosed if it
+// isn't scheduled or postponed, and ErrDuplicateRecord if the officer is
+// already enrolled or waitlisted.
+//
+// Officers can't be enrolled in, or waitlisted for, a session that clashes
+// with one they are already in: that returns a *ScheduleConflictError,
+// unless force is set, in which case the override is recorded in the audit
+// log.
+func (m SessionEnrollmentModel) Enroll(actor AuditActor, enrollment *SessionEnrollment, force bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockSchedule(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	capacity, err := lockSessionCapacity(ctx, tx, enrollment.SessionID)
+	if err != nil {
+		return err
+	}
+
+	check, err := sessionSchedule(ctx, tx, enrollment.SessionID)
+	if err != nil {
+		return err
+	}
+	if !takesEnrollments(check.Status) {
+		return ErrEnrollmentClosed
+	}
+	check.Location = ""
+	check.OfficerIDs = []string{enrollment.OfficerID}
+
+	conflicts, err := checkSchedule(ctx, tx, check, force)
+	if err != nil {
+		return err
+	}
+
+	var enrolled, waitlisted int
+	err = tx.QueryRowContext(ctx, `
+        SELECT count(*) FILTER (WHERE status = 'enrolled'), count(*) FILTER (WHERE status = 'waitlisted')
+        FROM session_enrollments
+        WHERE session_id = $1`, enrollment.SessionID).Scan(&enrolled, &waitlisted)
+	if err != nil {
+		return err
+	}
+
+	// Nobody jumps the queue, even if a place has come free and the waitlist
+	// hasn't been promoted yet.
+	enrollment.Status = EnrollmentStatusEnrolled
+	if waitlisted > 0 || (capacity != nil && enrolled >= *capacity) {
+		enrollment.Status = EnrollmentStatusWaitlisted
+		position := waitlisted + 1
+		enrollment.WaitlistPosition = &position
+	}
+
+	// created_at orders the waitlist, so it is taken now that the lock is
+	// held rather than when the transaction started.
+	query := `
+        INSERT INTO session_enrollments (session_id, officer_id, status, created_at)
+        VALUES ($1, $2, $3, clock_timestamp())
+        RETURNING id, created_at, version`
+
+	err = tx.QueryRowContext(ctx, query, enrollment.SessionID, enrollment.OfficerID, enrollment.Status).Scan(&enrollment.ID, &enrollment.CreatedAt, &enrollment.Version)
+	if err != nil {
+		if isPQError(err, pqUniqueViolation) {
+			return ErrDuplicateRecord
+		}
+		return err
+	}
+
+	err = audit(ctx, tx, actor, AuditActionInsert, "session_enrollment", enrollment.ID, nil, enrollment)
+	if err != nil {
+		return err
+	}
+	err = auditOverride(ctx, tx, actor, "session_enrollment", enrollment.ID, conflicts)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Get returns a specific enrollment.
+func (m SessionEnrollmentModel) Get(id string) (*SessionEnrollment, error) {
+	query := `SELECT * FROM (` + enrollmentsWithPositions + `) e WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var enrollment SessionEnrollment
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&enrollment.ID,
+		&enrollment.SessionID,
+		&enrollment.OfficerID,
+		&enrollment.Status,
+		&enrollment.WaitlistPosition,
+		&enrollment.CreatedAt,
+		&enrollment.UpdatedAt,
+		&enrollment.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &enrollment, nil
+}
+
+// Withdraw removes an enrollment and, if that frees a place, promotes
+// officers from the waitlist into it. It returns the promoted enrollments.
+func (m SessionEnrollmentModel) Withdraw(actor AuditActor, enrollment *SessionEnrollment) ([]*SessionEnrollment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = lockSchedule(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = lockSessionCapacity(ctx, tx, enrollment.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := deleteSessionEnrollments(ctx, tx, actor, "id = $1", enrollment.ID)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	promoted, err := promoteWaitlisted(ctx, tx, actor, enrollment.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return promoted, nil
+}
+
+// PromoteWaitlisted fills any free places in a session from its waitlist,
+// for example after its capacity has been raised. It returns the promoted
+// enrollments.
+func (m SessionEnrollmentModel) PromoteWaitlisted(actor AuditActor, sessionID string) ([]*SessionEnrollment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = lockSchedule(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = lockSessionCapacity(ctx, tx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	promoted, err := promoteWaitlisted(ctx, tx, actor, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return promoted, nil
+}
+
+// takesEnrollments reports whether officers can enroll in a session with the
+// given status: only one that hasn't started, been cancelled or finished.
+func takesEnrollments(status string) bool {
+	return status == SessionStatusScheduled || status == SessionStatusPostponed
+}
+
+// lockSessionCapacity locks a session's row until the end of tx and returns
+// its capacity. Every change to a session's enrollments takes this lock
+// first, so they happen one at a time.
+func lockSessionCapacity(ctx context.Context, tx *sql.Tx, sessionID string) (*int, error) {
+	var capacity *int
+	err := tx.QueryRowContext(ctx, `SELECT capacity FROM sessions WHERE id = $1 FOR UPDATE`, sessionID).Scan(&capacity)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return capacity, nil
+}
+
+// promoteWaitlisted enrolls as many waitlisted officers as there are free
+// places, longest waiting first, and audits each promotion. A session without
+// a capacity promotes everyone. Officers are checked for clashes the same way
+// as when they enroll; anyone now booked into an overlapping session is passed
+// over and keeps their place on the waitlist. The caller must hold the
+// schedule lock.
+func promoteWaitlisted(ctx context.Context, tx *sql.Tx, actor AuditActor, sessionID string) ([]*SessionEnrollment, error) {
+	check, err := sessionSchedule(ctx, tx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	check.Location = ""
+
+	var capacity *int
+	var enrolled int
+	err = tx.QueryRowContext(ctx, `
+        SELECT s.capacity, count(e.id)
+        FROM sessions s
+        LEFT JOIN session_enrollments e ON e.session_id = s.id AND e.status = 'enrolled'
+        WHERE s.id = $1
+        GROUP BY s.capacity`, sessionID).Scan(&capacity, &enrolled)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+        SELECT id, officer_id FROM session_enrollments
+        WHERE session_id = $1 AND status = 'waitlisted'
+        ORDER BY created_at, id`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var waiting []SessionEnrollment
+	for rows.Next() {
+		var enrollment SessionEnrollment
+		err := rows.Scan(&enrollment.ID, &enrollment.OfficerID)
+		if err != nil {
+			return nil, err
+		}
+		waiting = append(waiting, enrollment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	query := `
+        UPDATE session_enrollments
+        SET status = 'enrolled', updated_at = NOW(), version = version + 1
+        WHERE id = $1
+        RETURNING id, session_id, officer_id, status, created_at, updated_at, version`
+
+	promoted := []*SessionEnrollment{}
+	for _, candidate := range waiting {
+		if capacity != nil && enrolled+len(promoted) >= *capacity {
+			break
+		}
+
+		check.OfficerIDs = []string{candidate.OfficerID}
+		_, err := checkSchedule(ctx, tx, check, false)
+		if err != nil {
+			var conflictErr *ScheduleConflictError
+			if errors.As(err, &conflictErr) {
+				continue
+			}
+			return nil, err
+		}
+
+		var enrollment SessionEnrollment
+		err = tx.QueryRowContext(ctx, query, candidate.ID).Scan(
+			&enrollment.ID,
+			&enrollment.SessionID,
+			&enrollment.OfficerID,
+			&enrollment.Status,
+			&enrollment.CreatedAt,
+			&enrollment.UpdatedAt,
+			&enrollment.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		before := enrollment
+		before.Status = EnrollmentStatusWaitlisted
+		before.Version--
+		err = audit(ctx, tx, actor, AuditActionUpdate, "session_enrollment", enrollment.ID, before, &enrollment)
+		if err != nil {
+			return nil, err
+		}
+		promoted = append(promoted, &enrollment)
+	}
+	return promoted, nil
+}
+
+// deleteSessionEnrollments deletes and audits the enrollments matching where,
+// a condition on $1, which is arg, and returns how many there were. It
+// doesn't promote anyone from the waitlist.
+func deleteSessionEnrollments(ctx context.Context, q queryer, actor AuditActor, where, arg string) (int, error) {
+	query := `
+        DELETE FROM session_enrollments
+        WHERE ` + where + `
+        RETURNING id, session_id, officer_id, status, created_at, updated_at, version`
+
+	rows, err := q.QueryContext(ctx, query, arg)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	enrollments := []*SessionEnrollment{}
+	for rows.Next() {
+		var enrollment SessionEnrollment
+		err := rows.Scan(
+			&enrollment.ID,
+			&enrollment.SessionID,
+			&enrollment.OfficerID,
+			&enrollment.Status,
+			&enrollment.CreatedAt,
+			&enrollment.UpdatedAt,
+			&enrollment.Version,
+		)
+		if err != nil {
+			return 0, err
+		}
+		enrollments = append(enrollments, &enrollment)
+	}
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	for _, enrollment := range enrollments {
+		err = audit(ctx, q, actor, AuditActionDelete, "session_enrollment", enrollment.ID, enrollment, nil)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(enrollments), nil
+}
+
+// GetAll returns a paginated list of enrollments, optionally for one session,
+// one officer or with one status.
+func (m SessionEnrollmentModel) GetAll(sessionID string, officerID string, status string, filters Filters) ([]*SessionEnrollment, Metadata, error) {
+	query := fmt.Sprintf(`
+        SELECT count(*) OVER(), id, session_id, officer_id, status, waitlist_position,
+               created_at, updated_at, version
+        FROM (`+enrollmentsWithPositions+`) e
+        WHERE (session_id::text = $1 OR $1 = '')
+        AND (officer_id::text = $2 OR $2 = '')
+        AND (status = $3 OR $3 = '')
+        ORDER BY %s %s, id ASC
+        LIMIT $4 OFFSET $5`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{sessionID, officerID, status, filters.limit(), filters.offset()}
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := int64(0)
+	enrollments := []*SessionEnrollment{}
+
+	for rows.Next() {
+		var enrollment SessionEnrollment
+		err := rows.Scan(
+			&totalRecords,
+			&enrollment.ID,
+			&enrollment.SessionID,
+			&enrollment.OfficerID,
+			&enrollment.Status,
+			&enrollment.WaitlistPosition,
+			&enrollment.CreatedAt,
+			&enrollment.UpdatedAt,
+			&enrollment.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		enrollments = append(enrollments, &enrollment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return enrollments, metadata, nil
+}
@@ -28,8 +28,11 @@ func ValidateSessionFacilitator(v *validator.Validator, sf *SessionFacilitator)
 	v.Check(sf.FacilitatorID != "", "facilitator_id", "must be provided")
 }
 
-// Insert a new session_facilitator record.
-func (m SessionFacilitatorModel) Insert(sf *SessionFacilitator) error {
+// Insert a new session_facilitator record. It returns ErrRecordNotFound if
+// the session doesn't exist, and a *ScheduleConflictError if the facilitator
+// is already in a session at the same time, unless force is set; then the
+// override is recorded in the audit log.
+func (m SessionFacilitatorModel) Insert(actor AuditActor, sf *SessionFacilitator, force bool) error {
 	query := `
         INSERT INTO session_facilitators (session_id, facilitator_id, role)
         VALUES ($1, $2, $3)
@@ -40,38 +43,109 @@ func (m SessionFacilitatorModel) Insert(sf *SessionFacilitator) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockSchedule(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	check, err := sessionSchedule(ctx, tx, sf.SessionID)
+	if err != nil {
+		return err
+	}
+	check.Location = ""
+	check.FacilitatorIDs = []string{sf.FacilitatorID}
+
+	conflicts, err := checkSchedule(ctx, tx, check, force)
+	if err != nil {
+		return err
+	}
+
 	// UPDATED THIS LINE (removed &sf.CreatedAt)
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(&sf.ID, &sf.Version)
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&sf.ID, &sf.Version)
+	if err != nil {
+		return err
+	}
+
+	err = audit(ctx, tx, actor, AuditActionInsert, "session_facilitator", sf.ID, nil, sf)
+	if err != nil {
+		return err
+	}
+	err = auditOverride(ctx, tx, actor, "session_facilitator", sf.ID, conflicts)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // Delete a specific session_facilitator record by its ID.
-func (m SessionFacilitatorModel) Delete(id string) error {
+func (m SessionFacilitatorModel) Delete(actor AuditActor, id string) error {
 	if id == "" {
 		return errors.New("id must be provided")
 	}
 
-	query := `
-        DELETE FROM session_facilitators
-        WHERE id = $1`
-
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, id)
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	n, err := deleteSessionFacilitators(ctx, tx, actor, "id = $1", id)
 	if err != nil {
 		return err
 	}
-
-	if rowsAffected == 0 {
+	if n == 0 {
 		return ErrRecordNotFound
 	}
 
-	return nil
+	return tx.Commit()
+}
+
+// deleteSessionFacilitators deletes and audits the session_facilitator
+// records matching where, a condition on $1, which is arg, and returns how
+// many there were.
+func deleteSessionFacilitators(ctx context.Context, q queryer, actor AuditActor, where, arg string) (int, error) {
+	query := `
+        DELETE FROM session_facilitators
+        WHERE ` + where + `
+        RETURNING id, session_id, facilitator_id, role, version`
+
+	rows, err := q.QueryContext(ctx, query, arg)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	sfs := []*SessionFacilitator{}
+	for rows.Next() {
+		var sf SessionFacilitator
+		err := rows.Scan(&sf.ID, &sf.SessionID, &sf.FacilitatorID, &sf.Role, &sf.Version)
+		if err != nil {
+			return 0, err
+		}
+		sfs = append(sfs, &sf)
+	}
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	for _, sf := range sfs {
+		err = audit(ctx, q, actor, AuditActionDelete, "session_facilitator", sf.ID, sf, nil)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(sfs), nil
 }
 
 // GetAll returns a paginated and filtered list of session-facilitator links.
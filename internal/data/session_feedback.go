@@ -32,7 +32,7 @@ func ValidateSessionFeedback(v *validator.Validator, sf *SessionFeedback) {
 }
 
 // Insert a new session_feedback record.
-func (m SessionFeedbackModel) Insert(sf *SessionFeedback) error {
+func (m SessionFeedbackModel) Insert(actor AuditActor, sf *SessionFeedback) error {
 	query := `
         INSERT INTO session_feedback (session_id, officer_id, facilitator_id, rating, comments)
         VALUES ($1, $2, $3, $4, $5)
@@ -49,7 +49,68 @@ func (m SessionFeedbackModel) Insert(sf *SessionFeedback) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(&sf.ID, &sf.CreatedAt, &sf.Version) // UPDATE THIS
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&sf.ID, &sf.CreatedAt, &sf.Version) // UPDATE THIS
+	if err != nil {
+		return err
+	}
+
+	err = audit(ctx, tx, actor, AuditActionInsert, "session_feedback", sf.ID, nil, sf)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// deleteSessionFeedback deletes and audits the feedback matching where, a
+// condition on $1, which is arg, and returns how much there was.
+func deleteSessionFeedback(ctx context.Context, q queryer, actor AuditActor, where, arg string) (int, error) {
+	query := `
+        DELETE FROM session_feedback
+        WHERE ` + where + `
+        RETURNING id, session_id, officer_id, facilitator_id, rating, comments, created_at, version`
+
+	rows, err := q.QueryContext(ctx, query, arg)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	feedbacks := []*SessionFeedback{}
+	for rows.Next() {
+		var feedback SessionFeedback
+		err := rows.Scan(
+			&feedback.ID,
+			&feedback.SessionID,
+			&feedback.OfficerID,
+			&feedback.FacilitatorID,
+			&feedback.Rating,
+			&feedback.Comments,
+			&feedback.CreatedAt,
+			&feedback.Version,
+		)
+		if err != nil {
+			return 0, err
+		}
+		feedbacks = append(feedbacks, &feedback)
+	}
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	for _, feedback := range feedbacks {
+		err = audit(ctx, q, actor, AuditActionDelete, "session_feedback", feedback.ID, feedback, nil)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(feedbacks), nil
 }
 
 // GetAll returns a paginated and filtered list of session feedback.
@@ -0,0 +1,806 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/amari03/test1/internal/validator"
+	"github.com/lib/pq"
+)
+
+// SessionSeries is a session that repeats, such as a weekly fitness class.
+// Its sessions are created up front, one for each occurrence of its rule,
+// and look like any other session. Start and End are the first occurrence's,
+// and give the others their time of day, in TimeZone, and length. The other
+// fields are copied to every session.
+type SessionSeries struct {
+	ID                  string         `json:"id"`
+	CourseID            string         `json:"course_id"`
+	Start               time.Time      `json:"start_datetime"`
+	End                 time.Time      `json:"end_datetime"`
+	TimeZone            string         `json:"time_zone"`
+	Rule                RecurrenceRule `json:"rule"`
+	Location            string         `json:"location_text"`
+	RegionID            *string        `json:"region_id,omitempty"`
+	FormationID         *string        `json:"formation_id,omitempty"`
+	PostingID           *string        `json:"posting_id,omitempty"`
+	FacilitatorNotes    *string        `json:"facilitator_notes,omitempty"`
+	CreditHoursOverride *float64       `json:"credit_hours_override,omitempty"`
+	Capacity            *int           `json:"capacity,omitempty"`
+	CancelledAt         *time.Time     `json:"cancelled_at,omitempty"`
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           *time.Time     `json:"updated_at,omitempty"`
+	Version             int32          `json:"version"`
+}
+
+// SessionChange is a change a series made to one of its sessions. Before is
+// nil for a session it created and After is nil for one it deleted.
+type SessionChange struct {
+	Before *Session
+	After  *Session
+}
+
+// SeriesChanges is what a write to a series did. If an update split the
+// series, Ended is the original series, which now stops where Series starts.
+// Conflicts are the scheduling conflicts that were overridden, if any.
+type SeriesChanges struct {
+	Series    *SessionSeries
+	Ended     *SessionSeries
+	Sessions  []SessionChange
+	Conflicts []ScheduleConflict
+}
+
+type SessionSeriesModel struct {
+	DB *sql.DB
+}
+
+// seriesTimeout is longer than usual, as a write to a series can touch
+// hundreds of sessions and check each of them for conflicts.
+const seriesTimeout = 10 * time.Second
+
+func ValidateSessionSeries(v *validator.Validator, series *SessionSeries) {
+	// Every session has to be valid, and they only differ in their times.
+	session := Session{Status: SessionStatusScheduled}
+	series.apply(&session, 0, series.Start)
+	ValidateSession(v, &session)
+
+	_, err := time.LoadLocation(series.TimeZone)
+	v.Check(series.TimeZone != "" && err == nil, "time_zone", "must be a valid IANA time zone name")
+
+	ValidateRecurrenceRule(v, series.Rule, series.Start)
+	if v.Valid() {
+		n := len(series.Occurrences())
+		v.Check(n > 0, "rule", "must produce at least one session")
+		v.Check(n <= MaxSeriesOccurrences, "rule", "must not produce more than 200 sessions")
+	}
+}
+
+// Occurrences returns the start of each of the series' sessions.
+func (series *SessionSeries) Occurrences() []time.Time {
+	loc, err := time.LoadLocation(series.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return series.Rule.Occurrences(series.Start.In(loc))
+}
+
+// apply makes session the series' occurrence number index, starting at start.
+func (series *SessionSeries) apply(session *Session, index int, start time.Time) {
+	seriesID := series.ID
+	session.CourseID = series.CourseID
+	session.Start = start
+	session.End = start.Add(series.End.Sub(series.Start))
+	session.Location = series.Location
+	session.RegionID = series.RegionID
+	session.FormationID = series.FormationID
+	session.PostingID = series.PostingID
+	session.FacilitatorNotes = series.FacilitatorNotes
+	session.CreditHoursOverride = series.CreditHoursOverride
+	session.Capacity = series.Capacity
+	session.SeriesID = &seriesID
+	session.SeriesIndex = &index
+}
+
+const seriesColumns = `id, course_id, start_datetime, end_datetime, time_zone, frequency, repeat_interval,
+               by_day, until_datetime, occurrence_count, location_text, region_id, formation_id, posting_id,
+               facilitator_notes, credit_hours_override, capacity, cancelled_at, created_at, updated_at, version`
+
+func scanSeries(row interface{ Scan(...interface{}) error }, series *SessionSeries, extra ...interface{}) error {
+	dest := append(extra,
+		&series.ID,
+		&series.CourseID,
+		&series.Start,
+		&series.End,
+		&series.TimeZone,
+		&series.Rule.Frequency,
+		&series.Rule.Interval,
+		pq.Array(&series.Rule.ByDay),
+		&series.Rule.Until,
+		&series.Rule.Count,
+		&series.Location,
+		&series.RegionID,
+		&series.FormationID,
+		&series.PostingID,
+		&series.FacilitatorNotes,
+		&series.CreditHoursOverride,
+		&series.Capacity,
+		&series.CancelledAt,
+		&series.CreatedAt,
+		&series.UpdatedAt,
+		&series.Version,
+	)
+	return row.Scan(dest...)
+}
+
+// Insert adds a series and creates its sessions. They are checked for
+// conflicts like any other new session: if there are any, it returns a
+// *ScheduleConflictError, unless force is set.
+func (m SessionSeriesModel) Insert(actor AuditActor, series *SessionSeries, force bool) (*SeriesChanges, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), seriesTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = lockSchedule(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = insertSeries(ctx, tx, series)
+	if err != nil {
+		return nil, err
+	}
+
+	err = audit(ctx, tx, actor, AuditActionInsert, "session_series", series.ID, nil, series)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := &SeriesChanges{Series: series}
+	changes.Sessions, err = syncSeriesSessions(ctx, tx, actor, series, series.ID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	changes.Conflicts, err = checkSeriesSchedule(ctx, tx, changes.Sessions, force)
+	if err != nil {
+		return nil, err
+	}
+
+	err = auditOverride(ctx, tx, actor, "session_series", series.ID, changes.Conflicts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+func insertSeries(ctx context.Context, q queryer, series *SessionSeries) error {
+	query := `
+        INSERT INTO session_series (course_id, start_datetime, end_datetime, time_zone, frequency,
+                                    repeat_interval, by_day, until_datetime, occurrence_count, location_text,
+                                    region_id, formation_id, posting_id, facilitator_notes,
+                                    credit_hours_override, capacity)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+        RETURNING id, created_at, updated_at, version`
+
+	args := []interface{}{
+		series.CourseID,
+		series.Start,
+		series.End,
+		series.TimeZone,
+		series.Rule.Frequency,
+		series.Rule.Interval,
+		pq.Array(byDay(series.Rule.ByDay)),
+		series.Rule.Until,
+		series.Rule.Count,
+		series.Location,
+		series.RegionID,
+		series.FormationID,
+		series.PostingID,
+		series.FacilitatorNotes,
+		series.CreditHoursOverride,
+		series.Capacity,
+	}
+
+	return q.QueryRowContext(ctx, query, args...).Scan(&series.ID, &series.CreatedAt, &series.UpdatedAt, &series.Version)
+}
+
+// byDay stores a missing BYDAY as an empty array, as the column isn't NULL.
+func byDay(days []string) []string {
+	if days == nil {
+		return []string{}
+	}
+	return days
+}
+
+// Get a specific series by ID.
+func (m SessionSeriesModel) Get(id string) (*SessionSeries, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return getSeries(ctx, m.DB, id)
+}
+
+// getSeries fetches a series using q, which may be a transaction.
+func getSeries(ctx context.Context, q queryer, id string) (*SessionSeries, error) {
+	query := `
+        SELECT ` + seriesColumns + `
+        FROM session_series
+        WHERE id = $1`
+
+	var series SessionSeries
+	err := scanSeries(q.QueryRowContext(ctx, query, id), &series)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &series, nil
+}
+
+// Update changes a series' sessions from its occurrence number from onwards,
+// leaving earlier ones as they were. series holds the edited series, whose
+// Start is the first changed occurrence's.
+//
+// With from at 0 the series is changed in place. Otherwise it is split, in
+// the way calendars handle "this and following events": the original series
+// keeps its first from occurrences and ends, and a new series, described by
+// series, takes over its later sessions. Either way, the sessions are then
+// brought into line with the series, and any that have moved or been added
+// are checked for conflicts.
+//
+// Sessions that have been edited on their own, or that already have
+// attendance, are left alone. It returns ErrEditConflict if the series has
+// changed or been cancelled since it was read.
+func (m SessionSeriesModel) Update(actor AuditActor, series *SessionSeries, from int, force bool) (*SeriesChanges, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), seriesTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = lockSchedule(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := getSeries(ctx, tx, series.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRecordNotFound):
+			return nil, ErrEditConflict
+		default:
+			return nil, err
+		}
+	}
+
+	changes := &SeriesChanges{Series: series}
+	sourceID := series.ID
+
+	if from == 0 {
+		err = updateSeries(ctx, tx, series)
+		if err == nil {
+			err = audit(ctx, tx, actor, AuditActionUpdate, "session_series", series.ID, before, series)
+		}
+	} else {
+		changes.Ended, err = endSeries(ctx, tx, series.ID, series.Version, from)
+		if err == nil {
+			err = audit(ctx, tx, actor, AuditActionUpdate, "session_series", before.ID, before, changes.Ended)
+		}
+		if err == nil {
+			err = insertSeries(ctx, tx, series)
+		}
+		if err == nil {
+			err = audit(ctx, tx, actor, AuditActionInsert, "session_series", series.ID, nil, series)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	changes.Sessions, err = syncSeriesSessions(ctx, tx, actor, series, sourceID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	changes.Conflicts, err = checkSeriesSchedule(ctx, tx, changes.Sessions, force)
+	if err != nil {
+		return nil, err
+	}
+
+	err = auditOverride(ctx, tx, actor, "session_series", series.ID, changes.Conflicts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+func updateSeries(ctx context.Context, q queryer, series *SessionSeries) error {
+	query := `
+        UPDATE session_series
+        SET course_id = $1, start_datetime = $2, end_datetime = $3, time_zone = $4, frequency = $5,
+            repeat_interval = $6, by_day = $7, until_datetime = $8, occurrence_count = $9,
+            location_text = $10, region_id = $11, formation_id = $12, posting_id = $13,
+            facilitator_notes = $14, credit_hours_override = $15, capacity = $16,
+            updated_at = NOW(), version = version + 1
+        WHERE id = $17 AND version = $18 AND cancelled_at IS NULL
+        RETURNING updated_at, version`
+
+	args := []interface{}{
+		series.CourseID,
+		series.Start,
+		series.End,
+		series.TimeZone,
+		series.Rule.Frequency,
+		series.Rule.Interval,
+		pq.Array(byDay(series.Rule.ByDay)),
+		series.Rule.Until,
+		series.Rule.Count,
+		series.Location,
+		series.RegionID,
+		series.FormationID,
+		series.PostingID,
+		series.FacilitatorNotes,
+		series.CreditHoursOverride,
+		series.Capacity,
+		series.ID,
+		series.Version,
+	}
+
+	err := q.QueryRowContext(ctx, query, args...).Scan(&series.UpdatedAt, &series.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// endSeries cuts a series down to its first count occurrences and returns it.
+func endSeries(ctx context.Context, q queryer, id string, version int32, count int) (*SessionSeries, error) {
+	query := `
+        UPDATE session_series
+        SET occurrence_count = $3, until_datetime = NULL, updated_at = NOW(), version = version + 1
+        WHERE id = $1 AND version = $2 AND cancelled_at IS NULL
+        RETURNING ` + seriesColumns
+
+	var series SessionSeries
+	err := scanSeries(q.QueryRowContext(ctx, query, id, version, count), &series)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrEditConflict
+		default:
+			return nil, err
+		}
+	}
+	return &series, nil
+}
+
+// deleteSeries deletes and audits the series matching where, a condition on
+// $1, which is arg, and returns how many there were. Their sessions must
+// have been dealt with first.
+func deleteSeries(ctx context.Context, q queryer, actor AuditActor, where, arg string) (int, error) {
+	query := `
+        DELETE FROM session_series
+        WHERE ` + where + `
+        RETURNING ` + seriesColumns
+
+	rows, err := q.QueryContext(ctx, query, arg)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	allSeries := []*SessionSeries{}
+	for rows.Next() {
+		var series SessionSeries
+		if err := scanSeries(rows, &series); err != nil {
+			return 0, err
+		}
+		allSeries = append(allSeries, &series)
+	}
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	for _, series := range allSeries {
+		err = audit(ctx, q, actor, AuditActionDelete, "session_series", series.ID, series, nil)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(allSeries), nil
+}
+
+// Cancel cancels a series and those of its sessions that haven't started yet,
+// giving them reason as their cancellation reason. Sessions that already have
+// attendance are left as they are, as a record of training that happened. It
+// returns ErrEditConflict if the series has changed or been cancelled since it
+// was read.
+func (m SessionSeriesModel) Cancel(actor AuditActor, series *SessionSeries, reason string) (*SeriesChanges, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), seriesTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := getSeries(ctx, tx, series.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRecordNotFound):
+			return nil, ErrEditConflict
+		default:
+			return nil, err
+		}
+	}
+
+	query := `
+        UPDATE session_series
+        SET cancelled_at = NOW(), updated_at = NOW(), version = version + 1
+        WHERE id = $1 AND version = $2 AND cancelled_at IS NULL
+        RETURNING cancelled_at, updated_at, version`
+
+	err = tx.QueryRowContext(ctx, query, series.ID, series.Version).Scan(&series.CancelledAt, &series.UpdatedAt, &series.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrEditConflict
+		default:
+			return nil, err
+		}
+	}
+
+	err = audit(ctx, tx, actor, AuditActionUpdate, "session_series", series.ID, before, series)
+	if err != nil {
+		return nil, err
+	}
+
+	// The old status, updated_at and version come from the rows as they were
+	// before the update, so that each change has its before.
+	query = `
+        WITH old AS (
+            SELECT id AS old_id, status AS old_status, updated_at AS old_updated_at, version AS old_version
+            FROM sessions s
+            WHERE series_id = $1 AND start_datetime > NOW() AND status NOT IN ('completed', 'cancelled')
+            AND NOT EXISTS (SELECT 1 FROM attendance a WHERE a.session_id = s.id)
+            FOR UPDATE
+        )
+        UPDATE sessions
+        SET status = 'cancelled', cancellation_reason = $2, updated_at = NOW(), version = version + 1
+        FROM old
+        WHERE id = old_id
+        RETURNING old_status, old_updated_at, old_version, ` + sessionColumns
+
+	rows, err := tx.QueryContext(ctx, query, series.ID, reason)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := &SeriesChanges{Series: series, Sessions: []SessionChange{}}
+	for rows.Next() {
+		var after Session
+		var oldStatus string
+		var oldUpdatedAt *time.Time
+		var oldVersion int32
+		err := scanSession(rows, &after, &oldStatus, &oldUpdatedAt, &oldVersion)
+		if err != nil {
+			return nil, err
+		}
+		old := after
+		old.Status, old.CancellationReason = oldStatus, nil
+		old.UpdatedAt, old.Version = oldUpdatedAt, oldVersion
+		changes.Sessions = append(changes.Sessions, SessionChange{Before: &old, After: &after})
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for _, change := range changes.Sessions {
+		err = audit(ctx, tx, actor, AuditActionUpdate, "session", change.After.ID, change.Before, change.After)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// GetAll returns a paginated list of series, optionally for one course.
+func (m SessionSeriesModel) GetAll(courseID string, filters Filters) ([]*SessionSeries, Metadata, error) {
+	query := fmt.Sprintf(`
+        SELECT count(*) OVER(), `+seriesColumns+`
+        FROM session_series
+        WHERE (course_id::text = $1 OR $1 = '')
+        ORDER BY %s %s, id ASC
+        LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, courseID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := int64(0)
+	allSeries := []*SessionSeries{}
+
+	for rows.Next() {
+		var series SessionSeries
+		if err := scanSeries(rows, &series, &totalRecords); err != nil {
+			return nil, Metadata{}, err
+		}
+		allSeries = append(allSeries, &series)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return allSeries, metadata, nil
+}
+
+// syncSeriesSessions brings a series' sessions into line with it. The
+// sessions are those of the series sourceID from occurrence number from
+// onwards, which is the series itself unless it has just been split off.
+// Each is renumbered from 0 and given to series, and its times and details
+// are updated to match the series, unless it is detached or has attendance.
+// Sessions for occurrences the series no longer has are deleted, and
+// sessions are created for new ones, apart from excluded occurrences. Each
+// change is audited as it is made.
+func syncSeriesSessions(ctx context.Context, tx *sql.Tx, actor AuditActor, series *SessionSeries, sourceID string, from int) ([]SessionChange, error) {
+	excluded, err := seriesExclusions(ctx, tx, series, sourceID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+        SELECT EXISTS (SELECT 1 FROM attendance a WHERE a.session_id = s.id), ` + sessionColumns + `
+        FROM sessions s
+        WHERE series_id = $1 AND series_index >= $2
+        ORDER BY series_index
+        FOR UPDATE`
+
+	rows, err := tx.QueryContext(ctx, query, sourceID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing []*Session
+	attended := make(map[string]bool)
+	for rows.Next() {
+		var session Session
+		var hasAttendance bool
+		if err := scanSession(rows, &session, &hasAttendance); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		existing = append(existing, &session)
+		attended[session.ID] = hasAttendance
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	occurrences := series.Occurrences()
+	taken := make(map[int]bool)
+	changes := []SessionChange{}
+
+	for _, before := range existing {
+		index := *before.SeriesIndex - from
+		taken[index] = true
+
+		after := *before
+		switch {
+		case before.Detached || attended[before.ID]:
+			// Only renumbered, if the series was split.
+			seriesID := series.ID
+			after.SeriesID, after.SeriesIndex = &seriesID, &index
+		case index < len(occurrences):
+			series.apply(&after, index, occurrences[index])
+		default:
+			_, err := deleteSessions(ctx, tx, actor, "id = $1", before.ID)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, SessionChange{Before: before})
+			continue
+		}
+
+		if sameSession(before, &after) {
+			continue
+		}
+		err := updateSeriesSession(ctx, tx, &after)
+		if err != nil {
+			return nil, err
+		}
+		err = audit(ctx, tx, actor, AuditActionUpdate, "session", after.ID, before, &after)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, SessionChange{Before: before, After: &after})
+	}
+
+	for index, start := range occurrences {
+		if taken[index] || excluded[index] {
+			continue
+		}
+		session := &Session{Status: SessionStatusScheduled}
+		series.apply(session, index, start)
+		err := insertSession(ctx, tx, session)
+		if err != nil {
+			return nil, err
+		}
+		err = audit(ctx, tx, actor, AuditActionInsert, "session", session.ID, nil, session)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, SessionChange{After: session})
+	}
+
+	return changes, nil
+}
+
+// seriesExclusions returns the occurrences excluded from series, renumbered
+// from from. A series split off from another takes its later exclusions.
+func seriesExclusions(ctx context.Context, tx *sql.Tx, series *SessionSeries, sourceID string, from int) (map[int]bool, error) {
+	var indexes []int64
+	err := tx.QueryRowContext(ctx, `SELECT excluded_indexes FROM session_series WHERE id = $1`, sourceID).Scan(pq.Array(&indexes))
+	if err != nil {
+		return nil, err
+	}
+
+	excluded := make(map[int]bool)
+	var kept []int64
+	for _, index := range indexes {
+		if int(index) >= from {
+			excluded[int(index)-from] = true
+			kept = append(kept, index-int64(from))
+		}
+	}
+
+	if series.ID != sourceID && len(kept) > 0 {
+		_, err = tx.ExecContext(ctx, `UPDATE session_series SET excluded_indexes = $2 WHERE id = $1`, series.ID, pq.Array(kept))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return excluded, nil
+}
+
+func updateSeriesSession(ctx context.Context, q queryer, session *Session) error {
+	query := `
+        UPDATE sessions
+        SET course_id = $1, start_datetime = $2, end_datetime = $3, location_text = $4,
+            region_id = $5, formation_id = $6, posting_id = $7, facilitator_notes = $8,
+            credit_hours_override = $9, capacity = $10, series_id = $11, series_index = $12,
+            updated_at = NOW(), version = version + 1
+        WHERE id = $13
+        RETURNING updated_at, version`
+
+	args := []interface{}{
+		session.CourseID,
+		session.Start,
+		session.End,
+		session.Location,
+		session.RegionID,
+		session.FormationID,
+		session.PostingID,
+		session.FacilitatorNotes,
+		session.CreditHoursOverride,
+		session.Capacity,
+		session.SeriesID,
+		session.SeriesIndex,
+		session.ID,
+	}
+
+	return q.QueryRowContext(ctx, query, args...).Scan(&session.UpdatedAt, &session.Version)
+}
+
+// sameSession reports whether a series would leave a session as it is.
+func sameSession(a, b *Session) bool {
+	return a.CourseID == b.CourseID &&
+		a.Start.Equal(b.Start) &&
+		a.End.Equal(b.End) &&
+		a.Location == b.Location &&
+		equalPtr(a.RegionID, b.RegionID) &&
+		equalPtr(a.FormationID, b.FormationID) &&
+		equalPtr(a.PostingID, b.PostingID) &&
+		equalPtr(a.FacilitatorNotes, b.FacilitatorNotes) &&
+		equalPtr(a.CreditHoursOverride, b.CreditHoursOverride) &&
+		equalPtr(a.Capacity, b.Capacity) &&
+		equalPtr(a.SeriesID, b.SeriesID) &&
+		equalPtr(a.SeriesIndex, b.SeriesIndex)
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// checkSeriesSchedule checks the sessions a series has added or moved for
+// conflicts, including with each other. Each conflict carries the start of
+// the occurrence that clashes, and two of the series' own sessions clashing
+// is only reported once.
+func checkSeriesSchedule(ctx context.Context, q queryer, changes []SessionChange, force bool) ([]ScheduleConflict, error) {
+	conflicts := []ScheduleConflict{}
+	checked := make(map[string]bool)
+
+	for _, change := range changes {
+		session := change.After
+		if session == nil {
+			continue
+		}
+		before := change.Before
+		if before != nil && before.Start.Equal(session.Start) && before.End.Equal(session.End) && before.Location == session.Location {
+			continue
+		}
+
+		check := scheduleCheck{SessionID: session.ID, Start: session.Start, End: session.End, Location: session.Location}
+		if before != nil {
+			var err error
+			check.FacilitatorIDs, check.OfficerIDs, err = sessionParticipants(ctx, q, session.ID)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		found, err := findScheduleConflicts(ctx, q, check)
+		if err != nil {
+			return nil, err
+		}
+		for _, conflict := range found {
+			if checked[conflict.SessionID] {
+				continue
+			}
+			start := session.Start
+			conflict.OccurrenceStart = &start
+			conflicts = append(conflicts, conflict)
+		}
+		checked[session.ID] = true
+	}
+
+	if len(conflicts) > 0 && !force {
+		return nil, &ScheduleConflictError{Conflicts: conflicts}
+	}
+	return conflicts, nil
+}
@@ -0,0 +1,226 @@
+package data
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/amari03/test1/internal/validator"
+	"github.com/stretchr/testify/require"
+)
+
+// setupSessionSeriesTestDB adds the session_series table to the sessions
+// test database.
+func setupSessionSeriesTestDB(t *testing.T) (*sql.DB, string) {
+	db, courseID := setupSessionsTestDB(t)
+	createSessionSeriesTable(t, db)
+	return db, courseID
+}
+
+// createSessionSeriesTable adds the session_series table to a test DB.
+func createSessionSeriesTable(t *testing.T, db *sql.DB) {
+	_, err := db.Exec(`
+    CREATE TABLE IF NOT EXISTS session_series (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+        course_id UUID NOT NULL,
+        start_datetime TIMESTAMPTZ NOT NULL,
+        end_datetime TIMESTAMPTZ NOT NULL,
+        time_zone TEXT NOT NULL DEFAULT 'UTC',
+        frequency TEXT NOT NULL,
+        repeat_interval INTEGER NOT NULL DEFAULT 1,
+        by_day TEXT[] NOT NULL DEFAULT '{}',
+        until_datetime TIMESTAMPTZ,
+        occurrence_count INTEGER,
+        location_text TEXT NOT NULL,
+        region_id TEXT,
+        formation_id TEXT,
+        posting_id TEXT,
+        facilitator_notes TEXT,
+        credit_hours_override NUMERIC(4, 1),
+        capacity INTEGER,
+        excluded_indexes INTEGER[] NOT NULL DEFAULT '{}',
+        cancelled_at TIMESTAMPTZ,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        updated_at TIMESTAMPTZ,
+        version INTEGER NOT NULL DEFAULT 1
+    );`)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		db.Exec("DROP TABLE IF EXISTS session_series;")
+	})
+}
+
+// newTestSeries returns a weekly series of four sessions, starting on a
+// Monday next year.
+func newTestSeries(courseID string) *SessionSeries {
+	start := time.Date(time.Now().Year()+1, time.January, 5, 9, 0, 0, 0, time.UTC)
+	for start.Weekday() != time.Monday {
+		start = start.AddDate(0, 0, 1)
+	}
+	return &SessionSeries{
+		CourseID: courseID,
+		Start:    start,
+		End:      start.Add(2 * time.Hour),
+		TimeZone: "UTC",
+		Rule:     RecurrenceRule{Frequency: FrequencyWeekly, Interval: 1, Count: ptr(4)},
+		Location: "Range 1",
+	}
+}
+
+func seriesSessions(t *testing.T, db *sql.DB, seriesID string) []*Session {
+	filters := Filters{Page: 1, PageSize: 100, Sort: "start_datetime", SortSafelist: []string{"start_datetime"}}
+	sessions, _, err := SessionModel{DB: db}.GetAll("", "", "", seriesID, "", nil, nil, filters)
+	require.NoError(t, err)
+	return sessions
+}
+
+func TestSessionSeriesModel_Insert(t *testing.T) {
+	db, courseID := setupSessionSeriesTestDB(t)
+	m := SessionSeriesModel{DB: db}
+
+	series := newTestSeries(courseID)
+	changes, err := m.Insert(AuditActor{}, series, false)
+	require.NoError(t, err)
+	require.NotEmpty(t, series.ID)
+	require.Len(t, changes.Sessions, 4)
+	require.Empty(t, changes.Conflicts)
+
+	sessions := seriesSessions(t, db, series.ID)
+	require.Len(t, sessions, 4)
+	for i, session := range sessions {
+		require.Equal(t, i, *session.SeriesIndex)
+		require.True(t, series.Start.AddDate(0, 0, 7*i).Equal(session.Start))
+		require.Equal(t, 2*time.Hour, session.End.Sub(session.Start))
+		require.Equal(t, "Range 1", session.Location)
+	}
+
+	fetched, err := m.Get(series.ID)
+	require.NoError(t, err)
+	require.Equal(t, FrequencyWeekly, fetched.Rule.Frequency)
+	require.Equal(t, 4, *fetched.Rule.Count)
+
+	// A second series at the same range and times clashes with every session.
+	_, err = m.Insert(AuditActor{}, newTestSeries(courseID), false)
+	var conflictErr *ScheduleConflictError
+	require.ErrorAs(t, err, &conflictErr)
+	require.Len(t, conflictErr.Conflicts, 4)
+	require.NotNil(t, conflictErr.Conflicts[0].OccurrenceStart)
+}
+
+func TestSessionSeriesModel_Update(t *testing.T) {
+	db, courseID := setupSessionSeriesTestDB(t)
+	m := SessionSeriesModel{DB: db}
+	sessionModel := SessionModel{DB: db}
+
+	series := newTestSeries(courseID)
+	_, err := m.Insert(AuditActor{}, series, false)
+	require.NoError(t, err)
+	sessions := seriesSessions(t, db, series.ID)
+
+	// Edit one occurrence on its own, which detaches it, and delete another.
+	sessions[1].Location = "Range 2"
+	err = sessionModel.Update(AuditActor{}, sessions[1], false)
+	require.NoError(t, err)
+	require.True(t, sessions[1].Detached)
+	require.NoError(t, sessionModel.Delete(AuditActor{}, sessions[3].ID))
+
+	// Postponing one only changes its status, so it stays in the series.
+	sessions[2].Status = SessionStatusPostponed
+	err = sessionModel.Update(AuditActor{}, sessions[2], false)
+	require.NoError(t, err)
+	require.False(t, sessions[2].Detached)
+
+	// Change every occurrence in place, and add one more.
+	series.Location = "Range 3"
+	series.Rule.Count = ptr(5)
+	changes, err := m.Update(AuditActor{}, series, 0, false)
+	require.NoError(t, err)
+	require.Nil(t, changes.Ended)
+
+	sessions = seriesSessions(t, db, series.ID)
+	require.Len(t, sessions, 4)
+	require.Equal(t, "Range 3", sessions[0].Location)
+	require.Equal(t, "Range 2", sessions[1].Location)
+	require.Equal(t, "Range 3", sessions[2].Location)
+	require.Equal(t, 4, *sessions[3].SeriesIndex)
+
+	// Split the series from the third occurrence, moving it and the rest to
+	// the afternoon.
+	original := *series
+	split := *series
+	split.Start = sessions[2].Start.Add(5 * time.Hour)
+	split.End = split.Start.Add(2 * time.Hour)
+	split.Rule.Count = ptr(3)
+	changes, err = m.Update(AuditActor{}, &split, 2, false)
+	require.NoError(t, err)
+	require.NotNil(t, changes.Ended)
+	require.Equal(t, 2, *changes.Ended.Rule.Count)
+	require.NotEqual(t, original.ID, split.ID)
+
+	require.Len(t, seriesSessions(t, db, original.ID), 2)
+	later := seriesSessions(t, db, split.ID)
+	require.Len(t, later, 2)
+	require.Equal(t, 14, later[0].Start.UTC().Hour())
+	require.Equal(t, 0, *later[0].SeriesIndex)
+	require.Equal(t, 2, *later[1].SeriesIndex)
+
+	// The original series has moved on.
+	_, err = m.Update(AuditActor{}, &original, 0, false)
+	require.ErrorIs(t, err, ErrEditConflict)
+}
+
+func TestSessionSeriesModel_Cancel(t *testing.T) {
+	db, courseID := setupSessionSeriesTestDB(t)
+	m := SessionSeriesModel{DB: db}
+
+	series := newTestSeries(courseID)
+	_, err := m.Insert(AuditActor{}, series, false)
+	require.NoError(t, err)
+	sessions := seriesSessions(t, db, series.ID)
+
+	_, err = db.Exec(`INSERT INTO attendance (officer_id, session_id) VALUES (gen_random_uuid(), $1)`, sessions[2].ID)
+	require.NoError(t, err)
+
+	changes, err := m.Cancel(AuditActor{}, series, "Instructor posted overseas")
+	require.NoError(t, err)
+	require.NotNil(t, series.CancelledAt)
+	require.Len(t, changes.Sessions, 3)
+	require.Equal(t, SessionStatusScheduled, changes.Sessions[0].Before.Status)
+	require.Equal(t, SessionStatusCancelled, changes.Sessions[0].After.Status)
+
+	// The sessions are kept, cancelled, apart from the one with attendance.
+	sessions = seriesSessions(t, db, series.ID)
+	require.Len(t, sessions, 4)
+	for i, session := range sessions {
+		if i == 2 {
+			require.Equal(t, SessionStatusScheduled, session.Status)
+			continue
+		}
+		require.Equal(t, SessionStatusCancelled, session.Status)
+		require.Equal(t, "Instructor posted overseas", *session.CancellationReason)
+	}
+
+	_, err = m.Cancel(AuditActor{}, series, "Instructor posted overseas")
+	require.ErrorIs(t, err, ErrEditConflict)
+}
+
+func TestValidateSessionSeries(t *testing.T) {
+	v := validator.New()
+	ValidateSessionSeries(v, newTestSeries("dummy-course-id"))
+	require.True(t, v.Valid())
+
+	v = validator.New()
+	series := newTestSeries("dummy-course-id")
+	series.TimeZone = "Mars/Olympus_Mons"
+	series.Location = ""
+	ValidateSessionSeries(v, series)
+	require.Contains(t, v.Errors, "time_zone")
+	require.Contains(t, v.Errors, "location_text")
+
+	v = validator.New()
+	series = newTestSeries("dummy-course-id")
+	series.Rule = RecurrenceRule{Frequency: FrequencyDaily, Interval: 1, Until: ptr(series.Start.AddDate(1, 0, 0))}
+	ValidateSessionSeries(v, series)
+	require.Contains(t, v.Errors, "rule")
+}
@@ -5,26 +5,64 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
     "github.com/amari03/test1/internal/validator"
 )
 
 type Session struct {
-    ID          string     `json:"id"`
-    CourseID    string     `json:"course_id"`
-    Start       time.Time  `json:"start_datetime"`
-    End         time.Time  `json:"end_datetime"`
-    Location    string     `json:"location_text"`
-    CreatedAt   time.Time  `json:"created_at"`
-    UpdatedAt   *time.Time `json:"updated_at,omitempty"`
-    Version   int32      `json:"version"`
+    ID                  string     `json:"id"`
+    CourseID            string     `json:"course_id"`
+    Start               time.Time  `json:"start_datetime"`
+    End                 time.Time  `json:"end_datetime"`
+    Location            string     `json:"location_text"`
+    RegionID            *string    `json:"region_id,omitempty"`
+    FormationID         *string    `json:"formation_id,omitempty"`
+    PostingID           *string    `json:"posting_id,omitempty"`
+    FacilitatorNotes    *string    `json:"facilitator_notes,omitempty"`
+    CreditHoursOverride *float64   `json:"credit_hours_override,omitempty"`
+    Capacity            *int       `json:"capacity,omitempty"`
+    Status              string     `json:"status"`
+    CancellationReason  *string    `json:"cancellation_reason,omitempty"`
+    SeriesID            *string    `json:"series_id,omitempty"`
+    SeriesIndex         *int       `json:"series_index,omitempty"`
+    Detached            bool       `json:"detached,omitempty"`
+    CreatedAt           time.Time  `json:"created_at"`
+    UpdatedAt           *time.Time `json:"updated_at,omitempty"`
+    Version             int32      `json:"version"`
 }
 
 type SessionModel struct {
     DB *sql.DB
 }
 
+// Session statuses. A session is scheduled until it starts, in progress while
+// it runs, and then completed, which locks its attendance. A postponed
+// session is waiting for a new date, and is scheduled again once it has one.
+const (
+	SessionStatusScheduled  = "scheduled"
+	SessionStatusInProgress = "in_progress"
+	SessionStatusCompleted  = "completed"
+	SessionStatusCancelled  = "cancelled"
+	SessionStatusPostponed  = "postponed"
+)
+
+// sessionTransitions lists the statuses a session can move to from each
+// status. Completed and cancelled sessions are final.
+var sessionTransitions = map[string][]string{
+	SessionStatusScheduled:  {SessionStatusInProgress, SessionStatusCompleted, SessionStatusCancelled, SessionStatusPostponed},
+	SessionStatusInProgress: {SessionStatusCompleted, SessionStatusCancelled},
+	SessionStatusPostponed:  {SessionStatusScheduled, SessionStatusCancelled},
+}
+
+// CanTransitionSession reports whether a session's status can change from one
+// status to another. Staying put is always allowed.
+func CanTransitionSession(from, to string) bool {
+	return from == to || slices.Contains(sessionTransitions[from], to)
+}
+
 // We'll add a basic validator
 func ValidateSession(v *validator.Validator, session *Session) {
 	v.Check(session.CourseID != "", "course_id", "must be provided")
@@ -32,12 +70,77 @@ func ValidateSession(v *validator.Validator, session *Session) {
 	v.Check(!session.End.IsZero(), "end_datetime", "must be provided")
 	v.Check(session.End.After(session.Start), "end_datetime", "must be after start_datetime")
 	v.Check(session.Location != "", "location_text", "must be provided")
+	v.Check(session.FormationID == nil || session.RegionID != nil, "region_id", "must be provided when formation_id is set")
+	if session.FacilitatorNotes != nil {
+		v.Check(len(*session.FacilitatorNotes) <= 2000, "facilitator_notes", "must not be more than 2000 bytes long")
+	}
+	// credit_hours_override is NUMERIC(4, 1), like a course's default hours.
+	if session.CreditHoursOverride != nil {
+		v.Check(*session.CreditHoursOverride >= 0, "credit_hours_override", "must be zero or greater")
+		v.Check(*session.CreditHoursOverride < 1000, "credit_hours_override", "must be less than 1000")
+	}
+	if session.Capacity != nil {
+		v.Check(*session.Capacity > 0, "capacity", "must be greater than zero")
+	}
+	v.Check(validator.In(session.Status, SessionStatusScheduled, SessionStatusInProgress, SessionStatusCompleted, SessionStatusCancelled, SessionStatusPostponed), "status", "must be one of scheduled, in_progress, completed, cancelled or postponed")
+	if session.Status == SessionStatusCancelled {
+		v.Check(session.CancellationReason != nil, "cancellation_reason", "must be provided when cancelling a session")
+	} else {
+		v.Check(session.CancellationReason == nil, "cancellation_reason", "must only be provided when cancelling a session")
+	}
+	if session.CancellationReason != nil {
+		v.Check(len(*session.CancellationReason) <= 500, "cancellation_reason", "must not be more than 500 bytes long")
+	}
 }
 
-func (m SessionModel) Insert(session *Session) error {
+// Insert adds a session, unless another session overlaps it at the same
+// venue, in which case it returns a *ScheduleConflictError. With force set
+// the session is added anyway, and the override is recorded in the audit log.
+func (m SessionModel) Insert(actor AuditActor, session *Session, force bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockSchedule(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	check := scheduleCheck{Start: session.Start, End: session.End, Location: session.Location, Status: session.Status}
+	conflicts, err := checkSchedule(ctx, tx, check, force)
+	if err != nil {
+		return err
+	}
+
+	err = insertSession(ctx, tx, session)
+	if err != nil {
+		return err
+	}
+
+	err = audit(ctx, tx, actor, AuditActionInsert, "session", session.ID, nil, session)
+	if err != nil {
+		return err
+	}
+	err = auditOverride(ctx, tx, actor, "session", session.ID, conflicts)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertSession inserts a session using q, which may be a transaction.
+func insertSession(ctx context.Context, q queryer, session *Session) error {
 	query := `
-        INSERT INTO sessions (course_id, start_datetime, end_datetime, location_text)
-        VALUES ($1, $2, $3, $4)
+        INSERT INTO sessions (course_id, start_datetime, end_datetime, location_text,
+                              region_id, formation_id, posting_id, facilitator_notes, credit_hours_override,
+                              capacity, status, cancellation_reason, series_id, series_index)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
         RETURNING id, created_at, version`
 
 	args := []interface{}{
@@ -45,35 +148,67 @@ func (m SessionModel) Insert(session *Session) error {
 		session.Start, 
 		session.End, 
 		session.Location,
+		session.RegionID,
+		session.FormationID,
+		session.PostingID,
+		session.FacilitatorNotes,
+		session.CreditHoursOverride,
+		session.Capacity,
+		session.Status,
+		session.CancellationReason,
+		session.SeriesID,
+		session.SeriesIndex,
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
 
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(&session.ID, &session.CreatedAt, &session.Version)
+	return q.QueryRowContext(ctx, query, args...).Scan(&session.ID, &session.CreatedAt, &session.Version)
 }
 
-// Get a specific session by ID.
-func (m SessionModel) Get(id string) (*Session, error) {
-	query := `
-        SELECT id, course_id, start_datetime, end_datetime, location_text,
-               created_at, updated_at, version
-        FROM sessions
-        WHERE id = $1`
-
-	var session Session
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+const sessionColumns = `id, course_id, start_datetime, end_datetime, location_text,
+               region_id, formation_id, posting_id, facilitator_notes, credit_hours_override, capacity,
+               status, cancellation_reason, series_id, series_index, detached, created_at, updated_at, version`
 
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+func scanSession(row interface{ Scan(...interface{}) error }, session *Session, extra ...interface{}) error {
+	dest := append(extra,
 		&session.ID,
 		&session.CourseID,
 		&session.Start,
 		&session.End,
 		&session.Location,
+		&session.RegionID,
+		&session.FormationID,
+		&session.PostingID,
+		&session.FacilitatorNotes,
+		&session.CreditHoursOverride,
+		&session.Capacity,
+		&session.Status,
+		&session.CancellationReason,
+		&session.SeriesID,
+		&session.SeriesIndex,
+		&session.Detached,
 		&session.CreatedAt,
 		&session.UpdatedAt,
 		&session.Version,
 	)
+	return row.Scan(dest...)
+}
+
+// Get a specific session by ID.
+func (m SessionModel) Get(id string) (*Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return getSession(ctx, m.DB, id)
+}
+
+// getSession fetches a session using q, which may be a transaction.
+func getSession(ctx context.Context, q queryer, id string) (*Session, error) {
+	query := `
+        SELECT ` + sessionColumns + `
+        FROM sessions
+        WHERE id = $1`
+
+	var session Session
+	err := scanSession(q.QueryRowContext(ctx, query, id), &session)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -85,27 +220,94 @@ func (m SessionModel) Get(id string) (*Session, error) {
 	return &session, nil
 }
 
-// Update a specific session record.
-func (m SessionModel) Update(session *Session) error {
+// Update a specific session record. If the session moves, in time or to
+// another venue, or is back on after being postponed, it is checked for
+// clashes with other sessions at the new venue and for its facilitators and
+// officers, the same way as Insert. It returns ErrInvalidTransition if the
+// session can't move from its current status to the new one. A session in a
+// series is detached from it once it is changed in a way the series controls,
+// such as its time or venue, so that later changes to the series leave it
+// alone; a change of status on its own leaves it in the series.
+func (m SessionModel) Update(actor AuditActor, session *Session, force bool) error {
 	query := `
         UPDATE sessions
         SET course_id = $1, start_datetime = $2, end_datetime = $3, location_text = $4,
-            updated_at = NOW(), version = version + 1
-        WHERE id = $5 AND version = $6
+            region_id = $5, formation_id = $6, posting_id = $7, facilitator_notes = $8,
+            credit_hours_override = $9, capacity = $10, status = $11, cancellation_reason = $12,
+            detached = $13, updated_at = NOW(), version = version + 1
+        WHERE id = $14 AND version = $15
         RETURNING updated_at, version`
 
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockSchedule(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	// A session that has gone is reported as an edit conflict, like any other
+	// version mismatch.
+	current, err := sessionSchedule(ctx, tx, session.ID)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return ErrEditConflict
+		}
+		return err
+	}
+
+	before, err := getSession(ctx, tx, session.ID)
+	if err != nil {
+		return err
+	}
+
+	if !CanTransitionSession(current.Status, session.Status) {
+		return ErrInvalidTransition
+	}
+
+	var conflicts []ScheduleConflict
+	moved := !session.Start.Equal(current.Start) || !session.End.Equal(current.End) || session.Location != current.Location
+	if moved || (!holdsSchedule(current.Status) && holdsSchedule(session.Status)) {
+		check := scheduleCheck{SessionID: session.ID, Start: session.Start, End: session.End, Location: session.Location, Status: session.Status}
+		check.FacilitatorIDs, check.OfficerIDs, err = sessionParticipants(ctx, tx, session.ID)
+		if err != nil {
+			return err
+		}
+
+		conflicts, err = checkSchedule(ctx, tx, check, force)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Only a change to what the series controls takes a session out of it.
+	session.Detached = before.Detached || (before.SeriesID != nil && !sameSession(before, session))
+
 	args := []interface{}{
 		session.CourseID,
 		session.Start,
 		session.End,
 		session.Location,
+		session.RegionID,
+		session.FormationID,
+		session.PostingID,
+		session.FacilitatorNotes,
+		session.CreditHoursOverride,
+		session.Capacity,
+		session.Status,
+		session.CancellationReason,
+		session.Detached,
 		session.ID,
 		session.Version,
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&session.UpdatedAt, &session.Version)
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&session.UpdatedAt, &session.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -114,48 +316,157 @@ func (m SessionModel) Update(session *Session) error {
 			return err
 		}
 	}
-	return nil
+
+	err = audit(ctx, tx, actor, AuditActionUpdate, "session", session.ID, before, session)
+	if err != nil {
+		return err
+	}
+	err = auditOverride(ctx, tx, actor, "session", session.ID, conflicts)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-// Delete a specific session by ID.
-func (m SessionModel) Delete(id string) error {
-    
+// Delete a specific session by ID, along with its facilitators, feedback
+// and enrollments. Only a session that hasn't happened can be deleted: one
+// that is in progress, completed or cancelled, or has any attendance, returns
+// ErrRecordInUse and should be cancelled instead so that its history is kept.
+// Deleting a session in a series excludes its occurrence from the series, so
+// that later changes to the series don't bring it back.
+func (m SessionModel) Delete(actor AuditActor, id string) error {
 	if id == "" {
 		return ErrRecordNotFound
 	}
-	query := `DELETE FROM sessions WHERE id = $1`
+
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, id)
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	rowsAffected, err := result.RowsAffected()
+	defer tx.Rollback()
+
+	query := `
+        SELECT s.status, EXISTS (SELECT 1 FROM attendance a WHERE a.session_id = s.id)
+        FROM sessions s
+        WHERE s.id = $1
+        FOR UPDATE`
+
+	var status string
+	var hasAttendance bool
+	err = tx.QueryRowContext(ctx, query, id).Scan(&status, &hasAttendance)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	if hasAttendance || (status != SessionStatusScheduled && status != SessionStatusPostponed) {
+		return ErrRecordInUse
+	}
+
+	deleted, err := deleteSessions(ctx, tx, actor, "id = $1", id)
 	if err != nil {
 		return err
 	}
-	if rowsAffected == 0 {
+	if len(deleted) == 0 {
 		return ErrRecordNotFound
 	}
-	return nil
+
+	if session := deleted[0]; session.SeriesID != nil {
+		query := `
+            UPDATE session_series
+            SET excluded_indexes = array_append(excluded_indexes, $2)
+            WHERE id = $1`
+
+		_, err = tx.ExecContext(ctx, query, *session.SeriesID, *session.SeriesIndex)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// deleteSessions deletes and audits the sessions matching where, a condition
+// on $1, which is arg. Their attendance, facilitators, feedback and
+// enrollments are deleted and audited first, rather than left to the
+// database to cascade, so that they don't leave the audit log unnoticed.
+func deleteSessions(ctx context.Context, q queryer, actor AuditActor, where, arg string) ([]*Session, error) {
+	children := "session_id IN (SELECT id FROM sessions WHERE " + where + ")"
+	for _, deleteChildren := range []func(context.Context, queryer, AuditActor, string, string) (int, error){
+		deleteAttendance,
+		deleteSessionFacilitators,
+		deleteSessionFeedback,
+		deleteSessionEnrollments,
+	} {
+		_, err := deleteChildren(ctx, q, actor, children, arg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	query := `
+        DELETE FROM sessions
+        WHERE ` + where + `
+        RETURNING ` + sessionColumns
+
+	rows, err := q.QueryContext(ctx, query, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*Session{}
+	for rows.Next() {
+		var session Session
+		if err := scanSession(rows, &session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, &session)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for _, session := range sessions {
+		err = audit(ctx, q, actor, AuditActionDelete, "session", session.ID, session, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sessions, nil
 }
 
-// GetAll returns a slice of all sessions.
-func (m SessionModel) GetAll(location string, courseID string, filters Filters) ([]*Session, Metadata, error) {
+// GetAll returns a slice of all sessions. Sessions can be filtered by region,
+// by series, by status, and by when they start: from is inclusive and to is
+// exclusive, and either may be nil.
+func (m SessionModel) GetAll(location string, courseID string, regionID string, seriesID string, status string, from, to *time.Time, filters Filters) ([]*Session, Metadata, error) {
 	query := fmt.Sprintf(`
         SELECT count(*) OVER(), id, course_id, start_datetime, end_datetime, location_text,
-               created_at, updated_at, version
+               region_id, formation_id, posting_id, facilitator_notes, credit_hours_override, capacity,
+               status, cancellation_reason, series_id, series_index, detached, created_at, updated_at, version
         FROM sessions
         WHERE (to_tsvector('simple', COALESCE(location_text, '')) @@ plainto_tsquery('simple', $1) OR $1 = '')
         AND (course_id::text = $2 OR $2 = '')
+        AND (region_id = $3 OR $3 = '')
+        AND (series_id::text = $4 OR $4 = '')
+        AND (status = $5 OR $5 = '')
+        AND (start_datetime >= $6 OR $6::timestamptz IS NULL)
+        AND (start_datetime < $7 OR $7::timestamptz IS NULL)
         ORDER BY %s %s, id ASC
-        LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+        LIMIT $8 OFFSET $9`, filters.sortColumn(), filters.sortDirection())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []interface{}{location, courseID, filters.limit(), filters.offset()}
+	args := []interface{}{location, courseID, regionID, seriesID, status, from, to, filters.limit(), filters.offset()}
 	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, Metadata{}, err
@@ -173,6 +484,17 @@ func (m SessionModel) GetAll(location string, courseID string, filters Filters)
 			&session.Start,
 			&session.End,
 			&session.Location,
+			&session.RegionID,
+			&session.FormationID,
+			&session.PostingID,
+			&session.FacilitatorNotes,
+			&session.CreditHoursOverride,
+			&session.Capacity,
+			&session.Status,
+			&session.CancellationReason,
+			&session.SeriesID,
+			&session.SeriesIndex,
+			&session.Detached,
 			&session.CreatedAt,
 			&session.UpdatedAt,
 			&session.Version,
@@ -189,4 +511,87 @@ func (m SessionModel) GetAll(location string, courseID string, filters Filters)
 
 	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
 	return sessions, metadata, nil
-}
\ No newline at end of file
+}
+
+// DefaultCreditHours returns the hours an officer is credited with for
+// attending a session, unless they are given some other amount: the session's
+// credit_hours_override if it has one, otherwise its course's default hours.
+func (m SessionModel) DefaultCreditHours(id string) (float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return sessionDefaultCreditHours(ctx, m.DB, id)
+}
+
+// sessionDefaultCreditHours looks up a session's default credit using q, which
+// may be a transaction.
+func sessionDefaultCreditHours(ctx context.Context, q queryer, id string) (float64, error) {
+	query := `
+        SELECT COALESCE(s.credit_hours_override, c.default_credit_hours)
+        FROM sessions s
+        INNER JOIN courses c ON c.id = s.course_id
+        WHERE s.id = $1`
+
+	var hours float64
+	err := q.QueryRowContext(ctx, query, id).Scan(&hours)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+	return hours, nil
+}
+
+// SessionContact is someone to tell about a change to a session.
+type SessionContact struct {
+	Name  string
+	Email string
+}
+
+// Contacts returns the people to tell when a session is cancelled or moved:
+// its enrolled officers and its facilitators. Each address is only returned
+// once. People without an email address can't be told; they are left out and
+// counted in skipped.
+func (m SessionModel) Contacts(id string) (contacts []SessionContact, skipped int, err error) {
+	query := `
+        SELECT o.first_name || ' ' || o.last_name AS name, COALESCE(o.email, '') AS email
+        FROM session_enrollments e
+        INNER JOIN officers o ON o.id = e.officer_id
+        WHERE e.session_id = $1 AND e.status = 'enrolled'
+        UNION ALL
+        SELECT f.first_name || ' ' || f.last_name, COALESCE(f.email, '')
+        FROM session_facilitators sf
+        INNER JOIN facilitators f ON f.id = sf.facilitator_id
+        WHERE sf.session_id = $1
+        ORDER BY email, name`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	contacts = []SessionContact{}
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var contact SessionContact
+		if err := rows.Scan(&contact.Name, &contact.Email); err != nil {
+			return nil, 0, err
+		}
+
+		switch key := strings.ToLower(contact.Email); {
+		case key == "":
+			skipped++
+		case !seen[key]:
+			seen[key] = true
+			contacts = append(contacts, contact)
+		}
+	}
+	return contacts, skipped, rows.Err()
+}
@@ -28,21 +28,6 @@ func setupSessionsTestDB(t *testing.T) (*sql.DB, string) {
 	db.Exec(`CREATE TABLE IF NOT EXISTS users (id UUID PRIMARY KEY DEFAULT gen_random_uuid(), email TEXT UNIQUE NOT NULL);`)
 	db.Exec(`CREATE TABLE IF NOT EXISTS courses (id UUID PRIMARY KEY DEFAULT gen_random_uuid(), title TEXT NOT NULL, category TEXT NOT NULL, default_credit_hours NUMERIC NOT NULL, created_by_user_id UUID NOT NULL REFERENCES users(id), created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(), updated_at TIMESTAMPTZ, version INTEGER NOT NULL DEFAULT 1);`)
 
-	// Create the sessions table.
-	createTableSQL := `
-    CREATE TABLE IF NOT EXISTS sessions (
-        id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-        course_id UUID NOT NULL REFERENCES courses(id),
-        start_datetime TIMESTAMPTZ NOT NULL,
-        end_datetime TIMESTAMPTZ NOT NULL,
-        location_text TEXT NOT NULL,
-        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-        updated_at TIMESTAMPTZ,
-        version INTEGER NOT NULL DEFAULT 1
-    );`
-	_, err = db.Exec(createTableSQL)
-	require.NoError(t, err)
-
 	// Insert dummy user and course to get a valid course_id.
 	var userID string
 	err = db.QueryRow(`INSERT INTO users (email) VALUES ('sessionuser@example.com') ON CONFLICT (email) DO NOTHING RETURNING id;`).Scan(&userID)
@@ -57,18 +42,79 @@ func setupSessionsTestDB(t *testing.T) (*sql.DB, string) {
 
 	// Cleanup runs in reverse order of creation.
 	t.Cleanup(func() {
-		_, err := db.Exec("DROP TABLE IF EXISTS sessions;")
-		require.NoError(t, err)
-		_, err = db.Exec("DROP TABLE IF EXISTS courses;")
+		_, err := db.Exec("DROP TABLE IF EXISTS courses;")
 		require.NoError(t, err)
 		_, err = db.Exec("DROP TABLE IF EXISTS users;")
 		require.NoError(t, err)
 		db.Close()
 	})
 
+	// Moving a session checks its facilitators and officers for clashes, and
+	// deleting one takes its records with it, so these tables need to exist.
+	// Their cleanups are registered later, so they run first.
+	createSessionsTable(t, db)
+	createSessionRecordTables(t, db)
+	createAuditEventsTable(t, db)
+
 	return db, courseID
 }
 
+// createSessionsTable adds the sessions table to a test DB that has courses.
+func createSessionsTable(t *testing.T, db *sql.DB) {
+	createTableSQL := `
+    CREATE TABLE IF NOT EXISTS sessions (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+        course_id UUID NOT NULL REFERENCES courses(id),
+        start_datetime TIMESTAMPTZ NOT NULL,
+        end_datetime TIMESTAMPTZ NOT NULL,
+        location_text TEXT NOT NULL,
+        region_id TEXT,
+        formation_id TEXT,
+        posting_id TEXT,
+        facilitator_notes TEXT,
+        credit_hours_override NUMERIC(4, 1),
+        capacity INTEGER,
+        status TEXT NOT NULL DEFAULT 'scheduled',
+        cancellation_reason TEXT,
+        series_id UUID,
+        series_index INTEGER,
+        detached BOOLEAN NOT NULL DEFAULT false,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        updated_at TIMESTAMPTZ,
+        version INTEGER NOT NULL DEFAULT 1
+    );`
+	_, err := db.Exec(createTableSQL)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_, err := db.Exec("DROP TABLE IF EXISTS sessions;")
+		require.NoError(t, err)
+	})
+}
+
+// createSessionRecordTables adds the tables of records that belong to a
+// session, without their foreign keys, for tests of anything that checks or
+// deletes them.
+func createSessionRecordTables(t *testing.T, db *sql.DB) {
+	tables := []string{
+		`CREATE TABLE IF NOT EXISTS attendance (id UUID PRIMARY KEY DEFAULT gen_random_uuid(), officer_id UUID NOT NULL, session_id UUID NOT NULL, status TEXT NOT NULL DEFAULT 'attended', credited_hours NUMERIC NOT NULL DEFAULT 0, created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(), version INTEGER NOT NULL DEFAULT 1);`,
+		`CREATE TABLE IF NOT EXISTS session_facilitators (id UUID PRIMARY KEY DEFAULT gen_random_uuid(), session_id UUID NOT NULL, facilitator_id UUID NOT NULL, role TEXT, version INTEGER NOT NULL DEFAULT 1);`,
+		`CREATE TABLE IF NOT EXISTS session_feedback (id UUID PRIMARY KEY DEFAULT gen_random_uuid(), session_id UUID NOT NULL, officer_id UUID NOT NULL, facilitator_id UUID, rating INTEGER NOT NULL, comments TEXT, created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(), version INTEGER NOT NULL DEFAULT 1);`,
+		`CREATE TABLE IF NOT EXISTS session_enrollments (id UUID PRIMARY KEY DEFAULT gen_random_uuid(), session_id UUID NOT NULL, officer_id UUID NOT NULL, status TEXT NOT NULL, created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(), updated_at TIMESTAMPTZ, version INTEGER NOT NULL DEFAULT 1);`,
+	}
+	for _, table := range tables {
+		_, err := db.Exec(table)
+		require.NoError(t, err)
+	}
+
+	t.Cleanup(func() {
+		for _, table := range []string{"attendance", "session_facilitators", "session_feedback", "session_enrollments"} {
+			_, err := db.Exec("DROP TABLE IF EXISTS " + table + ";")
+			require.NoError(t, err)
+		}
+	})
+}
+
 // newTestSession is a helper to create a valid session instance for testing.
 func newTestSession(t *testing.T, courseID string) *Session {
 	return &Session{
@@ -76,6 +122,7 @@ func newTestSession(t *testing.T, courseID string) *Session {
 		Start:    time.Now().Add(24 * time.Hour),
 		End:      time.Now().Add(32 * time.Hour),
 		Location: "Training Room A",
+		Status:   SessionStatusScheduled,
 	}
 }
 
@@ -85,7 +132,7 @@ func TestSessionModel_Insert(t *testing.T) {
 
 	session := newTestSession(t, courseID)
 
-	err := m.Insert(session)
+	err := m.Insert(AuditActor{}, session, false)
 	require.NoError(t, err)
 
 	// Check that the database populated the ID, CreatedAt, and Version.
@@ -107,7 +154,7 @@ func TestSessionModel_Get(t *testing.T) {
 
 	// Insert a record to test Get.
 	session := newTestSession(t, courseID)
-	err := m.Insert(session)
+	err := m.Insert(AuditActor{}, session, false)
 	require.NoError(t, err)
 
 	// Test successful Get.
@@ -134,14 +181,18 @@ func TestSessionModel_Update(t *testing.T) {
 	m := SessionModel{DB: db}
 
 	session := newTestSession(t, courseID)
-	err := m.Insert(session)
+	err := m.Insert(AuditActor{}, session, false)
 	require.NoError(t, err)
 
 	// Update some fields.
 	session.Location = "Auditorium B"
 	session.End = session.End.Add(time.Hour)
+	session.RegionID = ptr("north")
+	session.FormationID = ptr("north-1")
+	session.FacilitatorNotes = ptr("Bring the projector")
+	session.CreditHoursOverride = ptr(2.5)
 
-	err = m.Update(session)
+	err = m.Update(AuditActor{}, session, false)
 	require.NoError(t, err)
 
 	// Check version and updated_at.
@@ -154,11 +205,16 @@ func TestSessionModel_Update(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, "Auditorium B", fetched.Location)
 	require.Equal(t, session.End.Unix(), fetched.End.Unix())
+	require.Equal(t, "north", *fetched.RegionID)
+	require.Equal(t, "north-1", *fetched.FormationID)
+	require.Nil(t, fetched.PostingID)
+	require.Equal(t, "Bring the projector", *fetched.FacilitatorNotes)
+	require.Equal(t, 2.5, *fetched.CreditHoursOverride)
 	require.Equal(t, int32(2), fetched.Version)
 
 	// Test for edit conflict.
 	session.Version = 1
-	err = m.Update(session)
+	err = m.Update(AuditActor{}, session, false)
 	require.Error(t, err)
 	require.True(t, errors.Is(err, ErrEditConflict))
 }
@@ -168,12 +224,31 @@ func TestSessionModel_Delete(t *testing.T) {
 	m := SessionModel{DB: db}
 
 	session := newTestSession(t, courseID)
-	err := m.Insert(session)
+	err := m.Insert(AuditActor{}, session, false)
+	require.NoError(t, err)
+
+	// Deleting a session takes its facilitators and enrollments with it, and
+	// audits them along with the session.
+	sf := &SessionFacilitator{SessionID: session.ID, FacilitatorID: "0b7cfe0e-4a4b-4f7e-9a0e-1b2c3d4e5f60"}
+	err = SessionFacilitatorModel{DB: db}.Insert(AuditActor{}, sf, false)
 	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO session_enrollments (officer_id, session_id, status) VALUES (gen_random_uuid(), $1, 'enrolled')`, session.ID)
+	require.NoError(t, err)
+
+	requestID := "req-delete"
+	err = m.Delete(AuditActor{RequestID: &requestID}, session.ID)
+	require.NoError(t, err)
+
+	var deleted []string
+	for _, event := range auditEvents(t, db, AuditEventFilter{Action: AuditActionDelete, RequestID: requestID}) {
+		deleted = append(deleted, event.EntityType)
+	}
+	require.ElementsMatch(t, []string{"session", "session_facilitator", "session_enrollment"}, deleted)
 
-	// Test successful deletion.
-	err = m.Delete(session.ID)
+	var facilitators int
+	err = db.QueryRow(`SELECT COUNT(*) FROM session_facilitators WHERE session_id = $1`, session.ID).Scan(&facilitators)
 	require.NoError(t, err)
+	require.Zero(t, facilitators)
 
 	// Verify it's gone.
 	_, err = m.Get(session.ID)
@@ -181,9 +256,27 @@ func TestSessionModel_Delete(t *testing.T) {
 	require.True(t, errors.Is(err, ErrRecordNotFound))
 
 	// Test deleting a non-existent record.
-	err = m.Delete("f47ac10b-58cc-4372-a567-0e02b2c3d479")
+	err = m.Delete(AuditActor{}, "f47ac10b-58cc-4372-a567-0e02b2c3d479")
 	require.Error(t, err)
 	require.True(t, errors.Is(err, ErrRecordNotFound))
+
+	// A session that has happened is kept, and has to be cancelled instead.
+	completed := newTestSession(t, courseID)
+	completed.Status = SessionStatusCompleted
+	err = m.Insert(AuditActor{}, completed, false)
+	require.NoError(t, err)
+	err = m.Delete(AuditActor{}, completed.ID)
+	require.ErrorIs(t, err, ErrRecordInUse)
+
+	attended := newTestSession(t, courseID)
+	attended.Start = attended.Start.Add(48 * time.Hour)
+	attended.End = attended.End.Add(48 * time.Hour)
+	err = m.Insert(AuditActor{}, attended, false)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO attendance (officer_id, session_id) VALUES (gen_random_uuid(), $1)`, attended.ID)
+	require.NoError(t, err)
+	err = m.Delete(AuditActor{}, attended.ID)
+	require.ErrorIs(t, err, ErrRecordInUse)
 }
 
 func TestSessionModel_GetAll(t *testing.T) {
@@ -198,51 +291,291 @@ func TestSessionModel_GetAll(t *testing.T) {
 	// Insert records for testing.
 	session1 := Session{CourseID: courseID1, Start: time.Now().Add(10 * time.Hour), End: time.Now().Add(12 * time.Hour), Location: "Main Hall"}
 	session2 := Session{CourseID: courseID2, Start: time.Now().Add(20 * time.Hour), End: time.Now().Add(22 * time.Hour), Location: "Room 101"}
-	session3 := Session{CourseID: courseID1, Start: time.Now().Add(30 * time.Hour), End: time.Now().Add(32 * time.Hour), Location: "Main Hall"}
-	require.NoError(t, m.Insert(&session1))
-	require.NoError(t, m.Insert(&session2))
-	require.NoError(t, m.Insert(&session3))
+	session3 := Session{CourseID: courseID1, Start: time.Now().Add(30 * time.Hour), End: time.Now().Add(32 * time.Hour), Location: "Main Hall", RegionID: ptr("north")}
+	err = m.Insert(AuditActor{}, &session1, false)
+	require.NoError(t, err)
+	err = m.Insert(AuditActor{}, &session2, false)
+	require.NoError(t, err)
+	err = m.Insert(AuditActor{}, &session3, false)
+	require.NoError(t, err)
 
 	safelist := []string{"id", "start_datetime", "location_text", "-id", "-start_datetime", "-location_text"}
 	filters := Filters{Page: 1, PageSize: 20, Sort: "id", SortSafelist: safelist}
 
 	// Test case 1: Get all records.
-	allSessions, metadata, err := m.GetAll("", "", filters)
+	allSessions, metadata, err := m.GetAll("", "", "", "", "", nil, nil, filters)
 	require.NoError(t, err)
 	require.Len(t, allSessions, 3)
 	require.Equal(t, int64(3), metadata.TotalRecords)
 
 	// Test case 2: Filter by location.
-	filtered, metadata, err := m.GetAll("Main Hall", "", filters)
+	filtered, metadata, err := m.GetAll("Main Hall", "", "", "", "", nil, nil, filters)
 	require.NoError(t, err)
 	require.Len(t, filtered, 2)
 	require.Equal(t, int64(2), metadata.TotalRecords)
 
 	// Test case 3: Filter by course_id.
-	filtered, metadata, err = m.GetAll("", courseID2, filters)
+	filtered, metadata, err = m.GetAll("", courseID2, "", "", "", nil, nil, filters)
 	require.NoError(t, err)
 	require.Len(t, filtered, 1)
 	require.Equal(t, "Room 101", filtered[0].Location)
 	require.Equal(t, int64(1), metadata.TotalRecords)
 
-	// Test case 4: Sorting.
+	// Test case 4: Filter by region and by start time.
+	filtered, metadata, err = m.GetAll("", "", "north", "", "", nil, nil, filters)
+	require.NoError(t, err)
+	require.Len(t, filtered, 1)
+	require.Equal(t, session3.ID, filtered[0].ID)
+	require.Equal(t, "north", *filtered[0].RegionID)
+
+	from := time.Now().Add(15 * time.Hour)
+	to := time.Now().Add(25 * time.Hour)
+	filtered, metadata, err = m.GetAll("", "", "", "", "", &from, &to, filters)
+	require.NoError(t, err)
+	require.Len(t, filtered, 1)
+	require.Equal(t, session2.ID, filtered[0].ID)
+	require.Equal(t, int64(1), metadata.TotalRecords)
+
+	filtered, _, err = m.GetAll("", "", "", "", "", &from, nil, filters)
+	require.NoError(t, err)
+	require.Len(t, filtered, 2)
+
+	// Test case 5: Sorting.
 	filters.Sort = "-start_datetime"
-	sorted, _, err := m.GetAll("", "", filters)
+	sorted, _, err := m.GetAll("", "", "", "", "", nil, nil, filters)
 	require.NoError(t, err)
 	require.Len(t, sorted, 3)
 	require.Equal(t, session3.ID, sorted[0].ID) // session3 is the latest, so it should be first.
 
-	// Test case 5: Pagination.
+	// Test case 6: Pagination.
 	filters.Page = 2
 	filters.PageSize = 2
 	filters.Sort = "start_datetime"
-	paginated, metadata, err := m.GetAll("", "", filters)
+	paginated, metadata, err := m.GetAll("", "", "", "", "", nil, nil, filters)
 	require.NoError(t, err)
 	require.Len(t, paginated, 1)
 	require.Equal(t, session3.ID, paginated[0].ID) // Page 1: session1, session2. Page 2: session3
 	require.Equal(t, int64(3), metadata.TotalRecords)
 }
 
+func TestSessionModel_DefaultCreditHours(t *testing.T) {
+	db, courseID := setupSessionsTestDB(t)
+	m := SessionModel{DB: db}
+
+	session := newTestSession(t, courseID)
+	err := m.Insert(AuditActor{}, session, false)
+	require.NoError(t, err)
+
+	// Without an override, the course's default hours apply.
+	hours, err := m.DefaultCreditHours(session.ID)
+	require.NoError(t, err)
+	require.Equal(t, 8.0, hours)
+
+	session.CreditHoursOverride = ptr(3.5)
+	err = m.Update(AuditActor{}, session, false)
+	require.NoError(t, err)
+
+	hours, err = m.DefaultCreditHours(session.ID)
+	require.NoError(t, err)
+	require.Equal(t, 3.5, hours)
+
+	_, err = m.DefaultCreditHours("f47ac10b-58cc-4372-a567-0e02b2c3d479")
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestSessionModel_ScheduleConflicts(t *testing.T) {
+	db, courseID := setupSessionsTestDB(t)
+	m := SessionModel{DB: db}
+	sf := SessionFacilitatorModel{DB: db}
+	attendance := AttendanceModel{DB: db}
+
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	first := &Session{CourseID: courseID, Start: start, End: start.Add(2 * time.Hour), Location: "Range 1"}
+	err := m.Insert(AuditActor{}, first, false)
+	require.NoError(t, err)
+
+	// Same venue, overlapping: refused with the clashing session listed.
+	second := &Session{CourseID: courseID, Start: start.Add(time.Hour), End: start.Add(3 * time.Hour), Location: " range 1"}
+	err = m.Insert(AuditActor{}, second, false)
+	var conflictErr *ScheduleConflictError
+	require.ErrorAs(t, err, &conflictErr)
+	require.Len(t, conflictErr.Conflicts, 1)
+	require.Equal(t, ConflictVenue, conflictErr.Conflicts[0].Type)
+	require.Equal(t, first.ID, conflictErr.Conflicts[0].SessionID)
+	require.Empty(t, second.ID)
+
+	// Elsewhere, or back to back, is fine.
+	second.Location = "Range 2"
+	err = m.Insert(AuditActor{}, second, false)
+	require.NoError(t, err)
+
+	third := &Session{CourseID: courseID, Start: start.Add(2 * time.Hour), End: start.Add(4 * time.Hour), Location: "Range 1"}
+	err = m.Insert(AuditActor{}, third, false)
+	require.NoError(t, err)
+
+	// A facilitator can't be in the first two at once, unless forced.
+	facilitatorID := "0b7cfe0e-4a4b-4f7e-9a0e-1b2c3d4e5f60"
+	err = sf.Insert(AuditActor{}, &SessionFacilitator{SessionID: first.ID, FacilitatorID: facilitatorID}, false)
+	require.NoError(t, err)
+
+	err = sf.Insert(AuditActor{}, &SessionFacilitator{SessionID: second.ID, FacilitatorID: facilitatorID}, false)
+	require.ErrorAs(t, err, &conflictErr)
+	require.Equal(t, ConflictFacilitator, conflictErr.Conflicts[0].Type)
+	require.Equal(t, facilitatorID, *conflictErr.Conflicts[0].FacilitatorID)
+
+	forced := &SessionFacilitator{SessionID: second.ID, FacilitatorID: facilitatorID}
+	err = sf.Insert(AuditActor{}, forced, true)
+	require.NoError(t, err)
+	require.Len(t, auditedOverride(t, db, "session_facilitator", forced.ID), 1)
+
+	// Likewise an officer.
+	officerID := "9f8e7d6c-5b4a-4321-8fed-cba987654321"
+	_, err = db.Exec(`INSERT INTO attendance (officer_id, session_id) VALUES ($1, $2)`, officerID, third.ID)
+	require.NoError(t, err)
+
+	err = attendance.Insert(AuditActor{}, &Attendance{OfficerID: officerID, SessionID: second.ID, Status: "attended"}, false)
+	require.ErrorAs(t, err, &conflictErr)
+	require.Equal(t, ConflictOfficer, conflictErr.Conflicts[0].Type)
+	require.Equal(t, third.ID, conflictErr.Conflicts[0].SessionID)
+
+	// Moving the third session onto the first clashes on venue and
+	// facilitator alike; changing anything else doesn't check.
+	third.FacilitatorNotes = ptr("Bring ear defenders")
+	err = m.Update(AuditActor{}, third, false)
+	require.NoError(t, err)
+
+	_, err = db.Exec(`INSERT INTO session_facilitators (session_id, facilitator_id) VALUES ($1, $2)`, third.ID, facilitatorID)
+	require.NoError(t, err)
+	third.Start = start
+	err = m.Update(AuditActor{}, third, false)
+	require.ErrorAs(t, err, &conflictErr)
+	require.Len(t, conflictErr.Conflicts, 3) // first's venue and facilitator, second's facilitator
+
+	err = m.Update(AuditActor{}, third, true)
+	require.NoError(t, err)
+	require.Len(t, auditedOverride(t, db, "session", third.ID), 3)
+	require.Equal(t, int32(3), third.Version)
+}
+
+func TestSessionModel_Status(t *testing.T) {
+	db, courseID := setupSessionsTestDB(t)
+	m := SessionModel{DB: db}
+
+	session := newTestSession(t, courseID)
+	err := m.Insert(AuditActor{}, session, false)
+	require.NoError(t, err)
+
+	// A postponed session gives up its venue to another one.
+	session.Status = SessionStatusPostponed
+	err = m.Update(AuditActor{}, session, false)
+	require.NoError(t, err)
+
+	other := newTestSession(t, courseID)
+	err = m.Insert(AuditActor{}, other, false)
+	require.NoError(t, err)
+
+	// Putting it back on, even at the same time and place, checks again.
+	session.Status = SessionStatusScheduled
+	err = m.Update(AuditActor{}, session, false)
+	var conflictErr *ScheduleConflictError
+	require.ErrorAs(t, err, &conflictErr)
+	require.Equal(t, other.ID, conflictErr.Conflicts[0].SessionID)
+
+	session.Status = SessionStatusCancelled
+	session.CancellationReason = ptr("Range closed for maintenance")
+	err = m.Update(AuditActor{}, session, false)
+	require.NoError(t, err)
+
+	fetched, err := m.Get(session.ID)
+	require.NoError(t, err)
+	require.Equal(t, SessionStatusCancelled, fetched.Status)
+	require.Equal(t, "Range closed for maintenance", *fetched.CancellationReason)
+
+	// A cancelled session stays cancelled.
+	fetched.Status = SessionStatusScheduled
+	fetched.CancellationReason = nil
+	err = m.Update(AuditActor{}, fetched, false)
+	require.ErrorIs(t, err, ErrInvalidTransition)
+
+	filters := Filters{Page: 1, PageSize: 10, Sort: "start_datetime", SortSafelist: []string{"start_datetime"}}
+	cancelled, _, err := m.GetAll("", "", "", "", SessionStatusCancelled, nil, nil, filters)
+	require.NoError(t, err)
+	require.Len(t, cancelled, 1)
+	require.Equal(t, session.ID, cancelled[0].ID)
+}
+
+func TestSessionModel_Contacts(t *testing.T) {
+	db, courseID := setupSessionsTestDB(t)
+	m := SessionModel{DB: db}
+
+	for _, table := range []string{
+		`CREATE TABLE IF NOT EXISTS officers (id UUID PRIMARY KEY DEFAULT gen_random_uuid(), first_name TEXT NOT NULL, last_name TEXT NOT NULL, email TEXT);`,
+		`CREATE TABLE IF NOT EXISTS facilitators (id UUID PRIMARY KEY DEFAULT gen_random_uuid(), first_name TEXT NOT NULL, last_name TEXT NOT NULL, email TEXT);`,
+	} {
+		_, err := db.Exec(table)
+		require.NoError(t, err)
+	}
+	t.Cleanup(func() {
+		db.Exec("DROP TABLE IF EXISTS officers;")
+		db.Exec("DROP TABLE IF EXISTS facilitators;")
+	})
+
+	session := newTestSession(t, courseID)
+	require.NoError(t, m.Insert(AuditActor{}, session, false))
+
+	// An officer who also facilitates is only emailed once, and people
+	// without an address are counted rather than emailed.
+	_, err := db.Exec(`
+        WITH o AS (
+            INSERT INTO officers (first_name, last_name, email)
+            VALUES ('Jane', 'Doe', 'jane@example.com'), ('John', 'Smith', NULL)
+            RETURNING id
+        )
+        INSERT INTO session_enrollments (session_id, officer_id, status)
+        SELECT $1, id, 'enrolled' FROM o`, session.ID)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+        WITH f AS (
+            INSERT INTO facilitators (first_name, last_name, email)
+            VALUES ('Jane', 'Doe', 'JANE@example.com'), ('Ann', 'Lee', NULL)
+            RETURNING id
+        )
+        INSERT INTO session_facilitators (session_id, facilitator_id)
+        SELECT $1, id FROM f`, session.ID)
+	require.NoError(t, err)
+
+	contacts, skipped, err := m.Contacts(session.ID)
+	require.NoError(t, err)
+	require.Len(t, contacts, 1)
+	require.Equal(t, "Jane Doe", contacts[0].Name)
+	require.Equal(t, 2, skipped)
+}
+
+func TestCanTransitionSession(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{SessionStatusScheduled, SessionStatusScheduled, true},
+		{SessionStatusScheduled, SessionStatusInProgress, true},
+		{SessionStatusScheduled, SessionStatusCompleted, true},
+		{SessionStatusScheduled, SessionStatusPostponed, true},
+		{SessionStatusInProgress, SessionStatusCompleted, true},
+		{SessionStatusInProgress, SessionStatusPostponed, false},
+		{SessionStatusInProgress, SessionStatusScheduled, false},
+		{SessionStatusPostponed, SessionStatusScheduled, true},
+		{SessionStatusPostponed, SessionStatusCancelled, true},
+		{SessionStatusPostponed, SessionStatusCompleted, false},
+		{SessionStatusCompleted, SessionStatusInProgress, false},
+		{SessionStatusCompleted, SessionStatusCancelled, false},
+		{SessionStatusCancelled, SessionStatusScheduled, false},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.want, CanTransitionSession(tt.from, tt.to), "%s to %s", tt.from, tt.to)
+	}
+}
+
 func TestValidateSession(t *testing.T) {
 	v := validator.New()
 	session := &Session{
@@ -271,4 +604,30 @@ func TestValidateSession(t *testing.T) {
 	validSession := newTestSession(t, "dummy-course-id")
 	ValidateSession(v, validSession)
 	require.True(t, v.Valid())
+
+	// A formation needs its region, and the override must fit NUMERIC(4, 1).
+	v = validator.New()
+	validSession.FormationID = ptr("north-1")
+	validSession.CreditHoursOverride = ptr(1000.0)
+	ValidateSession(v, validSession)
+	require.Contains(t, v.Errors, "region_id")
+	require.Contains(t, v.Errors, "credit_hours_override")
+
+	// Cancelling needs a reason, and only cancelling has one.
+	v = validator.New()
+	validSession = newTestSession(t, "dummy-course-id")
+	validSession.Status = SessionStatusCancelled
+	ValidateSession(v, validSession)
+	require.Contains(t, v.Errors, "cancellation_reason")
+
+	v = validator.New()
+	validSession.Status = SessionStatusPostponed
+	validSession.CancellationReason = ptr("Bad weather")
+	ValidateSession(v, validSession)
+	require.Contains(t, v.Errors, "cancellation_reason")
+
+	v = validator.New()
+	validSession.Status = "delayed"
+	ValidateSession(v, validSession)
+	require.Contains(t, v.Errors, "status")
 }
\ No newline at end of file
@@ -6,6 +6,7 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/base32"
+	"errors"
 	"time"
 
 	"github.com/amari03/test1/internal/validator"
@@ -16,14 +17,29 @@ const (
 	ScopeActivation     = "activation"
 	ScopeAuthentication = "authentication"
 	ScopePasswordReset	= "password-reset"
+	ScopeRefresh        = "refresh"
+	ScopeMFAPending     = "mfa-pending"
+	ScopeInvitation     = "invitation"
+	ScopeEmailChange    = "email-change"
 )
 
+// Token is a token issued to a user. The plaintext is only known when the
+// token is first generated; tokens read back from the database have just the
+// hash.
+//
+// The authentication and refresh tokens issued by one login share a FamilyID,
+// which carries over each time the refresh token is rotated, so that the whole
+// login can be revoked at once.
 type Token struct {
-	Plaintext string    `json:"token"`
-	Hash      []byte    `json:"-"`
-	UserID    string    `json:"-"`
-	Expiry    time.Time `json:"expiry"`
-	Scope     string    `json:"-"`
+	ID         string     `json:"id"`
+	Plaintext  string     `json:"token,omitempty"`
+	Hash       []byte     `json:"-"`
+	UserID     string     `json:"-"`
+	Expiry     time.Time  `json:"expiry"`
+	Scope      string     `json:"-"`
+	FamilyID   string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
 }
 
 func generateToken(userID string, ttl time.Duration, scope string) (*Token, error) {
@@ -70,19 +86,248 @@ func (m TokenModel) New(userID string, ttl time.Duration, scope string) (*Token,
 
 // Insert adds a new token record to the tokens table.
 func (m TokenModel) Insert(token *Token) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return insertToken(ctx, m.DB, token)
+}
+
+func insertToken(ctx context.Context, q queryer, token *Token) error {
+	query := `
+        INSERT INTO tokens (hash, user_id, expiry, scope, family_id)
+        VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid)
+        RETURNING id, created_at`
+
+	args := []any{token.Hash, token.UserID, token.Expiry, token.Scope, token.FamilyID}
+
+	return q.QueryRowContext(ctx, query, args...).Scan(&token.ID, &token.CreatedAt)
+}
+
+// NewLogin issues the authentication and refresh tokens for a new login, as
+// a new token family.
+func (m TokenModel) NewLogin(userID string, accessTTL, refreshTTL time.Duration) (access, refresh *Token, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	var familyID string
+	err = tx.QueryRowContext(ctx, `SELECT gen_random_uuid()`).Scan(&familyID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	access, refresh, err = newTokenPair(ctx, tx, userID, familyID, accessTTL, refreshTTL)
+	if err != nil {
+		return nil, nil, err
+	}
+	return access, refresh, tx.Commit()
+}
+
+// Refresh rotates a refresh token: it is marked as used and a new
+// authentication and refresh token are issued in the same family. Used
+// refresh tokens are kept until they expire so that reuse can be detected. If
+// one is presented again it has most likely been stolen, so the whole family
+// is revoked and ErrTokenReused returned. Unknown or expired tokens give
+// ErrRecordNotFound.
+func (m TokenModel) Refresh(refreshPlaintext string, accessTTL, refreshTTL time.Duration) (access, refresh *Token, err error) {
+	tokenHash := sha256.Sum256([]byte(refreshPlaintext))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock the token so that two concurrent refreshes can't both rotate it.
+	query := `
+        SELECT user_id, family_id, used_at IS NOT NULL
+        FROM tokens
+        WHERE hash = $1 AND scope = $2 AND expiry > NOW()
+        FOR UPDATE`
+
+	var userID, familyID string
+	var used bool
+	err = tx.QueryRowContext(ctx, query, tokenHash[:], ScopeRefresh).Scan(&userID, &familyID, &used)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil, ErrRecordNotFound
+		default:
+			return nil, nil, err
+		}
+	}
+
+	if used {
+		_, err = tx.ExecContext(ctx, `DELETE FROM tokens WHERE family_id = $1`, familyID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err = tx.Commit(); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrTokenReused
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE tokens SET used_at = NOW() WHERE hash = $1`, tokenHash[:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	access, refresh, err = newTokenPair(ctx, tx, userID, familyID, accessTTL, refreshTTL)
+	if err != nil {
+		return nil, nil, err
+	}
+	return access, refresh, tx.Commit()
+}
+
+func newTokenPair(ctx context.Context, q queryer, userID, familyID string, accessTTL, refreshTTL time.Duration) (access, refresh *Token, err error) {
+	access, err = generateToken(userID, accessTTL, ScopeAuthentication)
+	if err != nil {
+		return nil, nil, err
+	}
+	refresh, err = generateToken(userID, refreshTTL, ScopeRefresh)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, token := range []*Token{access, refresh} {
+		token.FamilyID = familyID
+		err = insertToken(ctx, q, token)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return access, refresh, nil
+}
+
+// GetAllForUser returns a user's unexpired tokens in a scope, newest first.
+func (m TokenModel) GetAllForUser(scope string, userID string) ([]*Token, error) {
 	query := `
-        INSERT INTO tokens (hash, user_id, expiry, scope)
-        VALUES ($1, $2, $3, $4)`
+        SELECT id, hash, user_id, expiry, scope, created_at, last_used_at
+        FROM tokens
+        WHERE scope = $1 AND user_id = $2 AND expiry > NOW()
+        ORDER BY created_at DESC, id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, scope, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*Token{}
+	for rows.Next() {
+		var token Token
+		err := rows.Scan(
+			&token.ID,
+			&token.Hash,
+			&token.UserID,
+			&token.Expiry,
+			&token.Scope,
+			&token.CreatedAt,
+			&token.LastUsedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, &token)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
 
-	args := []any{token.Hash, token.UserID, token.Expiry, token.Scope}
+// Reissue gives an unexpired token a new plaintext, replacing its hash, so
+// that emails can be queued with just the token's ID and the plaintext made
+// when they are sent. Any earlier plaintext stops working. It returns
+// ErrRecordNotFound if the token has expired or been deleted, e.g. because it
+// was used.
+func (m TokenModel) Reissue(id string) (*Token, error) {
+	token, err := generateToken("", 0, "")
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+        UPDATE tokens
+        SET hash = $1
+        WHERE id = $2 AND expiry > NOW()
+        RETURNING id, user_id, expiry, scope, created_at`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	_, err := m.DB.ExecContext(ctx, query, args...)
+	err = m.DB.QueryRowContext(ctx, query, token.Hash, id).Scan(&token.ID, &token.UserID, &token.Expiry, &token.Scope, &token.CreatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return token, nil
+}
+
+// Touch records that a token has just been used. To save a write on every
+// request, last_used_at is only moved on if it is more than a minute old.
+func (m TokenModel) Touch(scope, tokenPlaintext string) error {
+	query := `
+        UPDATE tokens
+        SET last_used_at = NOW()
+        WHERE hash = $1 AND scope = $2
+        AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`
+
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, tokenHash[:], scope)
 	return err
 }
 
+// Revoke deletes a token along with the rest of its family, so that logging
+// out also revokes the login's refresh token. It returns ErrRecordNotFound if
+// there is no such token in the scope.
+func (m TokenModel) Revoke(scope, tokenPlaintext string) error {
+	query := `
+        DELETE FROM tokens
+        WHERE family_id = (SELECT family_id FROM tokens WHERE hash = $1 AND scope = $2)
+        OR (hash = $1 AND scope = $2)`
+
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, tokenHash[:], scope)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
 // DeleteAllForUser deletes all tokens for a specific user and scope.
 func (m TokenModel) DeleteAllForUser(scope string, userID string) error {
 	query := `
@@ -94,4 +339,17 @@ func (m TokenModel) DeleteAllForUser(scope string, userID string) error {
 
 	_, err := m.DB.ExecContext(ctx, query, scope, userID)
 	return err
-}
\ No newline at end of file
+}
+// DeleteExpired deletes every token that has expired and returns how many
+// there were. Used refresh tokens are kept until they expire so that reuse
+// can still be detected.
+func (m TokenModel) DeleteExpired() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM tokens WHERE expiry < NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
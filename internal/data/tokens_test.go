@@ -0,0 +1,165 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenModel_Revocation(t *testing.T) {
+	db := setupUsersTestDB(t)
+	users := UserModel{DB: db}
+	m := TokenModel{DB: db}
+
+	user := newTestUser(t)
+	require.NoError(t, users.Insert(AuditActor{}, user))
+
+	first, err := m.New(user.ID, time.Hour, ScopeAuthentication)
+	require.NoError(t, err)
+	require.NotEmpty(t, first.ID)
+	second, err := m.New(user.ID, time.Hour, ScopeAuthentication)
+	require.NoError(t, err)
+	_, err = m.New(user.ID, time.Hour, ScopePasswordReset)
+	require.NoError(t, err)
+	_, err = m.New(user.ID, -time.Hour, ScopeAuthentication)
+	require.NoError(t, err)
+
+	// Only unexpired tokens in the scope are listed.
+	tokens, err := m.GetAllForUser(ScopeAuthentication, user.ID)
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	require.Empty(t, tokens[0].Plaintext)
+	require.Nil(t, tokens[0].LastUsedAt)
+
+	require.NoError(t, m.Touch(ScopeAuthentication, first.Plaintext))
+	tokens, err = m.GetAllForUser(ScopeAuthentication, user.ID)
+	require.NoError(t, err)
+	for _, token := range tokens {
+		require.Equal(t, token.ID == first.ID, token.LastUsedAt != nil)
+	}
+
+	// Logging out revokes just the one token.
+	require.NoError(t, m.Revoke(ScopeAuthentication, first.Plaintext))
+	require.ErrorIs(t, m.Revoke(ScopeAuthentication, first.Plaintext), ErrRecordNotFound)
+	_, err = users.GetForToken(ScopeAuthentication, second.Plaintext)
+	require.NoError(t, err)
+
+	require.NoError(t, m.DeleteAllForUser(ScopeAuthentication, user.ID))
+	_, err = users.GetForToken(ScopeAuthentication, second.Plaintext)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestTokenModel_Refresh(t *testing.T) {
+	db := setupUsersTestDB(t)
+	users := UserModel{DB: db}
+	m := TokenModel{DB: db}
+
+	user := newTestUser(t)
+	require.NoError(t, users.Insert(AuditActor{}, user))
+
+	access, refresh, err := m.NewLogin(user.ID, time.Minute, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, ScopeAuthentication, access.Scope)
+	require.Equal(t, ScopeRefresh, refresh.Scope)
+
+	// Rotating gives a new pair and uses up the old refresh token.
+	access2, refresh2, err := m.Refresh(refresh.Plaintext, time.Minute, time.Hour)
+	require.NoError(t, err)
+	require.NotEqual(t, refresh.Plaintext, refresh2.Plaintext)
+	_, err = users.GetForToken(ScopeAuthentication, access2.Plaintext)
+	require.NoError(t, err)
+
+	// Presenting the old refresh token again revokes the whole login.
+	_, _, err = m.Refresh(refresh.Plaintext, time.Minute, time.Hour)
+	require.ErrorIs(t, err, ErrTokenReused)
+	for _, token := range []*Token{access, access2} {
+		_, err = users.GetForToken(ScopeAuthentication, token.Plaintext)
+		require.ErrorIs(t, err, ErrRecordNotFound)
+	}
+	_, _, err = m.Refresh(refresh2.Plaintext, time.Minute, time.Hour)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	// Logging out of a login revokes its refresh token too.
+	access, refresh, err = m.NewLogin(user.ID, time.Minute, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, m.Revoke(ScopeAuthentication, access.Plaintext))
+	_, _, err = m.Refresh(refresh.Plaintext, time.Minute, time.Hour)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestTokenModel_Invitation(t *testing.T) {
+	db := setupUsersTestDB(t)
+	users := UserModel{DB: db}
+	tokens := TokenModel{DB: db}
+
+	user := newTestUser(t)
+	require.NoError(t, users.Insert(AuditActor{}, user))
+
+	invitation, err := tokens.New(user.ID, time.Hour, ScopeInvitation)
+	require.NoError(t, err)
+
+	// An invitation can't be used to activate or log in.
+	_, err = users.GetForToken(ScopeActivation, invitation.Plaintext)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+	_, err = users.GetForToken(ScopeAuthentication, invitation.Plaintext)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	invitee, err := users.GetForToken(ScopeInvitation, invitation.Plaintext)
+	require.NoError(t, err)
+	require.Equal(t, user.ID, invitee.ID)
+
+	require.NoError(t, tokens.DeleteAllForUser(ScopeInvitation, user.ID))
+	_, err = users.GetForToken(ScopeInvitation, invitation.Plaintext)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestTokenModel_Reissue(t *testing.T) {
+	db := setupUsersTestDB(t)
+	users := UserModel{DB: db}
+	tokens := TokenModel{DB: db}
+
+	user := newTestUser(t)
+	require.NoError(t, users.Insert(AuditActor{}, user))
+
+	original, err := tokens.New(user.ID, time.Hour, ScopePasswordReset)
+	require.NoError(t, err)
+
+	// The new plaintext works and the old one doesn't.
+	reissued, err := tokens.Reissue(original.ID)
+	require.NoError(t, err)
+	require.Equal(t, original.ID, reissued.ID)
+	require.Equal(t, ScopePasswordReset, reissued.Scope)
+	require.NotEqual(t, original.Plaintext, reissued.Plaintext)
+
+	_, err = users.GetForToken(ScopePasswordReset, original.Plaintext)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+	_, err = users.GetForToken(ScopePasswordReset, reissued.Plaintext)
+	require.NoError(t, err)
+
+	// A used token can't be brought back.
+	require.NoError(t, tokens.DeleteAllForUser(ScopePasswordReset, user.ID))
+	_, err = tokens.Reissue(original.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestTokenModel_DeleteExpired(t *testing.T) {
+	db := setupUsersTestDB(t)
+	users := UserModel{DB: db}
+	tokens := TokenModel{DB: db}
+
+	user := newTestUser(t)
+	require.NoError(t, users.Insert(AuditActor{}, user))
+
+	live, err := tokens.New(user.ID, time.Hour, ScopeActivation)
+	require.NoError(t, err)
+	_, err = tokens.New(user.ID, -time.Minute, ScopePasswordReset)
+	require.NoError(t, err)
+
+	deleted, err := tokens.DeleteExpired()
+	require.NoError(t, err)
+	require.EqualValues(t, 1, deleted)
+
+	_, err = users.GetForToken(ScopeActivation, live.Plaintext)
+	require.NoError(t, err)
+}
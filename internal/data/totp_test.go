@@ -0,0 +1,80 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// rfc6238Secret is the SHA1 test key from RFC 6238 ("12345678901234567890").
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// The RFC's test vectors are 8 digits; these are their last 6.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		code, err := TOTPCode(rfc6238Secret, time.Unix(tt.unix, 0))
+		require.NoError(t, err)
+		require.Equal(t, tt.want, code, "at %d", tt.unix)
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	code, err := TOTPCode(rfc6238Secret, now)
+	require.NoError(t, err)
+
+	step, ok, err := MatchTOTP(rfc6238Secret, code, now)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, now.Unix()/totpPeriod, step)
+
+	// A step of clock drift either way is allowed, but no more.
+	_, ok, _ = MatchTOTP(rfc6238Secret, code, now.Add(totpPeriod*time.Second))
+	require.True(t, ok)
+	_, ok, _ = MatchTOTP(rfc6238Secret, code, now.Add(-totpPeriod*time.Second))
+	require.True(t, ok)
+	_, ok, _ = MatchTOTP(rfc6238Secret, code, now.Add(2*totpPeriod*time.Second))
+	require.False(t, ok)
+
+	_, ok, _ = MatchTOTP(rfc6238Secret, "000000", now)
+	require.False(t, ok)
+
+	_, _, err = MatchTOTP("not base32!", code, now)
+	require.Error(t, err)
+}
+
+func TestNewTOTPSecret(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	require.NoError(t, err)
+	require.Len(t, secret, 32)
+
+	_, err = TOTPCode(secret, time.Now())
+	require.NoError(t, err)
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("National Training API", "jane@example.com", "ABCDEF")
+	require.Equal(t, "otpauth://totp/National%20Training%20API:jane@example.com?algorithm=SHA1&digits=6&issuer=National%20Training%20API&period=30&secret=ABCDEF", uri)
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	code, err := newRecoveryCode()
+	require.NoError(t, err)
+	require.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, code)
+
+	require.Equal(t, normalizeRecoveryCode(code), normalizeRecoveryCode(" "+code[:5]+code[6:]+" "))
+	require.Equal(t, "abcdefghij", normalizeRecoveryCode("ABCDE-FGHIJ"))
+}
@@ -0,0 +1,98 @@
+package data
+
+import (
+	"context"
+	"time"
+)
+
+// TranscriptEntry is a single session an officer was recorded against, with
+// the course details needed to read it on its own.
+type TranscriptEntry struct {
+	AttendanceID  string    `json:"attendance_id"`
+	SessionID     string    `json:"session_id"`
+	CourseID      string    `json:"course_id"`
+	CourseTitle   string    `json:"course_title"`
+	Category      string    `json:"category"`
+	Start         time.Time `json:"start_datetime"`
+	End           time.Time `json:"end_datetime"`
+	Status        string    `json:"status"`
+	CreditedHours float64   `json:"credited_hours"`
+}
+
+// Transcript is an officer's complete training history with credited hour
+// totals.
+type Transcript struct {
+	OfficerID       string             `json:"officer_id"`
+	Entries         []*TranscriptEntry `json:"entries"`
+	TotalHours      float64            `json:"total_hours"`
+	HoursByCategory map[string]float64 `json:"hours_by_category"`
+	HoursByYear     map[int]float64    `json:"hours_by_year"`
+}
+
+// newTranscript builds a transcript from its entries and calculates the totals.
+// Years are taken from each session's start date.
+func newTranscript(officerID string, entries []*TranscriptEntry) *Transcript {
+	transcript := &Transcript{
+		OfficerID:       officerID,
+		Entries:         entries,
+		HoursByCategory: make(map[string]float64),
+		HoursByYear:     make(map[int]float64),
+	}
+
+	for _, entry := range entries {
+		transcript.TotalHours += entry.CreditedHours
+		transcript.HoursByCategory[entry.Category] += entry.CreditedHours
+		transcript.HoursByYear[entry.Start.Year()] += entry.CreditedHours
+	}
+
+	return transcript
+}
+
+// GetTranscript returns every attendance record for an officer joined with its
+// session and course, oldest session first.
+func (m AttendanceModel) GetTranscript(officerID string) (*Transcript, error) {
+	query := `
+        SELECT a.id, s.id, c.id, c.title, c.category, s.start_datetime, s.end_datetime,
+               a.status, a.credited_hours
+        FROM attendance a
+        INNER JOIN sessions s ON s.id = a.session_id
+        INNER JOIN courses c ON c.id = s.course_id
+        WHERE a.officer_id = $1
+        ORDER BY s.start_datetime ASC, a.id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, officerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*TranscriptEntry{}
+
+	for rows.Next() {
+		var entry TranscriptEntry
+		err := rows.Scan(
+			&entry.AttendanceID,
+			&entry.SessionID,
+			&entry.CourseID,
+			&entry.CourseTitle,
+			&entry.Category,
+			&entry.Start,
+			&entry.End,
+			&entry.Status,
+			&entry.CreditedHours,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return newTranscript(officerID, entries), nil
+}
@@ -0,0 +1,31 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTranscript(t *testing.T) {
+	entries := []*TranscriptEntry{
+		{Category: "mandatory", Start: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC), Status: "attended", CreditedHours: 8},
+		{Category: "elective", Start: time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC), Status: "attended", CreditedHours: 4},
+		{Category: "mandatory", Start: time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC), Status: "attended", CreditedHours: 6.5},
+		{Category: "mandatory", Start: time.Date(2025, 2, 15, 9, 0, 0, 0, time.UTC), Status: "absent", CreditedHours: 0},
+	}
+
+	transcript := newTranscript("officer-1", entries)
+
+	require.Equal(t, "officer-1", transcript.OfficerID)
+	require.Len(t, transcript.Entries, 4)
+	require.Equal(t, 18.5, transcript.TotalHours)
+	require.Equal(t, map[string]float64{"mandatory": 14.5, "elective": 4}, transcript.HoursByCategory)
+	require.Equal(t, map[int]float64{2024: 12, 2025: 6.5}, transcript.HoursByYear)
+
+	// An officer with no history still gets empty, non-nil totals.
+	empty := newTranscript("officer-2", []*TranscriptEntry{})
+	require.Zero(t, empty.TotalHours)
+	require.NotNil(t, empty.HoursByCategory)
+	require.NotNil(t, empty.HoursByYear)
+}
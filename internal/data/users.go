@@ -8,7 +8,6 @@ import (
 	"time"
 
     "github.com/amari03/test1/internal/validator"
-    "golang.org/x/crypto/bcrypt"
 )
 
 // Create a global variable to represent an anonymous user.
@@ -20,6 +19,8 @@ type User struct {
 	Password    password   `json:"-"` // Use the custom password type. Changed from PasswordHash
 	Role        string     `json:"role"`
 	Activated   bool       `json:"activated"`
+	MFAEnabled  bool       `json:"mfa_enabled"`
+	PendingEmail *string   `json:"pending_email,omitempty"`
 	Version     int        `json:"-"` // Add the version number.
 	CreatedAt   time.Time  `json:"created_at"`
 	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
@@ -45,16 +46,18 @@ func ValidateEmail(v *validator.Validator, email string) {
 	v.Check(validator.Matches(email, validator.EmailRX), "email", "must be a valid email address")
 }
 
+// ValidatePasswordPlaintext checks a new password against the configured
+// password policy.
 func ValidatePasswordPlaintext(v *validator.Validator, password string) {
-	v.Check(password != "", "password", "must be provided")
-	v.Check(len(password) >= 8, "password", "must be at least 8 bytes long")
-	v.Check(len(password) <= 72, "password", "must not be more than 72 bytes long")
+	passwordPolicy.Validate(v, password)
 }
 
 func ValidateUser(v *validator.Validator, user *User) {
 	// Validate user's email.
 	ValidateEmail(v, user.Email)
 
+	v.Check(validator.In(user.Role, Roles...), "role", "must be admin, contributor, or viewer")
+
 	// If the plaintext password is not nil, validate it.
 	if user.Password.plaintext != nil {
 		ValidatePasswordPlaintext(v, *user.Password.plaintext)
@@ -68,7 +71,7 @@ func ValidateUser(v *validator.Validator, user *User) {
 }
 
 // Insert a new user record into the database.
-func (m UserModel) Insert(user *User) error {
+func (m UserModel) Insert(actor AuditActor, user *User) error {
 	query := `
         INSERT INTO users (email, password_hash, role, activated)
         VALUES ($1, $2, $3, $4)
@@ -79,7 +82,13 @@ func (m UserModel) Insert(user *User) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.CreatedAt, &user.Version)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.CreatedAt, &user.Version)
 	if err != nil {
 		// Check for a duplicate email error.
 		if err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"` {
@@ -87,37 +96,104 @@ func (m UserModel) Insert(user *User) error {
 		}
 		return err
 	}
-	return nil
+
+	err = audit(ctx, tx, actor, AuditActionInsert, "user", user.ID, nil, user)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const userColumns = `id, email, password_hash, role, activated, totp_enabled, pending_email, version, created_at, last_login_at`
+
+func scanUser(row interface{ Scan(...interface{}) error }, user *User) error {
+	return row.Scan(
+		&user.ID,
+		&user.Email,
+		&user.Password.hash,
+		&user.Role,
+		&user.Activated,
+		&user.MFAEnabled,
+		&user.PendingEmail,
+		&user.Version,
+		&user.CreatedAt,
+		&user.LastLoginAt,
+	)
 }
 
 // Get a specific user by ID.
 func (m UserModel) Get(id string) (*User, error) {
-    query := `
-        SELECT id, email, password_hash, role, created_at, last_login_at
+    ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+    defer cancel()
+
+    return getUser(ctx, m.DB, id)
+}
+
+// getUser fetches a user using q, which may be a transaction.
+func getUser(ctx context.Context, q queryer, id string) (*User, error) {
+	query := `
+        SELECT ` + userColumns + `
         FROM users
         WHERE id = $1`
 
-    var user User
-    err := m.DB.QueryRow(query, id).Scan(
-        &user.ID,
-        &user.Email,
-        &user.Password.hash,
-        &user.Role,
-        &user.CreatedAt,
-        &user.LastLoginAt,
-    )
+	var user User
+	err := scanUser(q.QueryRowContext(ctx, query, id), &user)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
 
-    if err != nil {
-        if err == sql.ErrNoRows {
-            return nil, ErrRecordNotFound
-        }
-        return nil, err
-    }
-    return &user, nil
+// updateUser runs an update to a user that returns its new version, scanning
+// what the update returns into dest, and audits it. The user as it was is read
+// in the same transaction first; the version check means it is exactly what
+// the update changed. A user that has gone is reported as an edit conflict.
+// mapErr, if set, gets the first say on what a failed update means.
+func (m UserModel) updateUser(actor AuditActor, user *User, query string, args []interface{}, mapErr func(error) error, dest ...interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getUser(ctx, tx, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRecordNotFound):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(dest...)
+	if err != nil {
+		if mapErr != nil {
+			err = mapErr(err)
+		}
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	err = audit(ctx, tx, actor, AuditActionUpdate, "user", user.ID, before, user)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // Update a specific user record.
-func (m UserModel) Update(user *User) error {
+func (m UserModel) Update(actor AuditActor, user *User) error {
     query := `
         UPDATE users
         SET email = $1, role = $2, activated = $3, version = version + 1
@@ -131,45 +207,52 @@ func (m UserModel) Update(user *User) error {
         user.ID,
         user.Version,
     }
-    
-    ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-    defer cancel()
 
-    err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.Version)
-    if err != nil {
+    return m.updateUser(actor, user, query, args, func(err error) error {
         if err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"` {
             return ErrDuplicateEmail
         }
-        if errors.Is(err, sql.ErrNoRows) {
-            return ErrEditConflict
-        }
         return err
-    }
-    return nil
+    }, &user.Version)
 }
 
-// Delete a specific user by ID.
-    func (m UserModel) Delete(id string) error {
-        query := `
-            DELETE FROM users
-            WHERE id = $1`
+// Delete a specific user by ID, along with their API keys, which are audited
+// as they go.
+func (m UserModel) Delete(actor AuditActor, id string) error {
+    query := `
+        DELETE FROM users
+        WHERE id = $1
+        RETURNING ` + userColumns
+
+    ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+    defer cancel()
 
-        result, err := m.DB.Exec(query, id)
-        if err != nil {
-            return err
-        }
+    tx, err := m.DB.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
 
-        rowsAffected, err := result.RowsAffected()
-        if err != nil {
-            return err
-        }
+    _, err = deleteAPIKeys(ctx, tx, actor, "user_id = $1", id)
+    if err != nil {
+        return err
+    }
 
-        if rowsAffected == 0 {
+    var user User
+    err = scanUser(tx.QueryRowContext(ctx, query, id), &user)
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
             return ErrRecordNotFound
         }
+        return err
+    }
 
-        return nil
+    err = audit(ctx, tx, actor, AuditActionDelete, "user", user.ID, &user, nil)
+    if err != nil {
+        return err
     }
+    return tx.Commit()
+}
 
 // GetAll returns a slice of all users.
 func (m UserModel) GetAll() ([]*User, error) {
@@ -208,9 +291,9 @@ func (m UserModel) GetAll() ([]*User, error) {
     return users, nil
 }
 
-// Set calculates the bcrypt hash of a plaintext password.
+// Set hashes a plaintext password with the configured hasher.
 func (p *password) Set(plaintextPassword string) error {
-	hash, err := bcrypt.GenerateFromPassword([]byte(plaintextPassword), 12)
+	hash, err := passwordHasher.Hash(plaintextPassword)
 	if err != nil {
 		return err
 	}
@@ -221,14 +304,14 @@ func (p *password) Set(plaintextPassword string) error {
 
 // Matches checks if the provided plaintext password matches the stored hash.
 func (p *password) Matches(plaintextPassword string) (bool, error) {
-	err := bcrypt.CompareHashAndPassword(p.hash, []byte(plaintextPassword))
-	if err != nil {
-		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-			return false, nil
-		}
-		return false, err
-	}
-	return true, nil
+	return comparePasswordHash(p.hash, plaintextPassword)
+}
+
+// NeedsRehash reports whether the hash was made with a different algorithm
+// or parameters from the configured hasher, so should be replaced the next
+// time the plaintext is known.
+func (p *password) NeedsRehash() bool {
+	return passwordHasher.NeedsRehash(p.hash)
 }
 
 // GetForToken returns a user record based on a token and its scope.
@@ -236,7 +319,7 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
     tokenHash := sha256.Sum256([]byte(tokenPlaintext))
 
     query := `
-        SELECT users.id, users.email, users.password_hash, users.role, users.activated, users.version, users.created_at
+        SELECT users.id, users.email, users.password_hash, users.role, users.activated, users.totp_enabled, users.pending_email, users.version, users.created_at
         FROM users
         INNER JOIN tokens
         ON users.id = tokens.user_id
@@ -256,6 +339,8 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
         &user.Password.hash, // Scan into the hash field of the password struct
         &user.Role,
         &user.Activated,
+        &user.MFAEnabled,
+        &user.PendingEmail,
         &user.Version,
         &user.CreatedAt,
     )
@@ -272,7 +357,7 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 // GetByEmail retrieves a user by their email address.
 func (m UserModel) GetByEmail(email string) (*User, error) {
 	query := `
-        SELECT id, email, password_hash, role, activated, version, created_at, last_login_at
+        SELECT id, email, password_hash, role, activated, totp_enabled, version, created_at, last_login_at
         FROM users
         WHERE email = $1`
 
@@ -286,6 +371,7 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 		&user.Password.hash,
 		&user.Role,
 		&user.Activated,
+		&user.MFAEnabled,
 		&user.Version,
 		&user.CreatedAt,
 		&user.LastLoginAt,
@@ -301,8 +387,23 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 	return &user, nil
 }
 
+// RecordLogin sets a user's last_login_at to now. It doesn't change the
+// version, so it can't cause edit conflicts with other updates.
+func (m UserModel) RecordLogin(user *User) error {
+	query := `
+        UPDATE users
+        SET last_login_at = NOW()
+        WHERE id = $1
+        RETURNING last_login_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, user.ID).Scan(&user.LastLoginAt)
+}
+
 // UpdatePassword updates the password for a specific user.
-func (m UserModel) UpdatePassword(user *User) error {
+func (m UserModel) UpdatePassword(actor AuditActor, user *User) error {
 	query := `
         UPDATE users
         SET password_hash = $1, version = version + 1
@@ -315,10 +416,39 @@ func (m UserModel) UpdatePassword(user *User) error {
 		user.Version,
 	}
 
+	return m.updateUser(actor, user, query, args, nil, &user.Version)
+}
+
+// AcceptInvitation sets an invited user's password and activates their
+// account, and deletes their invitation tokens so that the invitation can't
+// be used again, all in one transaction.
+func (m UserModel) AcceptInvitation(actor AuditActor, user *User) error {
+	query := `
+        UPDATE users
+        SET password_hash = $1, activated = true, version = version + 1
+        WHERE id = $2 AND version = $3
+        RETURNING activated, version`
+
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.Version)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getUser(ctx, tx, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRecordNotFound):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, query, user.Password.hash, user.ID, user.Version).Scan(&user.Activated, &user.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -327,5 +457,68 @@ func (m UserModel) UpdatePassword(user *User) error {
 			return err
 		}
 	}
-	return nil
-}
\ No newline at end of file
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM tokens WHERE scope = $1 AND user_id = $2`, ScopeInvitation, user.ID)
+	if err != nil {
+		return err
+	}
+
+	err = audit(ctx, tx, actor, AuditActionUpdate, "user", user.ID, before, user)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetPendingEmail records the address a user wants to change to. It isn't
+// used until they confirm it with ConfirmEmailChange. It returns
+// ErrDuplicateEmail if another user already has the address.
+func (m UserModel) SetPendingEmail(actor AuditActor, user *User, email string) error {
+	query := `
+        UPDATE users
+        SET pending_email = $1, version = version + 1
+        WHERE id = $2 AND version = $3
+        AND NOT EXISTS (SELECT 1 FROM users WHERE email = $1)
+        RETURNING pending_email, version`
+
+	args := []interface{}{email, user.ID, user.Version}
+
+	return m.updateUser(actor, user, query, args, func(err error) error {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		// Tell the two reasons apart so the handler can respond properly.
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		var taken bool
+		err = m.DB.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE email = $1)`, email).Scan(&taken)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrDuplicateEmail
+		}
+		return ErrEditConflict
+	}, &user.PendingEmail, &user.Version)
+}
+
+// ConfirmEmailChange makes a user's pending email address their email
+// address. It returns ErrDuplicateEmail if someone else has taken the address
+// in the meantime.
+func (m UserModel) ConfirmEmailChange(actor AuditActor, user *User) error {
+	query := `
+        UPDATE users
+        SET email = pending_email, pending_email = NULL, version = version + 1
+        WHERE id = $1 AND version = $2 AND pending_email IS NOT NULL
+        RETURNING email, pending_email, version`
+
+	args := []interface{}{user.ID, user.Version}
+
+	return m.updateUser(actor, user, query, args, func(err error) error {
+		if isPQError(err, pqUniqueViolation) {
+			return ErrDuplicateEmail
+		}
+		return err
+	}, &user.Email, &user.PendingEmail, &user.Version)
+}
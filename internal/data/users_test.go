@@ -35,7 +35,11 @@ func setupUsersTestDB(t *testing.T) *sql.DB {
         activated BOOL NOT NULL,
         role TEXT NOT NULL,
         version INTEGER NOT NULL DEFAULT 1,
-        last_login_at TIMESTAMPTZ
+        last_login_at TIMESTAMPTZ,
+        totp_secret TEXT,
+        totp_enabled BOOLEAN NOT NULL DEFAULT false,
+        totp_last_step BIGINT NOT NULL DEFAULT 0,
+        pending_email TEXT
     );`
 	_, err = db.Exec(createUsersTableSQL)
 	require.NoError(t, err)
@@ -46,7 +50,12 @@ func setupUsersTestDB(t *testing.T) *sql.DB {
         hash BYTEA PRIMARY KEY,
         user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
         expiry TIMESTAMPTZ NOT NULL,
-        scope TEXT NOT NULL
+        scope TEXT NOT NULL,
+        id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        last_used_at TIMESTAMPTZ,
+        family_id UUID,
+        used_at TIMESTAMPTZ
     );`
 	_, err = db.Exec(createTokensTableSQL)
 	require.NoError(t, err)
@@ -59,6 +68,9 @@ func setupUsersTestDB(t *testing.T) *sql.DB {
 		db.Close()
 	})
 
+	// Registered after the cleanup above, so it runs first.
+	createAuditEventsTable(t, db)
+
 	return db
 }
 
@@ -66,7 +78,7 @@ func setupUsersTestDB(t *testing.T) *sql.DB {
 func newTestUser(t *testing.T) *User {
 	user := &User{
 		Email:     "john.doe@example.com",
-		Role:      "user",
+		Role:      "viewer",
 		Activated: true,
 	}
 	err := user.Password.Set("password123")
@@ -112,6 +124,7 @@ func TestValidateUser(t *testing.T) {
 	require.False(t, v.Valid())
 	require.Contains(t, v.Errors, "email")
 	require.Contains(t, v.Errors, "password")
+	require.Contains(t, v.Errors, "role")
 
 	// Test case for valid user
 	v = validator.New()
@@ -125,7 +138,7 @@ func TestUserModel_InsertAndGetByEmail(t *testing.T) {
 	m := UserModel{DB: db}
 	user := newTestUser(t)
 
-	err := m.Insert(user)
+	err := m.Insert(AuditActor{}, user)
 	require.NoError(t, err)
 
 	// Verify ID, CreatedAt, and Version are set
@@ -141,8 +154,14 @@ func TestUserModel_InsertAndGetByEmail(t *testing.T) {
 	require.Equal(t, user.Email, fetchedUser.Email)
 	require.Equal(t, user.Role, fetchedUser.Role)
 
+	// Get by ID returns what Update needs for its version check
+	fetchedUser, err = m.Get(user.ID)
+	require.NoError(t, err)
+	require.Equal(t, user.Version, fetchedUser.Version)
+	require.True(t, fetchedUser.Activated)
+
 	// Test duplicate email
-	err = m.Insert(user)
+	err = m.Insert(AuditActor{}, user)
 	require.Error(t, err)
 	require.True(t, errors.Is(err, ErrDuplicateEmail))
 }
@@ -152,7 +171,7 @@ func TestUserModel_Update(t *testing.T) {
 	m := UserModel{DB: db}
 
 	user := newTestUser(t)
-	err := m.Insert(user)
+	err := m.Insert(AuditActor{}, user)
 	require.NoError(t, err)
 
 	// Update fields
@@ -160,7 +179,7 @@ func TestUserModel_Update(t *testing.T) {
 	user.Role = "admin"
 	user.Activated = false
 
-	err = m.Update(user)
+	err = m.Update(AuditActor{}, user)
 	require.NoError(t, err)
 	require.Equal(t, 2, user.Version)
 
@@ -173,19 +192,19 @@ func TestUserModel_Update(t *testing.T) {
 
 	// Test edit conflict
 	user.Version = 1
-	err = m.Update(user)
+	err = m.Update(AuditActor{}, user)
 	require.Error(t, err)
 	require.True(t, errors.Is(err, ErrEditConflict))
 
 	// Test duplicate email on update
-	user2 := &User{Email: "jane.doe@example.com", Role: "user", Activated: true}
+	user2 := &User{Email: "jane.doe@example.com", Role: "viewer", Activated: true}
 	err = user2.Password.Set("password123")
 	require.NoError(t, err)
-	err = m.Insert(user2)
+	err = m.Insert(AuditActor{}, user2)
 	require.NoError(t, err)
 
 	fetched.Email = user2.Email // try to update fetched user's email to user2's email
-	err = m.Update(fetched)
+	err = m.Update(AuditActor{}, fetched)
 	require.Error(t, err)
 	require.True(t, errors.Is(err, ErrDuplicateEmail))
 }
@@ -194,7 +213,7 @@ func TestUserModel_UpdatePassword(t *testing.T) {
 	db := setupUsersTestDB(t)
 	m := UserModel{DB: db}
 	user := newTestUser(t)
-	err := m.Insert(user)
+	err := m.Insert(AuditActor{}, user)
 	require.NoError(t, err)
 
 	// Set a new password
@@ -203,7 +222,7 @@ func TestUserModel_UpdatePassword(t *testing.T) {
 	require.NoError(t, err)
 
 	// Update in the database
-	err = m.UpdatePassword(user)
+	err = m.UpdatePassword(AuditActor{}, user)
 	require.NoError(t, err)
 	require.Equal(t, 2, user.Version) // Version should increment
 
@@ -221,13 +240,49 @@ func TestUserModel_UpdatePassword(t *testing.T) {
 	require.False(t, match)
 }
 
+func TestUserModel_AcceptInvitation(t *testing.T) {
+	db := setupUsersTestDB(t)
+	m := UserModel{DB: db}
+	user := newTestUser(t)
+	user.Activated = false
+	err := m.Insert(AuditActor{}, user)
+	require.NoError(t, err)
+
+	tokenHash := sha256.Sum256([]byte("invitationtoken"))
+	_, err = db.Exec("INSERT INTO tokens (hash, user_id, scope, expiry) VALUES ($1, $2, $3, $4)",
+		tokenHash[:], user.ID, ScopeInvitation, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	err = user.Password.Set("new-secure-password-456")
+	require.NoError(t, err)
+	err = m.AcceptInvitation(AuditActor{}, user)
+	require.NoError(t, err)
+	require.True(t, user.Activated)
+
+	fetched, err := m.GetByEmail(user.Email)
+	require.NoError(t, err)
+	require.True(t, fetched.Activated)
+	match, err := fetched.Password.Matches("new-secure-password-456")
+	require.NoError(t, err)
+	require.True(t, match)
+
+	// The invitation can't be used again.
+	_, err = m.GetForToken(ScopeInvitation, "invitationtoken")
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	// Nor can a stale copy of the user be accepted.
+	user.Version--
+	err = m.AcceptInvitation(AuditActor{}, user)
+	require.ErrorIs(t, err, ErrEditConflict)
+}
+
 func TestUserModel_GetForToken(t *testing.T) {
 	db := setupUsersTestDB(t)
 	m := UserModel{DB: db}
 
 	// 1. Create a user
 	user := newTestUser(t)
-	err := m.Insert(user)
+	err := m.Insert(AuditActor{}, user)
 	require.NoError(t, err)
 
 	// 2. Create a token
@@ -270,8 +325,8 @@ func TestUserModel_GetAll(t *testing.T) {
 	user1 := newTestUser(t)
 	user2 := newTestUser(t)
 	user2.Email = "jane.doe@example.com"
-	require.NoError(t, m.Insert(user1))
-	require.NoError(t, m.Insert(user2))
+	require.NoError(t, m.Insert(AuditActor{}, user1))
+	require.NoError(t, m.Insert(AuditActor{}, user2))
 
 	users, err := m.GetAll()
 	require.NoError(t, err)
@@ -280,3 +335,43 @@ func TestUserModel_GetAll(t *testing.T) {
 	require.Equal(t, "jane.doe@example.com", users[0].Email)
 	require.Equal(t, "john.doe@example.com", users[1].Email)
 }
+
+func TestUserModel_EmailChange(t *testing.T) {
+	db := setupUsersTestDB(t)
+	m := UserModel{DB: db}
+
+	user := newTestUser(t)
+	require.NoError(t, m.Insert(AuditActor{}, user))
+	oldEmail := user.Email
+
+	other := &User{Email: "jane.doe@example.com", Role: "viewer", Activated: true}
+	require.NoError(t, other.Password.Set("password123"))
+	require.NoError(t, m.Insert(AuditActor{}, other))
+
+	// An address someone else has can't be requested.
+	err := m.SetPendingEmail(AuditActor{}, user, other.Email)
+	require.ErrorIs(t, err, ErrDuplicateEmail)
+
+	// The email doesn't change until the new address is confirmed.
+	require.NoError(t, m.SetPendingEmail(AuditActor{}, user, "john.new@example.com"))
+	fetched, err := m.Get(user.ID)
+	require.NoError(t, err)
+	require.Equal(t, oldEmail, fetched.Email)
+	require.Equal(t, "john.new@example.com", *fetched.PendingEmail)
+
+	// A stale version is an edit conflict.
+	stale := *fetched
+	stale.Version--
+	require.ErrorIs(t, m.SetPendingEmail(AuditActor{}, &stale, "john.other@example.com"), ErrEditConflict)
+
+	require.NoError(t, m.ConfirmEmailChange(AuditActor{}, fetched))
+	require.Equal(t, "john.new@example.com", fetched.Email)
+	require.Nil(t, fetched.PendingEmail)
+
+	fetched, err = m.GetByEmail("john.new@example.com")
+	require.NoError(t, err)
+	require.Equal(t, user.ID, fetched.ID)
+
+	// There's nothing left to confirm.
+	require.ErrorIs(t, m.ConfirmEmailChange(AuditActor{}, fetched), ErrEditConflict)
+}
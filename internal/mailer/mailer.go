@@ -58,14 +58,6 @@ func (m Mailer) Send(recipient, templateFile string, data interface{}) error {
 	msg.SetBody("text/plain", plainBody.String())
 	msg.AddAlternative("text/html", htmlBody.String())
 
-	// Try sending the email up to three times.
-	for i := 1; i <= 3; i++ {
-		err = m.dialer.DialAndSend(msg)
-		if err == nil {
-			return nil
-		}
-		time.Sleep(500 * time.Millisecond)
-	}
-
-	return err
+	// Retries are left to the job queue, which backs off between attempts.
+	return m.dialer.DialAndSend(msg)
 }
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE regions DROP COLUMN version;
+ALTER TABLE formations DROP COLUMN version;
+ALTER TABLE postings DROP COLUMN version;
+ALTER TABLE ranks DROP COLUMN version;
\ No newline at end of file
@@ -0,0 +1,6 @@
+-- Add version column for optimistic locking to the lookup tables.
+
+ALTER TABLE regions ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE formations ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE postings ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE ranks ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
\ No newline at end of file
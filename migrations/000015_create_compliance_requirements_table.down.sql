@@ -0,0 +1 @@
+DROP TABLE IF EXISTS compliance_requirements;
\ No newline at end of file
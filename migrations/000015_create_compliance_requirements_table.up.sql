@@ -0,0 +1,20 @@
+-- Annual in-service training requirements. A 'credit_hours' requirement asks
+-- for a number of credited hours per year, optionally only counting one course
+-- category. A 'course' requirement asks for a specific course to be attended
+-- each year. A NULL rank_code applies the requirement to every rank.
+CREATE TABLE compliance_requirements (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('credit_hours', 'course')),
+    rank_code TEXT REFERENCES ranks(code) ON DELETE CASCADE,
+    category TEXT CHECK (category IN ('mandatory', 'elective', 'instructor')),
+    required_hours NUMERIC(5, 1),
+    course_id UUID REFERENCES courses(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ,
+    version INTEGER NOT NULL DEFAULT 1,
+    CHECK (
+        (kind = 'credit_hours' AND required_hours IS NOT NULL AND course_id IS NULL) OR
+        (kind = 'course' AND course_id IS NOT NULL AND required_hours IS NULL AND category IS NULL)
+    )
+);
\ No newline at end of file
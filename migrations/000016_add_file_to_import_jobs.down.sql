@@ -0,0 +1,2 @@
+ALTER TABLE import_jobs DROP COLUMN IF EXISTS rows_imported;
+ALTER TABLE import_jobs DROP COLUMN IF EXISTS file_path;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE import_jobs ADD COLUMN file_path TEXT NOT NULL DEFAULT '';
+ALTER TABLE import_jobs ADD COLUMN rows_imported INTEGER NOT NULL DEFAULT 0;
\ No newline at end of file
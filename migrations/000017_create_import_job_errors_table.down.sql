@@ -0,0 +1,2 @@
+ALTER TABLE import_jobs DROP COLUMN IF EXISTS dry_run;
+DROP TABLE IF EXISTS import_job_errors;
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE import_job_errors (
+    id BIGSERIAL PRIMARY KEY,
+    import_job_id UUID NOT NULL REFERENCES import_jobs(id) ON DELETE CASCADE,
+    row_number INTEGER NOT NULL,
+    column_name TEXT NOT NULL DEFAULT '',
+    message TEXT NOT NULL
+);
+
+CREATE INDEX import_job_errors_import_job_id_idx ON import_job_errors (import_job_id, row_number);
+
+ALTER TABLE import_jobs ADD COLUMN dry_run BOOLEAN NOT NULL DEFAULT false;
\ No newline at end of file
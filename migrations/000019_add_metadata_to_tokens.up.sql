@@ -0,0 +1,4 @@
+ALTER TABLE tokens ADD COLUMN id UUID NOT NULL DEFAULT gen_random_uuid() UNIQUE;
+ALTER TABLE tokens ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE tokens ADD COLUMN last_used_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS tokens_user_id_scope_idx ON tokens (user_id, scope);
\ No newline at end of file
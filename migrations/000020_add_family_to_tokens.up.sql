@@ -0,0 +1,3 @@
+ALTER TABLE tokens ADD COLUMN family_id UUID;
+ALTER TABLE tokens ADD COLUMN used_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS tokens_family_id_idx ON tokens (family_id);
\ No newline at end of file
@@ -0,0 +1,8 @@
+-- Recent failed logins, keyed by "email:<address>" or "ip:<address>". A key
+-- can't be used to log in again until blocked_until.
+CREATE TABLE login_failures (
+    key TEXT PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    blocked_until TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
\ No newline at end of file
@@ -0,0 +1,3 @@
+-- A new email address waits here until its owner confirms it with the token
+-- sent to it; only then is it copied into email.
+ALTER TABLE users ADD COLUMN pending_email TEXT;
\ No newline at end of file
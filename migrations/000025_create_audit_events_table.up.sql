@@ -0,0 +1,19 @@
+-- Every change made to training records, reference data and user accounts.
+-- The actor and API key are plain IDs rather than foreign keys so that the
+-- history survives them being deleted.
+CREATE TABLE audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    actor_user_id UUID,
+    api_key_id UUID,
+    action TEXT NOT NULL,
+    entity_type TEXT NOT NULL,
+    entity_id TEXT NOT NULL,
+    before JSONB,
+    after JSONB,
+    request_id TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX audit_events_entity_idx ON audit_events (entity_type, entity_id);
+CREATE INDEX audit_events_actor_user_id_idx ON audit_events (actor_user_id);
+CREATE INDEX audit_events_created_at_idx ON audit_events (created_at);
\ No newline at end of file
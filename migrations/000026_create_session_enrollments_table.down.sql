@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS session_enrollments;
+ALTER TABLE sessions DROP COLUMN IF EXISTS capacity;
\ No newline at end of file
@@ -0,0 +1,17 @@
+-- capacity is the most officers that can be enrolled in a session; NULL means
+-- there is no limit. Officers who enroll once it is full are waitlisted, and
+-- promoted in the order they enrolled as places come free.
+ALTER TABLE sessions ADD COLUMN capacity INTEGER CHECK (capacity > 0);
+
+CREATE TABLE session_enrollments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    officer_id UUID NOT NULL REFERENCES officers(id) ON DELETE CASCADE,
+    status TEXT NOT NULL CHECK (status IN ('enrolled', 'waitlisted')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ,
+    version INTEGER NOT NULL DEFAULT 1,
+    UNIQUE (session_id, officer_id)
+);
+
+CREATE INDEX session_enrollments_officer_id_idx ON session_enrollments (officer_id);
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE sessions DROP COLUMN IF EXISTS detached;
+ALTER TABLE sessions DROP COLUMN IF EXISTS series_index;
+ALTER TABLE sessions DROP COLUMN IF EXISTS series_id;
+DROP TABLE IF EXISTS session_series;
\ No newline at end of file
@@ -0,0 +1,43 @@
+-- A session series repeats a session on a schedule, described by a recurrence
+-- rule after RFC 5545's RRULE. Its sessions are created up front, so every
+-- series ends: after a number of occurrences or at a time. start_datetime and
+-- end_datetime are the first occurrence's, and give the others their time of
+-- day, in time_zone, and length.
+CREATE TABLE session_series (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    course_id UUID NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    start_datetime TIMESTAMPTZ NOT NULL,
+    end_datetime TIMESTAMPTZ NOT NULL,
+    time_zone TEXT NOT NULL DEFAULT 'UTC',
+    frequency TEXT NOT NULL CHECK (frequency IN ('daily', 'weekly', 'monthly')),
+    repeat_interval INTEGER NOT NULL DEFAULT 1 CHECK (repeat_interval > 0),
+    by_day TEXT[] NOT NULL DEFAULT '{}',
+    until_datetime TIMESTAMPTZ,
+    occurrence_count INTEGER CHECK (occurrence_count > 0),
+    location_text TEXT NOT NULL,
+    region_id TEXT REFERENCES regions(id) ON DELETE SET NULL,
+    formation_id TEXT REFERENCES formations(id) ON DELETE SET NULL,
+    posting_id TEXT REFERENCES postings(id) ON DELETE SET NULL,
+    facilitator_notes TEXT,
+    credit_hours_override NUMERIC(4, 1),
+    capacity INTEGER CHECK (capacity > 0),
+    -- The occurrences whose sessions were deleted on their own, like RFC
+    -- 5545's EXDATE, so that changes to the series don't bring them back.
+    excluded_indexes INTEGER[] NOT NULL DEFAULT '{}',
+    cancelled_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ,
+    version INTEGER NOT NULL DEFAULT 1,
+    CHECK ((until_datetime IS NULL) <> (occurrence_count IS NULL))
+);
+
+-- series_index numbers a series' sessions from 0, like RFC 5545's
+-- RECURRENCE-ID. A session edited on its own is detached: later changes to
+-- the series leave it alone.
+ALTER TABLE sessions
+    ADD COLUMN series_id UUID REFERENCES session_series(id) ON DELETE SET NULL,
+    ADD COLUMN series_index INTEGER,
+    ADD COLUMN detached BOOLEAN NOT NULL DEFAULT false,
+    ADD CHECK ((series_id IS NULL) = (series_index IS NULL));
+
+CREATE UNIQUE INDEX sessions_series_id_series_index_idx ON sessions (series_id, series_index);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS sessions_status_idx;
+ALTER TABLE sessions DROP COLUMN IF EXISTS cancellation_reason;
+ALTER TABLE sessions DROP COLUMN IF EXISTS status;
\ No newline at end of file
@@ -0,0 +1,10 @@
+-- A session is scheduled until it starts, in progress while it runs, and then
+-- completed, which locks its attendance. It can also be postponed, until it
+-- has a new date, or cancelled, with a reason.
+ALTER TABLE sessions
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'scheduled'
+        CHECK (status IN ('scheduled', 'in_progress', 'completed', 'cancelled', 'postponed')),
+    ADD COLUMN cancellation_reason TEXT,
+    ADD CHECK ((status = 'cancelled') = (cancellation_reason IS NOT NULL));
+
+CREATE INDEX sessions_status_idx ON sessions (status);
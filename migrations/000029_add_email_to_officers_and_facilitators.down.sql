@@ -0,0 +1,2 @@
+ALTER TABLE facilitators DROP COLUMN IF EXISTS email;
+ALTER TABLE officers DROP COLUMN IF EXISTS email;
\ No newline at end of file
@@ -0,0 +1,3 @@
+-- Where to tell officers and facilitators about changes to their sessions.
+ALTER TABLE officers ADD COLUMN email TEXT;
+ALTER TABLE facilitators ADD COLUMN email TEXT;